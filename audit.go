@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+// auditLogLabel は監査ログの検証エラーで使うファイルの種類
+const auditLogLabel = "audit log"
+
+// auditLogPath は監査ログのパスを返します
+// $XDG_STATE_HOME（未設定なら ~/.local/state）配下の esa-llm-scoped-guard/audit.jsonl
+func auditLogPath() (string, error) {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" || !filepath.IsAbs(stateDir) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		stateDir = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(stateDir, "esa-llm-scoped-guard", "audit.jsonl"), nil
+}
+
+// openAuditLog は書き込みを記録する監査ログを開きます
+// 監査ログを開けない場合は書き込み自体を行わないよう、呼び出し側はエラーで終了する（fail closed）
+// secretsに渡した文字列（アクセストークン）は監査ログに記録されない
+func openAuditLog(command string, secrets ...string) (*guard.AuditLog, error) {
+	path, err := auditLogPath()
+	if err != nil {
+		return nil, guard.WithKind(guard.ErrorKindIO, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, guard.WithKind(guard.ErrorKindIO, fmt.Errorf("failed to create audit log directory: %w", err))
+	}
+
+	path, err = checkAuditLogFile(path)
+	if err != nil {
+		return nil, guard.WithKind(guard.ErrorKindIO, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, guard.WithKind(guard.ErrorKindIO, fmt.Errorf("failed to open audit log: %w", err))
+	}
+	return guard.NewAuditLog(file, command, secrets...), nil
+}
+
+// checkAuditLogFile は監査ログとそのディレクトリの権限を設定ファイルと同じ基準で検証し、
+// symlinkを解決したパスを返します（ファイルがまだない場合はディレクトリのみ検証する）
+func checkAuditLogFile(path string) (string, error) {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		// まだ一度も書き込んでいない（ディレクトリもない）場合は空のログとして扱う
+		if _, err := os.Stat(filepath.Dir(path)); os.IsNotExist(err) {
+			return path, nil
+		}
+		if err := validatePrivateDir(filepath.Dir(path), auditLogLabel); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve symlink: %w", err)
+	}
+	if err := validatePrivateFile(realPath, auditLogLabel); err != nil {
+		return "", err
+	}
+	return realPath, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+func TestOpenAuditLog(t *testing.T) {
+	t.Run("状態ディレクトリを作成して追記する", func(t *testing.T) {
+		stateDir := t.TempDir()
+		t.Setenv("XDG_STATE_HOME", stateDir)
+
+		audit, err := openAuditLog("post", "secret-token")
+		if err != nil {
+			t.Fatalf("openAuditLog() error = %v", err)
+		}
+		if err := audit.Record(guard.AuditRecord{Operation: guard.AuditOperationCreate, Status: "error", Error: "token secret-token rejected"}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+
+		path := filepath.Join(stateDir, "esa-llm-scoped-guard", "audit.jsonl")
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("audit log not created: %v", err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("audit log mode = %o, want 600", info.Mode().Perm())
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "secret-token") || !strings.Contains(string(data), `"command":"post"`) {
+			t.Errorf("unexpected audit log: %s", data)
+		}
+	})
+
+	t.Run("group-writableな監査ログは拒否する", func(t *testing.T) {
+		stateDir := t.TempDir()
+		t.Setenv("XDG_STATE_HOME", stateDir)
+		dir := filepath.Join(stateDir, "esa-llm-scoped-guard")
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, "audit.jsonl")
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, 0o620); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := openAuditLog("post")
+		if err == nil || !strings.Contains(err.Error(), "audit log file is group or world writable") {
+			t.Errorf("openAuditLog() error = %v, want group writable error", err)
+		}
+	})
+
+	t.Run("world-writableなディレクトリは拒否する", func(t *testing.T) {
+		stateDir := t.TempDir()
+		t.Setenv("XDG_STATE_HOME", stateDir)
+		dir := filepath.Join(stateDir, "esa-llm-scoped-guard")
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(dir, 0o777); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := openAuditLog("post")
+		if err == nil || !strings.Contains(err.Error(), "audit log directory is group or world writable") {
+			t.Errorf("openAuditLog() error = %v, want directory error", err)
+		}
+	})
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 1, 28, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "期間", value: "24h", want: now.Add(-24 * time.Hour)},
+		{name: "RFC 3339", value: "2026-01-27T09:00:00Z", want: time.Date(2026, 1, 27, 9, 0, 0, 0, time.UTC)},
+		{name: "日付", value: "2026-01-27", want: time.Date(2026, 1, 27, 0, 0, 0, 0, time.Local)},
+		{name: "負の期間", value: "-1h", wantErr: true},
+		{name: "不正な値", value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSince(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSince() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("parseSince() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
 	"gopkg.in/yaml.v3"
 )
 
@@ -15,10 +24,239 @@ type Config struct {
 		TeamName string `yaml:"team_name"`
 	} `yaml:"esa"`
 	AllowedCategories []string `yaml:"allowed_categories"`
+	// ReadableCategories は読み取り（fetch/read）だけを追加で許可するカテゴリ（任意）
+	// allowed_categories は常に読み取り可能
+	ReadableCategories []string `yaml:"readable_categories"`
+	// DeniedCategories は allowed_categories・readable_categories の配下で、読み取りを含むすべての操作を拒否するカテゴリ（任意）
+	DeniedCategories []string `yaml:"denied_categories"`
+	// CategoryPermissions はカテゴリごとに許可する操作（read・create・update・delete）を限る（任意）
+	// 例: "LLM/Docs": [read, update] なら LLM/Docs 配下では作成・削除を拒否する。許可を狭めることしかできない
+	CategoryPermissions map[string][]string `yaml:"category_permissions"`
+	// Timezone はカテゴリの日付（/yyyy/mm/dd）を決めるタイムゾーン（IANA名、省略時はローカル時刻）
+	Timezone string `yaml:"timezone"`
+	// Timeouts はコマンドごとのタイムアウト（例: post: 2m）。default は個別に指定していないコマンドに使う
+	// 省略時はタイムアウトしない（1リクエストごとの30秒のタイムアウトのみ）
+	Timeouts map[string]string `yaml:"timeouts"`
+	// Retry はesa.io APIのリクエストのリトライ方針（任意、省略したキーは既定値）
+	Retry RetryConfig `yaml:"retry"`
+	// APIBaseURL はesa.io APIのベースURL（任意、省略時は https://api.esa.io）
+	// ローカルの代替サーバー（fake-server など）で動作確認するためのもので、https かループバックの http のみ許可する
+	APIBaseURL string `yaml:"api_base_url"`
+	// TokenFile はアクセストークンを書いたファイルの絶対パス（任意、~/ から始めてもよい）
+	// 設定ファイルと同じ所有者・権限の検証を行い、設定されていれば環境変数 ESA_ACCESS_TOKEN より優先する
+	TokenFile string `yaml:"token_file"`
+	// TokenCommand はアクセストークンを標準出力に書き出すコマンドと引数（任意、シェルは介さない）
+	// 設定されていれば環境変数 ESA_ACCESS_TOKEN より優先する。token_file とは同時に指定できない
+	TokenCommand []string `yaml:"token_command"`
+	// Proxy は proxy コマンドの設定（任意）
+	Proxy ProxyConfig `yaml:"proxy"`
+	// RepositoryCategories はgitリポジトリ（origin の owner/repo）ごとに書き込めるカテゴリ（任意）
+	// 設定すると、割り当てたリポジトリ内ではそのカテゴリ配下の、そのリポジトリのタグが付いた記事にしか書き込めない
+	RepositoryCategories map[string]string `yaml:"repository_categories"`
+	// DefaultRepositoryCategory は repository_categories にないリポジトリ（gitリポジトリの外を含む）で
+	// 書き込めるカテゴリ（任意、省略時は書き込めない）
+	DefaultRepositoryCategory string `yaml:"default_repository_category"`
+
+	// Profiles は名前付きのプロファイル（任意）。複数のesa.ioチームを使う場合に、プロファイルごとに
+	// esa・allowed_categories・readable_categories・denied_categories・category_permissions・token_file・
+	// token_command・repository_categories・default_repository_category を設定する
+	// （プロファイルを使う場合はトップレベルには書けない）
+	Profiles map[string]*Profile `yaml:"profiles"`
+	// DefaultProfile は -profile の指定もリポジトリの割り当てもない場合に使うプロファイル（任意）
+	DefaultProfile string `yaml:"default_profile"`
+
+	// Profile は選択したプロファイルの名前（LoadAndValidateConfigが設定する、プロファイルを使わない場合は空）
+	Profile string `yaml:"-"`
+	// Repository は repository_categories で割り当てたリポジトリ内で実行した場合の owner/repo
+	// （LoadAndValidateConfigが設定する）
+	Repository string `yaml:"-"`
+
+	// Sources は有効なカテゴリのルールごとに、それを定めた設定ファイル（LoadAndValidateConfigが設定する）
+	Sources []RuleSource `yaml:"-"`
+}
+
+// Profile はesa.ioチームごとの設定（Config のトップレベルの同名のキーと同じ意味）
+type Profile struct {
+	Esa struct {
+		TeamName string `yaml:"team_name"`
+	} `yaml:"esa"`
+	AllowedCategories         []string            `yaml:"allowed_categories"`
+	ReadableCategories        []string            `yaml:"readable_categories"`
+	DeniedCategories          []string            `yaml:"denied_categories"`
+	CategoryPermissions       map[string][]string `yaml:"category_permissions"`
+	TokenFile                 string              `yaml:"token_file"`
+	TokenCommand              []string            `yaml:"token_command"`
+	RepositoryCategories      map[string]string   `yaml:"repository_categories"`
+	DefaultRepositoryCategory string              `yaml:"default_repository_category"`
+}
+
+// LoadOptions は設定ファイルの読み込み方を切り替えるオプション
+type LoadOptions struct {
+	// WorkDir はリポジトリの設定・割り当てを探すディレクトリ
+	// 空ならリポジトリの設定を重ねず、repository_categories がある場合は割り当てのないリポジトリとして扱う
+	// （呼び出し元のリポジトリが分からない daemon・serve-mcp で、起動したディレクトリのリポジトリに割り当てない）
+	WorkDir string
+	// Profile は使うプロファイル（-profile、空ならリポジトリの割り当てか default_profile で選ぶ）
+	Profile string
+}
+
+// RuleSource は有効なカテゴリのルールと、それを定めた設定ファイル
+type RuleSource struct {
+	// Key は allowed_categories・readable_categories・denied_categories・category_permissions のいずれか
+	Key      string
+	Category string
+	File     string
+}
+
+// ProjectConfig はリポジトリのルートに置く設定ファイル（.esa-llm-scoped-guard.yaml）
+// ユーザーの設定の allowed_categories を狭めることだけができる
+type ProjectConfig struct {
+	// AllowedCategories はこのリポジトリで書き込めるカテゴリ（ユーザーの allowed_categories の範囲内のみ）
+	AllowedCategories []string `yaml:"allowed_categories"`
+}
+
+// projectConfigFileName はリポジトリのルートに置く設定ファイルの名前
+const projectConfigFileName = ".esa-llm-scoped-guard.yaml"
+
+// 設定のキー（RuleSource.Key）
+const (
+	keyAllowedCategories   = guard.RuleKeyAllowedCategories
+	keyReadableCategories  = guard.RuleKeyReadableCategories
+	keyDeniedCategories    = guard.RuleKeyDeniedCategories
+	keyCategoryPermissions = guard.RuleKeyCategoryPermissions
+)
+
+// RepositoryTag は割り当てたリポジトリ内で実行した場合のリポジトリのタグ（リポジトリ名）を返します（それ以外は空）
+func (c *Config) RepositoryTag() string {
+	if c.Repository == "" {
+		return ""
+	}
+	return path.Base(c.Repository)
+}
+
+// ProxyConfig は proxy コマンドの設定
+type ProxyConfig struct {
+	// ReadPolicy は記事の読み取りの扱い（readable: 読み取り可能なカテゴリの記事のみ返す（既定）、all: そのまま中継する）
+	ReadPolicy string `yaml:"read_policy"`
+}
+
+// RetryConfig はesa.io APIのリクエストのリトライ方針の設定
+// リトライするのはレート制限・5xx・通信エラーのみで、新規作成はレート制限のみ（重複を避けるため）
+type RetryConfig struct {
+	// MaxAttempts は最初の試行を含む最大試行回数（既定 3、1 ならリトライしない）
+	MaxAttempts int `yaml:"max_attempts"`
+	// BaseDelay は1回目のリトライまでの待ち時間（既定 1s、以降は倍々に増やす）
+	BaseDelay string `yaml:"base_delay"`
+	// MaxDelay は1回の待ち時間の上限（既定 30s）。esa.ioがこれより長い待ち時間を指示した場合はリトライしない
+	MaxDelay string `yaml:"max_delay"`
+}
+
+// RetryPolicy はesa.io APIクライアントのリトライ方針を返します（設定していない値は既定値）
+// retryはValidateConfigで検証済みのため、解釈できない値は既定値として扱う
+func (c *Config) RetryPolicy() esa.RetryPolicy {
+	policy := esa.RetryPolicy{MaxAttempts: c.Retry.MaxAttempts}
+	policy.BaseDelay, _ = time.ParseDuration(c.Retry.BaseDelay)
+	policy.MaxDelay, _ = time.ParseDuration(c.Retry.MaxDelay)
+	return policy
+}
+
+// timeoutCommands はタイムアウトを設定できるコマンド（esa.io APIを呼び出す単発のコマンド）
+var timeoutCommands = []string{"init", "diff", "fetch", "read", "list", "post", "patch", "task", "reconcile", "hook"}
+
+// defaultTimeoutKey は個別に指定していないコマンドに使うタイムアウトのキー
+const defaultTimeoutKey = "default"
+
+// ReadScope は読み取りを許可するカテゴリ（allowed_categories と readable_categories の和）を返します
+func (c *Config) ReadScope() []string {
+	scope := make([]string, 0, len(c.AllowedCategories)+len(c.ReadableCategories))
+	scope = append(scope, c.AllowedCategories...)
+	return append(scope, c.ReadableCategories...)
+}
+
+// Policy は有効なカテゴリのルールから、カテゴリごとに許可する操作を判定するポリシーを作成します
+// allowed_categories はすべての操作、readable_categories は読み取りを許可し、
+// category_permissions・denied_categories はその許可を狭める。各ルールには定めた設定ファイルを記録する
+func (c *Config) Policy() *guard.Policy {
+	policy := &guard.Policy{}
+	for _, category := range c.AllowedCategories {
+		policy.Grant(guard.Rule{Key: keyAllowedCategories, Category: category, Operations: guard.Operations,
+			File: c.SourceOf(keyAllowedCategories, category)})
+	}
+	for _, category := range c.ReadableCategories {
+		policy.Grant(guard.Rule{Key: keyReadableCategories, Category: category, Operations: []guard.Operation{guard.OperationRead},
+			File: c.SourceOf(keyReadableCategories, category)})
+	}
+	for _, category := range slices.Sorted(maps.Keys(c.CategoryPermissions)) {
+		// 操作名はValidateConfigで検証済み。解釈できない操作は許可しない（fail closed）
+		var ops []guard.Operation
+		for _, value := range c.CategoryPermissions[category] {
+			if op, err := guard.ParseOperation(value); err == nil {
+				ops = append(ops, op)
+			}
+		}
+		policy.Limit(guard.Rule{Key: keyCategoryPermissions, Category: category, Operations: ops,
+			File: c.SourceOf(keyCategoryPermissions, category)})
+	}
+	for _, category := range c.DeniedCategories {
+		policy.Limit(guard.Rule{Key: keyDeniedCategories, Category: category, File: c.SourceOf(keyDeniedCategories, category)})
+	}
+	return policy
+}
+
+// limitSources は許可を狭めるルール（denied_categories・category_permissions）をpathで定めたものとして返します
+func (c *Config) limitSources(path string) []RuleSource {
+	var sources []RuleSource
+	for _, category := range slices.Sorted(maps.Keys(c.CategoryPermissions)) {
+		sources = append(sources, RuleSource{Key: keyCategoryPermissions, Category: category, File: path})
+	}
+	for _, category := range c.DeniedCategories {
+		sources = append(sources, RuleSource{Key: keyDeniedCategories, Category: category, File: path})
+	}
+	return sources
+}
+
+// Location は日付の決定に使うタイムゾーンを返します
+// timezoneはValidateConfigで検証済みのため、読み込めない場合はローカル時刻にフォールバックする
+func (c *Config) Location() *time.Location {
+	if c.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Timeout はcommandのタイムアウトを返します（設定されていなければ0）
+// timeoutsはValidateConfigで検証済みのため、解釈できない値は0として扱う
+func (c *Config) Timeout(command string) time.Duration {
+	value, ok := c.Timeouts[command]
+	if !ok {
+		value = c.Timeouts[defaultTimeoutKey]
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// SourceOf はkeyのカテゴリのルールを定めた設定ファイルを返します（見つからなければ空）
+func (c *Config) SourceOf(key, category string) string {
+	for _, source := range c.Sources {
+		if source.Key == key && source.Category == category {
+			return source.File
+		}
+	}
+	return ""
 }
 
 // LoadAndValidateConfig は設定ファイルを読み込み、検証します
-func LoadAndValidateConfig(path string) (*Config, error) {
+// プロファイルがあれば opts.Profile、リポジトリの割り当て、default_profile の順に選んだプロファイルの設定を使う。
+// opts.WorkDirが空でなければ、そのディレクトリを含むgitリポジトリのルートの .esa-llm-scoped-guard.yaml を重ねる。
+// 有効なカテゴリのルールごとに、それを定めたファイルを Sources に記録する
+func LoadAndValidateConfig(path string, opts LoadOptions) (*Config, error) {
 	// symlinkを解決
 	realPath, err := filepath.EvalSymlinks(path)
 	if err != nil {
@@ -41,11 +279,269 @@ func LoadAndValidateConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
+	// プロファイルを選ぶ
+	workDir := opts.WorkDir
+	if len(config.Profiles) > 0 {
+		name, err := config.selectProfile(opts.Profile, workDir)
+		if err != nil {
+			return nil, err
+		}
+		config.applyProfile(name)
+	} else if opts.Profile != "" {
+		return nil, fmt.Errorf("profile %s is not defined (the config has no profiles)", opts.Profile)
+	}
+
+	config.Sources = nil
+	for _, category := range config.AllowedCategories {
+		config.Sources = append(config.Sources, RuleSource{Key: keyAllowedCategories, Category: category, File: path})
+	}
+	for _, category := range config.ReadableCategories {
+		config.Sources = append(config.Sources, RuleSource{Key: keyReadableCategories, Category: category, File: path})
+	}
+
+	// 許可を狭めるルールはリポジトリの設定・割り当てで変わらない
+	limits := config.limitSources(path)
+	if workDir == "" {
+		if len(config.RepositoryCategories) > 0 {
+			config.applyRepositoryCategory("", path)
+		}
+		config.Sources = append(config.Sources, limits...)
+		return config, nil
+	}
+
+	// リポジトリの設定を重ねる（任意）
+	projectPath, err := findProjectConfig(workDir)
+	if err != nil {
+		return nil, err
+	}
+	if projectPath != "" {
+		project, err := loadProjectConfig(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := ValidateProjectConfig(project, config); err != nil {
+			return nil, fmt.Errorf("%s: %w", projectPath, err)
+		}
+		config.applyProjectConfig(project, projectPath)
+	}
+
+	// リポジトリに割り当てたカテゴリに狭める（任意）
+	if len(config.RepositoryCategories) > 0 {
+		// origin を取得できない場合（gitリポジトリの外など）は割り当てのないリポジトリとして扱う
+		repository, _ := guard.GetRepository(workDir)
+		config.applyRepositoryCategory(repository, path)
+	}
+
+	config.Sources = append(config.Sources, limits...)
 	return config, nil
 }
 
+// selectProfile は使うプロファイルの名前を返します
+// requestedが空なら、プロファイルが1つだけならそれ、workDirのリポジトリを repository_categories に持つプロファイル、
+// default_profile の順に選ぶ。どれでも決まらなければエラー（チームを取り違えないよう推測しない）
+func (c *Config) selectProfile(requested, workDir string) (string, error) {
+	if requested != "" {
+		if _, ok := c.Profiles[requested]; !ok {
+			return "", fmt.Errorf("profile %s is not defined (defined: %s)", requested, strings.Join(slices.Sorted(maps.Keys(c.Profiles)), ", "))
+		}
+		return requested, nil
+	}
+	if len(c.Profiles) == 1 {
+		for name := range c.Profiles {
+			return name, nil
+		}
+	}
+
+	if workDir != "" {
+		if repository, err := guard.GetRepository(workDir); err == nil {
+			// 同じリポジトリを複数のプロファイルに割り当てることは ValidateConfig で拒否している
+			for _, name := range slices.Sorted(maps.Keys(c.Profiles)) {
+				for repo := range c.Profiles[name].RepositoryCategories {
+					if strings.EqualFold(repo, repository) {
+						return name, nil
+					}
+				}
+			}
+		}
+	}
+
+	if c.DefaultProfile != "" {
+		return c.DefaultProfile, nil
+	}
+	return "", fmt.Errorf("multiple profiles are defined; select one with -profile (or set default_profile or map this repository in repository_categories)")
+}
+
+// applyProfile はプロファイルの設定をトップレベルの設定として使います
+func (c *Config) applyProfile(name string) {
+	c.Profiles[name].applyTo(c)
+	c.Profile = name
+}
+
+// withProfile はトップレベルの設定をプロファイルの設定で置き換えたコピーを返します（検証用）
+func (c *Config) withProfile(profile *Profile) *Config {
+	copied := *c
+	copied.Profiles = nil
+	copied.DefaultProfile = ""
+	profile.applyTo(&copied)
+	return &copied
+}
+
+// applyTo はプロファイルの設定をcのトップレベルの同名のキーに設定します
+func (p *Profile) applyTo(c *Config) {
+	c.Esa = p.Esa
+	c.AllowedCategories = p.AllowedCategories
+	c.ReadableCategories = p.ReadableCategories
+	c.DeniedCategories = p.DeniedCategories
+	c.CategoryPermissions = p.CategoryPermissions
+	c.TokenFile = p.TokenFile
+	c.TokenCommand = p.TokenCommand
+	c.RepositoryCategories = p.RepositoryCategories
+	c.DefaultRepositoryCategory = p.DefaultRepositoryCategory
+}
+
+// applyRepositoryCategory は書き込めるカテゴリを、repositoryに割り当てたカテゴリ
+// （割り当てがなければ default_repository_category、それもなければなし）の範囲に狭めます
+// 狭めた結果書き込めなくなったカテゴリは読み取り専用のカテゴリとして残す
+func (c *Config) applyRepositoryCategory(repository, configPath string) {
+	category := ""
+	for repo, bound := range c.RepositoryCategories {
+		// GitHubのowner/repoは大文字・小文字を区別しない
+		if repository != "" && strings.EqualFold(repo, repository) {
+			category = bound
+			c.Repository = repository
+			break
+		}
+	}
+	if c.Repository == "" {
+		category = c.DefaultRepositoryCategory
+	}
+
+	// 書き込めるカテゴリとの共通部分（狭い方）だけを残す
+	var allowed []RuleSource
+	add := func(category, file string) {
+		if !slices.ContainsFunc(allowed, func(s RuleSource) bool { return s.Category == category }) {
+			allowed = append(allowed, RuleSource{Key: keyAllowedCategories, Category: category, File: file})
+		}
+	}
+	for _, current := range c.AllowedCategories {
+		if category == "" {
+			break
+		}
+		if ok, _ := guard.IsAllowedCategory(current, []string{category}); ok {
+			add(current, c.SourceOf(keyAllowedCategories, current))
+		} else if ok, _ := guard.IsAllowedCategory(category, []string{current}); ok {
+			add(category, configPath)
+		}
+	}
+
+	readable := c.ReadableCategories
+	sources := allowed
+	c.AllowedCategories = nil
+	for _, source := range allowed {
+		c.AllowedCategories = append(c.AllowedCategories, source.Category)
+	}
+	for _, source := range c.Sources {
+		if source.Key == keyAllowedCategories {
+			readable = append(readable, source.Category)
+		}
+		sources = append(sources, RuleSource{Key: keyReadableCategories, Category: source.Category, File: source.File})
+	}
+	c.ReadableCategories = readable
+	c.Sources = sources
+}
+
+// applyProjectConfig はリポジトリの設定で書き込めるカテゴリを置き換えます
+// ユーザーの設定で書き込めたカテゴリは読み取り専用として残す（読み取りの範囲は変わらない）
+func (c *Config) applyProjectConfig(project *ProjectConfig, projectPath string) {
+	userAllowed := c.AllowedCategories
+	c.AllowedCategories = project.AllowedCategories
+	c.ReadableCategories = append(c.ReadableCategories, userAllowed...)
+
+	sources := make([]RuleSource, 0, len(c.AllowedCategories)+len(c.ReadableCategories))
+	for _, category := range c.AllowedCategories {
+		sources = append(sources, RuleSource{Key: keyAllowedCategories, Category: category, File: projectPath})
+	}
+	for _, source := range c.Sources {
+		sources = append(sources, RuleSource{Key: keyReadableCategories, Category: source.Category, File: source.File})
+	}
+	c.Sources = sources
+}
+
+// findProjectConfig はworkDirを含むgitリポジトリのルートにある .esa-llm-scoped-guard.yaml のパスを返します
+// gitリポジトリの外、またはルートにファイルがない場合は空を返す
+func findProjectConfig(workDir string) (string, error) {
+	dir, err := filepath.Abs(workDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve working directory: %w", err)
+	}
+	for {
+		// .git はディレクトリ（通常のリポジトリ）かファイル（worktree・submodule）
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+
+	path := filepath.Join(dir, projectConfigFileName)
+	if _, err := os.Lstat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// loadProjectConfig はリポジトリの設定ファイルを読み込みます
+// 狭めることしかできないため権限の検証は行わないが、知らないキー（team_name など）はエラーにする
+func loadProjectConfig(path string) (*ProjectConfig, error) {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve symlink: %w", err)
+	}
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat project config file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("project config file is not a regular file: %s", path)
+	}
+
+	data, err := readConfigData(realPath)
+	if err != nil {
+		return nil, err
+	}
+	var project ProjectConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&project); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &project, nil
+}
+
 // loadConfig は設定ファイルを読み込みます（内部用）
 func loadConfig(path string) (*Config, error) {
+	data, err := readConfigData(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAMLをパース
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	return &config, nil
+}
+
+// readConfigData は設定ファイルの内容をサイズ制限付きで読み込みます
+func readConfigData(path string) ([]byte, error) {
 	// ファイルを開く
 	file, err := os.Open(path)
 	if err != nil {
@@ -66,11 +562,5 @@ func loadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("config file size exceeds 10MB")
 	}
 
-	// YAMLをパース
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
-	}
-
-	return &config, nil
+	return data, nil
 }
@@ -2,9 +2,15 @@ package main
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
 )
 
 func TestLoadAndValidateConfig(t *testing.T) {
@@ -101,7 +107,7 @@ allowed_categories:
 			}
 
 			// 設定を読み込み
-			config, err := LoadAndValidateConfig(configPath)
+			config, err := LoadAndValidateConfig(configPath, LoadOptions{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("LoadAndValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -122,3 +128,383 @@ allowed_categories:
 		})
 	}
 }
+
+func TestLoadAndValidateConfig_ProjectConfig(t *testing.T) {
+	const userYAML = `esa:
+  team_name: "my-team"
+allowed_categories:
+  - "LLM/Tasks"
+  - "Draft"
+readable_categories:
+  - "Docs"
+`
+	tests := []struct {
+		name        string
+		projectYAML string // 空ならリポジトリの設定ファイルを置かない
+		noGit       bool
+		wantErr     bool
+		errMsg      string
+		wantAllowed []string
+	}{
+		{name: "リポジトリの設定なし", wantAllowed: []string{"LLM/Tasks", "Draft"}},
+		{name: "許可カテゴリを狭める", projectYAML: "allowed_categories:\n  - LLM/Tasks/my-repo\n", wantAllowed: []string{"LLM/Tasks/my-repo"}},
+		{name: "gitリポジトリの外では使わない", projectYAML: "allowed_categories:\n  - LLM/Tasks/my-repo\n", noGit: true, wantAllowed: []string{"LLM/Tasks", "Draft"}},
+		{name: "許可カテゴリを広げる", projectYAML: "allowed_categories:\n  - Private\n", wantErr: true, errMsg: "can only narrow"},
+		{name: "境界を越えるカテゴリ", projectYAML: "allowed_categories:\n  - LLM/Tasks-evil\n", wantErr: true, errMsg: "can only narrow"},
+		{name: "読み取り専用カテゴリへの書き込み", projectYAML: "allowed_categories:\n  - Docs\n", wantErr: true, errMsg: "can only narrow"},
+		{name: "allowed_categoriesが空", projectYAML: "allowed_categories: []\n", wantErr: true, errMsg: "allowed_categories cannot be empty"},
+		{name: "ユーザーの設定のキー", projectYAML: "allowed_categories:\n  - LLM/Tasks\nesa:\n  team_name: other\n", wantErr: true, errMsg: "field esa not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userDir := t.TempDir()
+			userPath := filepath.Join(userDir, "config.yaml")
+			if err := os.WriteFile(userPath, []byte(userYAML), 0600); err != nil {
+				t.Fatal(err)
+			}
+			repoDir := t.TempDir()
+			if !tt.noGit {
+				if err := os.Mkdir(filepath.Join(repoDir, ".git"), 0700); err != nil {
+					t.Fatal(err)
+				}
+			}
+			projectPath := filepath.Join(repoDir, projectConfigFileName)
+			if tt.projectYAML != "" {
+				if err := os.WriteFile(projectPath, []byte(tt.projectYAML), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// リポジトリのサブディレクトリから実行してもルートの設定を使う
+			workDir := filepath.Join(repoDir, "sub", "dir")
+			if err := os.MkdirAll(workDir, 0700); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := LoadAndValidateConfig(userPath, LoadOptions{WorkDir: workDir})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadAndValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("LoadAndValidateConfig() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+
+			if strings.Join(config.AllowedCategories, ",") != strings.Join(tt.wantAllowed, ",") {
+				t.Errorf("AllowedCategories = %v, want %v", config.AllowedCategories, tt.wantAllowed)
+			}
+			// 読み取りの範囲は狭めても変わらない
+			for _, category := range []string{"LLM/Tasks", "Draft", "Docs"} {
+				if !slices.Contains(config.ReadScope(), category) {
+					t.Errorf("ReadScope() = %v, want to contain %s", config.ReadScope(), category)
+				}
+			}
+
+			// 有効なルールごとに、それを定めたファイルを記録する
+			wantAllowedFrom := userPath
+			if tt.projectYAML != "" && !tt.noGit {
+				wantAllowedFrom = projectPath
+			}
+			for _, category := range config.AllowedCategories {
+				if got := config.SourceOf(keyAllowedCategories, category); got != wantAllowedFrom {
+					t.Errorf("SourceOf(allowed_categories, %s) = %q, want %q", category, got, wantAllowedFrom)
+				}
+			}
+			for _, category := range config.ReadableCategories {
+				if got := config.SourceOf(keyReadableCategories, category); got != userPath {
+					t.Errorf("SourceOf(readable_categories, %s) = %q, want %q", category, got, userPath)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadAndValidateConfig_RepositoryCategories(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	const userYAML = `esa:
+  team_name: "my-team"
+allowed_categories:
+  - "LLM/Tasks"
+  - "Draft"
+repository_categories:
+  owner/repo-a: "LLM/Tasks/repo-a"
+`
+	tests := []struct {
+		name           string
+		extraYAML      string
+		remote         string // 空ならoriginを設定しない
+		noWorkDir      bool   // daemon・serve-mcp のように呼び出し元のリポジトリが分からない場合
+		wantAllowed    []string
+		wantRepository string
+		wantTag        string
+	}{
+		{name: "割り当てたリポジトリ", remote: "git@github.com:owner/repo-a.git", wantAllowed: []string{"LLM/Tasks/repo-a"}, wantRepository: "owner/repo-a", wantTag: "repo-a"},
+		{name: "大文字・小文字の違い", remote: "https://github.com/Owner/Repo-A", wantAllowed: []string{"LLM/Tasks/repo-a"}, wantRepository: "Owner/Repo-A", wantTag: "Repo-A"},
+		{name: "割り当てのないリポジトリは書き込めない", remote: "https://github.com/owner/repo-b.git", wantAllowed: nil},
+		{name: "originのないリポジトリは書き込めない", wantAllowed: nil},
+		{name: "割り当てのないリポジトリは既定のカテゴリ", extraYAML: "default_repository_category: Draft/misc\n", remote: "https://github.com/owner/repo-b.git", wantAllowed: []string{"Draft/misc"}},
+		{name: "作業ディレクトリなしでは割り当てない", remote: "git@github.com:owner/repo-a.git", noWorkDir: true, wantAllowed: nil},
+		{name: "作業ディレクトリなしでは既定のカテゴリ", extraYAML: "default_repository_category: Draft/misc\n", remote: "git@github.com:owner/repo-a.git", noWorkDir: true, wantAllowed: []string{"Draft/misc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(userPath, []byte(userYAML+tt.extraYAML), 0600); err != nil {
+				t.Fatal(err)
+			}
+			repoDir := t.TempDir()
+			git := func(args ...string) {
+				cmd := exec.Command("git", args...)
+				cmd.Dir = repoDir
+				if output, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("git %v: %v\n%s", args, err, output)
+				}
+			}
+			git("init", "-q")
+			if tt.remote != "" {
+				git("remote", "add", "origin", tt.remote)
+			}
+
+			opts := LoadOptions{WorkDir: repoDir}
+			if tt.noWorkDir {
+				opts.WorkDir = ""
+			}
+			config, err := LoadAndValidateConfig(userPath, opts)
+			if err != nil {
+				t.Fatalf("LoadAndValidateConfig() error = %v", err)
+			}
+			if strings.Join(config.AllowedCategories, ",") != strings.Join(tt.wantAllowed, ",") {
+				t.Errorf("AllowedCategories = %v, want %v", config.AllowedCategories, tt.wantAllowed)
+			}
+			if config.Repository != tt.wantRepository {
+				t.Errorf("Repository = %q, want %q", config.Repository, tt.wantRepository)
+			}
+			// 割り当てたリポジトリでは、そのリポジトリのタグが付いた記事だけを更新する
+			if got := config.RepositoryTag(); got != tt.wantTag {
+				t.Errorf("RepositoryTag() = %q, want %q", got, tt.wantTag)
+			}
+			// 書き込めなくなったカテゴリも読み取りはできる
+			for _, category := range []string{"LLM/Tasks", "Draft"} {
+				if !slices.Contains(config.ReadScope(), category) {
+					t.Errorf("ReadScope() = %v, want to contain %s", config.ReadScope(), category)
+				}
+			}
+			for _, category := range config.AllowedCategories {
+				if got := config.SourceOf(keyAllowedCategories, category); got != userPath {
+					t.Errorf("SourceOf(allowed_categories, %s) = %q, want %q", category, got, userPath)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadAndValidateConfig_Profiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	const profilesYAML = `profiles:
+  work:
+    esa:
+      team_name: "work-team"
+    allowed_categories:
+      - "LLM/Tasks"
+    token_command: ["op", "read", "op://work/esa/token"]
+    repository_categories:
+      owner/work-repo: "LLM/Tasks/work-repo"
+  private:
+    esa:
+      team_name: "my-team"
+    allowed_categories:
+      - "Notes"
+    readable_categories:
+      - "Docs"
+`
+	tests := []struct {
+		name        string
+		extraYAML   string
+		profile     string // -profile
+		remote      string // 空ならoriginを設定しない
+		wantProfile string
+		wantTeam    string
+		wantAllowed []string
+		errMsg      string // 空ならエラーにならない
+	}{
+		{name: "-profileで選ぶ", profile: "private", wantProfile: "private", wantTeam: "my-team", wantAllowed: []string{"Notes"}},
+		{name: "リポジトリの割り当てで選ぶ", remote: "git@github.com:owner/work-repo.git", wantProfile: "work", wantTeam: "work-team", wantAllowed: []string{"LLM/Tasks/work-repo"}},
+		{name: "-profileはリポジトリの割り当てより優先", profile: "private", remote: "git@github.com:owner/work-repo.git", wantProfile: "private", wantTeam: "my-team", wantAllowed: []string{"Notes"}},
+		{name: "default_profileで選ぶ", extraYAML: "default_profile: private\n", remote: "https://github.com/owner/other.git", wantProfile: "private", wantTeam: "my-team", wantAllowed: []string{"Notes"}},
+		{name: "選べない", remote: "https://github.com/owner/other.git", errMsg: "select one with -profile"},
+		{name: "定義されていないプロファイル", profile: "unknown", errMsg: "profile unknown is not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(userPath, []byte(profilesYAML+tt.extraYAML), 0600); err != nil {
+				t.Fatal(err)
+			}
+			repoDir := t.TempDir()
+			git := func(args ...string) {
+				cmd := exec.Command("git", args...)
+				cmd.Dir = repoDir
+				if output, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("git %v: %v\n%s", args, err, output)
+				}
+			}
+			git("init", "-q")
+			if tt.remote != "" {
+				git("remote", "add", "origin", tt.remote)
+			}
+
+			config, err := LoadAndValidateConfig(userPath, LoadOptions{WorkDir: repoDir, Profile: tt.profile})
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("LoadAndValidateConfig() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadAndValidateConfig() error = %v", err)
+			}
+			if config.Profile != tt.wantProfile || config.Esa.TeamName != tt.wantTeam {
+				t.Errorf("Profile = %q, TeamName = %q, want %q, %q", config.Profile, config.Esa.TeamName, tt.wantProfile, tt.wantTeam)
+			}
+			if strings.Join(config.AllowedCategories, ",") != strings.Join(tt.wantAllowed, ",") {
+				t.Errorf("AllowedCategories = %v, want %v", config.AllowedCategories, tt.wantAllowed)
+			}
+			// トークンの取得元もプロファイルのものを使う
+			if tt.wantProfile == "work" && len(config.TokenCommand) == 0 {
+				t.Errorf("TokenCommand = %v, want the work profile's command", config.TokenCommand)
+			}
+			if tt.wantProfile == "private" && (len(config.TokenCommand) != 0 || !slices.Contains(config.ReadScope(), "Docs")) {
+				t.Errorf("TokenCommand = %v, ReadScope() = %v, want the private profile's settings", config.TokenCommand, config.ReadScope())
+			}
+		})
+	}
+
+	t.Run("プロファイルがない設定で-profileを指定", func(t *testing.T) {
+		userPath := filepath.Join(t.TempDir(), "config.yaml")
+		yaml := "esa:\n  team_name: my-team\nallowed_categories:\n  - LLM/Tasks\n"
+		if err := os.WriteFile(userPath, []byte(yaml), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadAndValidateConfig(userPath, LoadOptions{Profile: "work"}); err == nil {
+			t.Error("LoadAndValidateConfig() error = nil, want error for an undefined profile")
+		}
+	})
+}
+
+func TestConfig_Policy(t *testing.T) {
+	const userYAML = `esa:
+  team_name: "my-team"
+allowed_categories:
+  - "LLM"
+readable_categories:
+  - "Docs"
+denied_categories:
+  - "LLM/Secrets"
+  - "Docs/Private"
+category_permissions:
+  "LLM/Docs": [read, update]
+  "LLM/Inbox": [create]
+`
+	userPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(userPath, []byte(userYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadAndValidateConfig(userPath, LoadOptions{})
+	if err != nil {
+		t.Fatalf("LoadAndValidateConfig() error = %v", err)
+	}
+	policy := config.Policy()
+
+	tests := []struct {
+		name     string
+		category string
+		op       guard.Operation
+		want     bool
+		wantRule string // 判定を決めたルール（空なら当てはまるルールなし）
+	}{
+		{"許可カテゴリへの作成", "LLM/Tasks", guard.OperationCreate, true, `allowed_categories "LLM"`},
+		{"拒否カテゴリへの作成", "LLM/Secrets/keys", guard.OperationCreate, false, `denied_categories "LLM/Secrets"`},
+		{"拒否カテゴリの読み取り", "LLM/Secrets", guard.OperationRead, false, `denied_categories "LLM/Secrets"`},
+		{"読み取り専用カテゴリ内の拒否", "Docs/Private/2026", guard.OperationRead, false, `denied_categories "Docs/Private"`},
+		{"読み取り専用カテゴリの読み取り", "Docs/Design", guard.OperationRead, true, `readable_categories "Docs"`},
+		{"読み取り専用カテゴリへの作成", "Docs/Design", guard.OperationCreate, false, `readable_categories "Docs"`},
+		{"更新のみのカテゴリの更新", "LLM/Docs/api", guard.OperationUpdate, true, `allowed_categories "LLM"`},
+		{"更新のみのカテゴリへの作成", "LLM/Docs/api", guard.OperationCreate, false, `category_permissions "LLM/Docs"`},
+		{"作成のみのカテゴリの削除", "LLM/Inbox", guard.OperationDelete, false, `category_permissions "LLM/Inbox"`},
+		{"許可カテゴリ外", "Private", guard.OperationRead, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := policy.Evaluate(tt.category, tt.op)
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if decision.Allowed != tt.want {
+				t.Errorf("Evaluate(%s, %s).Allowed = %v, want %v (%s)", tt.category, tt.op, decision.Allowed, tt.want, decision.Reason())
+			}
+			if tt.wantRule == "" {
+				if decision.Rule != nil {
+					t.Errorf("Rule = %s, want nil", decision.Rule)
+				}
+				return
+			}
+			if decision.Rule == nil || decision.Rule.String() != tt.wantRule {
+				t.Fatalf("Rule = %v, want %s", decision.Rule, tt.wantRule)
+			}
+			// どの設定ファイルのルールで判定したかを示す
+			if !strings.HasSuffix(decision.Reason(), " in "+userPath) {
+				t.Errorf("Reason() = %q, want the config path", decision.Reason())
+			}
+		})
+	}
+}
+
+func TestConfig_Timeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts map[string]string
+		command  string
+		want     time.Duration
+	}{
+		{name: "コマンドごとの設定", timeouts: map[string]string{"default": "1m", "post": "5m"}, command: "post", want: 5 * time.Minute},
+		{name: "defaultを使う", timeouts: map[string]string{"default": "1m", "post": "5m"}, command: "fetch", want: time.Minute},
+		{name: "設定なし", timeouts: nil, command: "post", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Timeouts: tt.timeouts}
+			if got := config.Timeout(tt.command); got != tt.want {
+				t.Errorf("Timeout(%q) = %v, want %v", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_RetryPolicy(t *testing.T) {
+	tests := []struct {
+		name  string
+		retry RetryConfig
+		want  esa.RetryPolicy
+	}{
+		{name: "設定なしは既定値（ゼロ値）", retry: RetryConfig{}, want: esa.RetryPolicy{}},
+		{name: "すべて設定", retry: RetryConfig{MaxAttempts: 1, BaseDelay: "500ms", MaxDelay: "10s"}, want: esa.RetryPolicy{MaxAttempts: 1, BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&Config{Retry: tt.retry}).RetryPolicy()
+			if got.MaxAttempts != tt.want.MaxAttempts || got.BaseDelay != tt.want.BaseDelay || got.MaxDelay != tt.want.MaxDelay {
+				t.Errorf("RetryPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,29 +2,41 @@ package main
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
 	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/proxy"
 )
 
 // ValidateConfigFile は設定ファイルのセキュリティ検証を行います
 func ValidateConfigFile(path string) error {
+	return validatePrivateFile(path, "config")
+}
+
+// validatePrivateFile は現在のユーザーだけが書き込めるファイルであることを検証します
+// labelはエラーメッセージに使うファイルの種類（"config" など）
+func validatePrivateFile(path string, label string) error {
 	// ファイル情報を取得
 	fileInfo, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("failed to stat config file: %w", err)
+		return fmt.Errorf("failed to stat %s file: %w", label, err)
 	}
 
 	// 通常ファイルかチェック
 	if !fileInfo.Mode().IsRegular() {
-		return fmt.Errorf("config file is not a regular file: %s", path)
+		return fmt.Errorf("%s file is not a regular file: %s", label, path)
 	}
 
 	// ファイル権限をチェック（group/world-writableでないこと）
 	if fileInfo.Mode().Perm()&0022 != 0 {
-		return fmt.Errorf("config file is group or world writable: %s", path)
+		return fmt.Errorf("%s file is group or world writable: %s", label, path)
 	}
 
 	// ファイルの所有者をチェック（現在のユーザーが所有していること）
@@ -33,33 +45,111 @@ func ValidateConfigFile(path string) error {
 		return fmt.Errorf("failed to get file ownership info")
 	}
 	if stat.Uid != uint32(os.Getuid()) {
-		return fmt.Errorf("config file is not owned by current user")
+		return fmt.Errorf("%s file is not owned by current user", label)
 	}
 
-	// 設定ディレクトリの権限もチェック
-	dirInfo, err := os.Stat(filepath.Dir(path))
+	// ディレクトリの権限もチェック
+	return validatePrivateDir(filepath.Dir(path), label)
+}
+
+// validatePrivateDir はディレクトリがgroup/world-writableでないことを検証します
+func validatePrivateDir(dir string, label string) error {
+	dirInfo, err := os.Stat(dir)
 	if err != nil {
-		return fmt.Errorf("failed to stat config directory: %w", err)
+		return fmt.Errorf("failed to stat %s directory: %w", label, err)
 	}
 	if dirInfo.Mode().Perm()&0022 != 0 {
-		return fmt.Errorf("config directory is group or world writable")
+		return fmt.Errorf("%s directory is group or world writable", label)
 	}
 
 	return nil
 }
 
 // ValidateConfig は設定の妥当性を検証します
+// プロファイルがある場合は、プロファイルごとにチーム・トークンの取得元・カテゴリを検証する
 func ValidateConfig(config *Config) error {
+	if len(config.Profiles) > 0 {
+		if err := validateProfiles(config); err != nil {
+			return err
+		}
+	} else {
+		if config.DefaultProfile != "" {
+			return fmt.Errorf("default_profile requires profiles")
+		}
+		if err := validateProfileConfig(config); err != nil {
+			return err
+		}
+	}
+
+	// api_base_urlの検証（トークンを平文で外部に送らないよう https かループバックの http のみ）
+	if config.APIBaseURL != "" {
+		if err := esa.ValidateBaseURL(config.APIBaseURL); err != nil {
+			return fmt.Errorf("invalid api_base_url: %w", err)
+		}
+	}
+
+	// timezoneの検証（IANAタイムゾーン名）
+	if config.Timezone != "" {
+		if _, err := time.LoadLocation(config.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %s: %w", config.Timezone, err)
+		}
+	}
+
+	// proxy.read_policyの検証
+	if _, err := proxy.ParseReadPolicy(config.Proxy.ReadPolicy); err != nil {
+		return fmt.Errorf("invalid proxy.read_policy: %w", err)
+	}
+
+	// timeoutsの検証（キーはdefaultかタイムアウトを設定できるコマンド、値は正の期間）
+	for _, command := range slices.Sorted(maps.Keys(config.Timeouts)) {
+		value := config.Timeouts[command]
+		if command != defaultTimeoutKey && !slices.Contains(timeoutCommands, command) {
+			return fmt.Errorf("invalid timeouts key %s (must be %s or one of %s)", command, defaultTimeoutKey, strings.Join(timeoutCommands, ", "))
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid timeout for %s: %q (must be a positive duration like 30s or 2m)", command, value)
+		}
+	}
+
+	// retryの検証（省略した値は既定値）
+	if config.Retry.MaxAttempts < 0 {
+		return fmt.Errorf("invalid retry.max_attempts: %d (must be 1 or more; 1 disables retries)", config.Retry.MaxAttempts)
+	}
+	delays := map[string]string{"base_delay": config.Retry.BaseDelay, "max_delay": config.Retry.MaxDelay}
+	for _, key := range slices.Sorted(maps.Keys(delays)) {
+		if delays[key] == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(delays[key]); err != nil || d <= 0 {
+			return fmt.Errorf("invalid retry.%s: %q (must be a positive duration like 1s or 30s)", key, delays[key])
+		}
+	}
+
+	return nil
+}
+
+// validateProfileConfig はプロファイルごとの設定（トップレベル、またはプロファイルを適用したコピー）を検証します
+func validateProfileConfig(config *Config) error {
 	// team_nameの検証
 	if config.Esa.TeamName == "" {
 		return fmt.Errorf("team_name cannot be empty")
 	}
 
 	// team_nameは [A-Za-z0-9_-] のみ許可
-	for _, c := range config.Esa.TeamName {
-		if !((c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_' || c == '-') {
-			return fmt.Errorf("team_name contains invalid characters (only A-Z, a-z, 0-9, _, - allowed): %s", config.Esa.TeamName)
-		}
+	if !guard.IsTeamName(config.Esa.TeamName) {
+		return fmt.Errorf("team_name contains invalid characters (only A-Z, a-z, 0-9, _, - allowed): %s", config.Esa.TeamName)
+	}
+
+	// トークンの取得元の検証（どちらか一方のみ）
+	if config.TokenFile != "" && len(config.TokenCommand) > 0 {
+		return fmt.Errorf("token_file and token_command cannot be used together")
+	}
+	if config.TokenFile != "" && !strings.HasPrefix(config.TokenFile, "~/") && !filepath.IsAbs(config.TokenFile) {
+		return fmt.Errorf("token_file must be an absolute path or start with ~/: %s", config.TokenFile)
+	}
+	if len(config.TokenCommand) > 0 && config.TokenCommand[0] == "" {
+		return fmt.Errorf("token_command must start with a command name")
 	}
 
 	// allowed_categoriesの検証（fail closed）
@@ -76,5 +166,190 @@ func ValidateConfig(config *Config) error {
 		config.AllowedCategories[i] = normalized
 	}
 
+	// readable_categoriesの検証（任意）
+	for i, category := range config.ReadableCategories {
+		normalized, err := guard.NormalizeCategory(category)
+		if err != nil {
+			return fmt.Errorf("invalid readable category %s: %w", category, err)
+		}
+		config.ReadableCategories[i] = normalized
+	}
+
+	// denied_categories・category_permissionsの検証（任意、allowed_categories か readable_categories の範囲内）
+	for i, category := range config.DeniedCategories {
+		normalized, err := validateLimitCategory(category, config.ReadScope())
+		if err != nil {
+			return fmt.Errorf("invalid denied category %s: %w", category, err)
+		}
+		config.DeniedCategories[i] = normalized
+	}
+	if config.CategoryPermissions != nil {
+		permissions := make(map[string][]string, len(config.CategoryPermissions))
+		for _, category := range slices.Sorted(maps.Keys(config.CategoryPermissions)) {
+			normalized, err := validateLimitCategory(category, config.ReadScope())
+			if err != nil {
+				return fmt.Errorf("invalid category_permissions key %s: %w", category, err)
+			}
+			if _, ok := permissions[normalized]; ok {
+				return fmt.Errorf("category_permissions has duplicate categories for %s", normalized)
+			}
+			ops := config.CategoryPermissions[category]
+			if len(ops) == 0 {
+				return fmt.Errorf("category_permissions for %s cannot be empty (use denied_categories to deny all operations)", category)
+			}
+			for _, op := range ops {
+				if _, err := guard.ParseOperation(op); err != nil {
+					return fmt.Errorf("invalid category_permissions for %s: %w", category, err)
+				}
+			}
+			permissions[normalized] = ops
+		}
+		config.CategoryPermissions = permissions
+	}
+
+	// repository_categoriesの検証（キーは owner/repo、カテゴリは allowed_categories の範囲内）
+	seen := make(map[string]string)
+	for _, repository := range slices.Sorted(maps.Keys(config.RepositoryCategories)) {
+		if !isRepositorySlug(repository) {
+			return fmt.Errorf("invalid repository_categories key %s (must be owner/repo)", repository)
+		}
+		if other, ok := seen[strings.ToLower(repository)]; ok {
+			return fmt.Errorf("repository_categories has duplicate repositories %s and %s", other, repository)
+		}
+		seen[strings.ToLower(repository)] = repository
+
+		normalized, err := validateBoundCategory(config.RepositoryCategories[repository], config.AllowedCategories)
+		if err != nil {
+			return fmt.Errorf("invalid repository category for %s: %w", repository, err)
+		}
+		config.RepositoryCategories[repository] = normalized
+	}
+	if config.DefaultRepositoryCategory != "" {
+		if len(config.RepositoryCategories) == 0 {
+			return fmt.Errorf("default_repository_category requires repository_categories")
+		}
+		normalized, err := validateBoundCategory(config.DefaultRepositoryCategory, config.AllowedCategories)
+		if err != nil {
+			return fmt.Errorf("invalid default_repository_category: %w", err)
+		}
+		config.DefaultRepositoryCategory = normalized
+	}
+
+	return nil
+}
+
+// validateProfiles はすべてのプロファイルを検証します
+// プロファイルを使う場合、プロファイルごとの設定をトップレベルに書くことはできない（どのチームの設定か曖昧になるため）
+func validateProfiles(config *Config) error {
+	if config.Esa.TeamName != "" || len(config.AllowedCategories) > 0 || len(config.ReadableCategories) > 0 ||
+		len(config.DeniedCategories) > 0 || len(config.CategoryPermissions) > 0 ||
+		config.TokenFile != "" || len(config.TokenCommand) > 0 || len(config.RepositoryCategories) > 0 || config.DefaultRepositoryCategory != "" {
+		return fmt.Errorf("esa, allowed_categories, readable_categories, denied_categories, category_permissions, token_file, token_command, repository_categories and default_repository_category must be set in each profile when profiles are used")
+	}
+
+	repositories := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(config.Profiles)) {
+		if !guard.IsTeamName(name) {
+			return fmt.Errorf("invalid profile name %q (only A-Z, a-z, 0-9, _, - allowed)", name)
+		}
+		profile := config.Profiles[name]
+		if profile == nil {
+			return fmt.Errorf("profile %s is empty", name)
+		}
+		profiled := config.withProfile(profile)
+		if err := validateProfileConfig(profiled); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+		// カテゴリ・リポジトリの割り当ては検証時に正規化した値を使う
+		profile.DefaultRepositoryCategory = profiled.DefaultRepositoryCategory
+		profile.CategoryPermissions = profiled.CategoryPermissions
+
+		// 同じリポジトリを複数のプロファイルに割り当てると、どのチームに書き込むか決まらない
+		for repository := range profile.RepositoryCategories {
+			key := strings.ToLower(repository)
+			if other, ok := repositories[key]; ok {
+				return fmt.Errorf("repository %s is mapped in both profiles %s and %s", repository, other, name)
+			}
+			repositories[key] = name
+		}
+	}
+
+	if config.DefaultProfile != "" {
+		if _, ok := config.Profiles[config.DefaultProfile]; !ok {
+			return fmt.Errorf("default_profile %s is not defined in profiles", config.DefaultProfile)
+		}
+	}
+	return nil
+}
+
+// validateBoundCategory はリポジトリに割り当てるカテゴリを正規化し、allowed_categories の範囲内であることを検証します
+func validateBoundCategory(category string, allowedCategories []string) (string, error) {
+	normalized, err := guard.NormalizeCategory(category)
+	if err != nil {
+		return "", err
+	}
+	allowed, err := guard.IsAllowedCategory(normalized, allowedCategories)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", fmt.Errorf("%s is not within allowed_categories", category)
+	}
+	return normalized, nil
+}
+
+// validateLimitCategory は許可を狭めるルールのカテゴリを正規化し、scope（allowed_categories・readable_categories）の
+// 範囲内であることを検証します（範囲外のルールは何も狭めないため、書き間違いとして拒否する）
+func validateLimitCategory(category string, scope []string) (string, error) {
+	normalized, err := guard.NormalizeCategory(category)
+	if err != nil {
+		return "", err
+	}
+	within, err := guard.IsAllowedCategory(normalized, scope)
+	if err != nil {
+		return "", err
+	}
+	if !within {
+		return "", fmt.Errorf("%s is not within allowed_categories or readable_categories", category)
+	}
+	return normalized, nil
+}
+
+// isRepositorySlug は owner/repo 形式（英数字・"."・"_"・"-"）かを返します
+func isRepositorySlug(repository string) bool {
+	owner, repo, ok := strings.Cut(repository, "/")
+	if !ok || owner == "" || repo == "" {
+		return false
+	}
+	for _, c := range owner + repo {
+		if !((c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateProjectConfig はリポジトリの設定がユーザーの設定を狭めるだけであることを検証します
+// 各カテゴリはユーザーの allowed_categories のいずれかの範囲内でなければならない（fail closed）
+func ValidateProjectConfig(project *ProjectConfig, user *Config) error {
+	if len(project.AllowedCategories) == 0 {
+		return fmt.Errorf("allowed_categories cannot be empty (fail closed)")
+	}
+
+	for i, category := range project.AllowedCategories {
+		normalized, err := guard.NormalizeCategory(category)
+		if err != nil {
+			return fmt.Errorf("invalid allowed category %s: %w", category, err)
+		}
+		allowed, err := guard.IsAllowedCategory(normalized, user.AllowedCategories)
+		if err != nil {
+			return fmt.Errorf("invalid allowed category %s: %w", category, err)
+		}
+		if !allowed {
+			return fmt.Errorf("allowed category %s is not within allowed_categories of the user config (a project config can only narrow them)", category)
+		}
+		project.AllowedCategories[i] = normalized
+	}
+
 	return nil
 }
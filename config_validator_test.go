@@ -192,6 +192,462 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "有効なreadable_categories",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories:  []string{"LLM/Tasks"},
+				ReadableCategories: []string{"Docs/Design"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "readable_categoriesに不正なカテゴリ",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories:  []string{"LLM/Tasks"},
+				ReadableCategories: []string{"Docs/../Secret"},
+			},
+			wantErr: true,
+			errMsg:  "invalid readable category",
+		},
+		{
+			name: "有効なdenied_categoriesとcategory_permissions",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories:  []string{"LLM"},
+				ReadableCategories: []string{"Docs"},
+				DeniedCategories:   []string{"LLM/Secrets", "Docs/Private"},
+				CategoryPermissions: map[string][]string{
+					"LLM/Docs": {"read", "update"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "許可カテゴリ外のdenied_categories",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories:  []string{"LLM"},
+				ReadableCategories: []string{"Docs"},
+				DeniedCategories:   []string{"Private"},
+			},
+			wantErr: true,
+			errMsg:  "is not within allowed_categories or readable_categories",
+		},
+		{
+			name: "境界を越えるcategory_permissions",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories:  []string{"LLM"},
+				ReadableCategories: []string{"Docs"},
+				CategoryPermissions: map[string][]string{
+					"LLM-evil": {"read"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "is not within allowed_categories or readable_categories",
+		},
+		{
+			name: "category_permissionsに不正な操作",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories:  []string{"LLM"},
+				ReadableCategories: []string{"Docs"},
+				CategoryPermissions: map[string][]string{
+					"LLM/Docs": {"read", "move"},
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid operation \"move\"",
+		},
+		{
+			name: "category_permissionsの操作が空",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories:  []string{"LLM"},
+				ReadableCategories: []string{"Docs"},
+				CategoryPermissions: map[string][]string{
+					"LLM/Docs": {},
+				},
+			},
+			wantErr: true,
+			errMsg:  "use denied_categories",
+		},
+		{
+			name: "有効なtimezone",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				Timezone:          "Asia/Tokyo",
+			},
+			wantErr: false,
+		},
+		{
+			name: "不正なtimezone",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				Timezone:          "Mars/Olympus",
+			},
+			wantErr: true,
+			errMsg:  "invalid timezone",
+		},
+		{
+			name: "ループバックのapi_base_url",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				APIBaseURL:        "http://127.0.0.1:8080",
+			},
+			wantErr: false,
+		},
+		{
+			name: "外部ホストへのhttpのapi_base_url",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				APIBaseURL:        "http://esa.example.com",
+			},
+			wantErr: true,
+			errMsg:  "invalid api_base_url",
+		},
+		{
+			name: "~/から始まるtoken_file",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				TokenFile:         "~/.config/esa-llm-scoped-guard/token",
+			},
+			wantErr: false,
+		},
+		{
+			name: "相対パスのtoken_file",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				TokenFile:         "token",
+			},
+			wantErr: true,
+			errMsg:  "token_file must be an absolute path",
+		},
+		{
+			name: "token_fileとtoken_commandの併用",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				TokenFile:         "/tmp/token",
+				TokenCommand:      []string{"pass", "show", "esa"},
+			},
+			wantErr: true,
+			errMsg:  "cannot be used together",
+		},
+		{
+			name: "コマンド名が空のtoken_command",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				TokenCommand:      []string{""},
+			},
+			wantErr: true,
+			errMsg:  "token_command must start with a command name",
+		},
+		{
+			name: "有効なtimeouts",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				Timeouts:          map[string]string{"default": "1m", "post": "2m30s"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "timeoutsに不明なコマンド",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				Timeouts:          map[string]string{"serve-mcp": "1m"},
+			},
+			wantErr: true,
+			errMsg:  "invalid timeouts key serve-mcp",
+		},
+		{
+			name: "timeoutsに不正な期間",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				Timeouts:          map[string]string{"post": "soon"},
+			},
+			wantErr: true,
+			errMsg:  "invalid timeout for post",
+		},
+		{
+			name: "timeoutsに0",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				Timeouts:          map[string]string{"default": "0s"},
+			},
+			wantErr: true,
+			errMsg:  "invalid timeout for default",
+		},
+		{
+			name: "有効なretry",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				Retry:             RetryConfig{MaxAttempts: 5, BaseDelay: "500ms", MaxDelay: "1m"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "retry.max_attemptsが負",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				Retry:             RetryConfig{MaxAttempts: -1},
+			},
+			wantErr: true,
+			errMsg:  "invalid retry.max_attempts",
+		},
+		{
+			name: "retry.base_delayに不正な期間",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				Retry:             RetryConfig{BaseDelay: "0s"},
+			},
+			wantErr: true,
+			errMsg:  "invalid retry.base_delay",
+		},
+		{
+			name: "proxy.read_policyが不正",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				Proxy:             ProxyConfig{ReadPolicy: "none"},
+			},
+			wantErr: true,
+			errMsg:  "invalid proxy.read_policy",
+		},
+		{
+			name: "repository_categoriesのキーがowner/repoでない",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories:    []string{"LLM/Tasks"},
+				RepositoryCategories: map[string]string{"repo-a": "LLM/Tasks/repo-a"},
+			},
+			wantErr: true,
+			errMsg:  "must be owner/repo",
+		},
+		{
+			name: "repository_categoriesのカテゴリが許可カテゴリ外",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories:    []string{"LLM/Tasks"},
+				RepositoryCategories: map[string]string{"owner/repo-a": "Private/repo-a"},
+			},
+			wantErr: true,
+			errMsg:  "not within allowed_categories",
+		},
+		{
+			name: "repository_categoriesの大文字・小文字違いの重複",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories:    []string{"LLM/Tasks"},
+				RepositoryCategories: map[string]string{"owner/repo-a": "LLM/Tasks/a", "Owner/Repo-A": "LLM/Tasks/b"},
+			},
+			wantErr: true,
+			errMsg:  "duplicate repositories",
+		},
+		{
+			name: "repository_categoriesなしのdefault_repository_category",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories:         []string{"LLM/Tasks"},
+				DefaultRepositoryCategory: "LLM/Tasks/misc",
+			},
+			wantErr: true,
+			errMsg:  "requires repository_categories",
+		},
+		{
+			name: "有効なプロファイル",
+			config: &Config{
+				Profiles: map[string]*Profile{
+					"work":    testProfile("work-team", "LLM/Tasks"),
+					"private": testProfile("my-team", "Notes"),
+				},
+				DefaultProfile: "work",
+			},
+			wantErr: false,
+		},
+		{
+			name: "プロファイルとトップレベルのカテゴリの併用",
+			config: &Config{
+				AllowedCategories: []string{"LLM/Tasks"},
+				Profiles:          map[string]*Profile{"work": testProfile("work-team", "LLM/Tasks")},
+			},
+			wantErr: true,
+			errMsg:  "must be set in each profile",
+		},
+		{
+			name: "プロファイル名に不正な文字",
+			config: &Config{
+				Profiles: map[string]*Profile{"work team": testProfile("work-team", "LLM/Tasks")},
+			},
+			wantErr: true,
+			errMsg:  "invalid profile name",
+		},
+		{
+			name: "プロファイルのallowed_categoriesが空",
+			config: &Config{
+				Profiles: map[string]*Profile{"work": testProfile("work-team")},
+			},
+			wantErr: true,
+			errMsg:  "profile work: allowed_categories cannot be empty",
+		},
+		{
+			name: "同じリポジトリを複数のプロファイルに割り当て",
+			config: &Config{
+				Profiles: map[string]*Profile{
+					"work":    withRepository(testProfile("work-team", "LLM/Tasks"), "owner/repo-a", "LLM/Tasks/repo-a"),
+					"private": withRepository(testProfile("my-team", "Notes"), "Owner/Repo-A", "Notes/repo-a"),
+				},
+			},
+			wantErr: true,
+			errMsg:  "is mapped in both profiles",
+		},
+		{
+			name: "定義されていないdefault_profile",
+			config: &Config{
+				Profiles:       map[string]*Profile{"work": testProfile("work-team", "LLM/Tasks")},
+				DefaultProfile: "private",
+			},
+			wantErr: true,
+			errMsg:  "is not defined in profiles",
+		},
+		{
+			name: "プロファイルなしのdefault_profile",
+			config: &Config{
+				Esa: struct {
+					TeamName string `yaml:"team_name"`
+				}{
+					TeamName: "my-team",
+				},
+				AllowedCategories: []string{"LLM/Tasks"},
+				DefaultProfile:    "work",
+			},
+			wantErr: true,
+			errMsg:  "default_profile requires profiles",
+		},
 	}
 
 	for _, tt := range tests {
@@ -207,3 +663,19 @@ func TestValidateConfig(t *testing.T) {
 		})
 	}
 }
+
+// testProfile はチーム名と許可カテゴリだけを設定したプロファイルを返す
+func testProfile(team string, allowedCategories ...string) *Profile {
+	profile := &Profile{AllowedCategories: allowedCategories}
+	profile.Esa.TeamName = team
+	return profile
+}
+
+// withRepository はプロファイルにリポジトリのカテゴリの割り当てを追加する
+func withRepository(profile *Profile, repository, category string) *Profile {
+	if profile.RepositoryCategories == nil {
+		profile.RepositoryCategories = make(map[string]string)
+	}
+	profile.RepositoryCategories[repository] = category
+	return profile
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// commandContext はcommandの実行に使うcontextを返します
+// Ctrl-C（SIGINT）・SIGTERMを受け取るとキャンセルされ、設定ファイルの timeouts でタイムアウトする
+// シグナルはcancelを呼ぶまで捕捉し続けるため、キャンセル後もJSONファイルの書き戻しなどの後処理は中断されない
+// configがnil（設定ファイルを読まないコマンド）の場合はタイムアウトしない
+func commandContext(command string, config *Config) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if config == nil {
+		return ctx, stop
+	}
+	timeout := config.Timeout(command)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
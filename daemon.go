@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/mcp"
+)
+
+// daemonSocketEnv はクライアントモードで接続するdaemonのソケットを指定する環境変数
+const daemonSocketEnv = "ESA_LLM_SCOPED_GUARD_SOCKET"
+
+// daemonSocketLabel はソケットのディレクトリの検証エラーで使う種類
+const daemonSocketLabel = "daemon socket"
+
+// defaultDaemonSocketPath はdaemonが待ち受ける既定のソケットのパスを返します
+// 環境変数 ESA_LLM_SCOPED_GUARD_SOCKET、$XDG_RUNTIME_DIR/esa-llm-scoped-guard/daemon.sock、
+// ~/.local/state/esa-llm-scoped-guard/daemon.sock の順
+func defaultDaemonSocketPath() (string, error) {
+	if path := os.Getenv(daemonSocketEnv); path != "" {
+		return path, nil
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" || !filepath.IsAbs(runtimeDir) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		runtimeDir = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(runtimeDir, "esa-llm-scoped-guard", "daemon.sock"), nil
+}
+
+func runDaemon(args []string) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var socketPath string
+	var allowUIDs string
+	var allowSameUser bool
+	var showHelp bool
+	fs.StringVar(&socketPath, "socket", "", "Unix socket path to listen on")
+	fs.StringVar(&allowUIDs, "allow-uid", "", "Comma-separated user IDs allowed to connect (the agent's user)")
+	fs.BoolVar(&allowSameUser, "allow-same-user", false, "Also accept connections from the daemon's own user (the token is not isolated from it)")
+	fs.BoolVar(&showHelp, "help", false, "Show help message")
+	fs.Parse(args)
+
+	if showHelp {
+		fs.Usage()
+		os.Exit(0)
+	}
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(guard.ErrorKindOf(err).ExitCode())
+	}
+
+	// 接続元を確認できない環境ではトークンを預からない（fail closed）
+	if !peerCredSupported {
+		fail(guard.WithKind(guard.ErrorKindConfig, errors.New("daemon requires peer credential checks, which are not supported on this platform")))
+	}
+	self := uint32(os.Getuid())
+	allowed, err := parseAllowedUIDs(allowUIDs, self, allowSameUser)
+	if err != nil {
+		fail(guard.WithKind(guard.ErrorKindUsage, err))
+	}
+	if allowSameUser {
+		fmt.Fprintln(os.Stderr, "Warning: -allow-same-user accepts the daemon's own user, which can read the token (e.g. via /proc); run the agent as another user to isolate it")
+	}
+	if socketPath == "" {
+		socketPath, err = defaultDaemonSocketPath()
+		if err != nil {
+			fail(guard.WithKind(guard.ErrorKindConfig, err))
+		}
+	}
+
+	config, accessToken, err := loadServerConfigAndToken()
+	if err != nil {
+		fail(err)
+	}
+	audit, err := openAuditLog("daemon", accessToken)
+	if err != nil {
+		fail(err)
+	}
+
+	ctx, cancel := commandContext("daemon", nil)
+	defer cancel()
+	client, err := newEsaClient(config, accessToken)
+	if err != nil {
+		fail(err)
+	}
+
+	// 他のユーザーを許可する場合は、そのユーザーが接続できるようソケット自体は誰でも開けるようにする
+	// （接続を受け付けるかどうかは接続元のUIDで判定する）
+	listener, err := listenDaemonSocket(socketPath, slices.ContainsFunc(allowed, func(uid uint32) bool { return uid != self }))
+	if err != nil {
+		fail(guard.WithKind(guard.ErrorKindConfig, err))
+	}
+	fmt.Fprintf(os.Stderr, "Daemon listening on %s (set %s to this path in the agent's environment)\n", socketPath, daemonSocketEnv)
+
+	newServer := func() *mcp.Server {
+		server := mcp.NewServer(config.Policy(), client)
+		server.SetAuditLog(audit)
+		server.SetRepositoryTag(config.RepositoryTag())
+		server.SetTeam(config.Esa.TeamName)
+		return server
+	}
+	if err := serveDaemon(ctx, listener, allowed, newServer); err != nil {
+		fail(guard.WithKind(guard.ErrorKindIO, err))
+	}
+}
+
+// parseAllowedUIDs は接続を許可するUIDの一覧を返します
+// 同じユーザーのプロセスは /proc などからdaemonのトークンを読めて隔離にならないため、
+// daemonを実行しているユーザー（self）は allowSameUser を指定した場合だけ含める。
+// 許可するユーザーが1人もいなければエラー（fail closed）
+func parseAllowedUIDs(value string, self uint32, allowSameUser bool) ([]uint32, error) {
+	var allowed []uint32
+	if allowSameUser {
+		allowed = append(allowed, self)
+	}
+	if value != "" {
+		for _, s := range strings.Split(value, ",") {
+			uid, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid -allow-uid %q (must be comma-separated user IDs)", s)
+			}
+			if uint32(uid) == self && !allowSameUser {
+				return nil, fmt.Errorf("-allow-uid %d is the daemon's own user, which is not isolated from the token (use -allow-same-user to accept it anyway)", uid)
+			}
+			if !slices.Contains(allowed, uint32(uid)) {
+				allowed = append(allowed, uint32(uid))
+			}
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, errors.New("no users are allowed to connect: pass the agent's user ID with -allow-uid (or -allow-same-user to accept the daemon's own user without isolation)")
+	}
+	return allowed, nil
+}
+
+// listenDaemonSocket はsocketPathでunixソケットを待ち受けます
+// ソケットのディレクトリはgroup/world-writableであってはならない。古いソケットが残っていれば削除し、
+// 別のdaemonが待ち受けている場合はエラーにする。sharedでなければソケットは所有者のみ接続できる
+func listenDaemonSocket(socketPath string, shared bool) (*net.UnixListener, error) {
+	// 他のユーザーを許可する場合はディレクトリを辿れるようにする（一覧の取得・書き込みはできない）
+	dirMode := os.FileMode(0o700)
+	if shared {
+		dirMode = 0o711
+	}
+	dir := filepath.Dir(socketPath)
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return nil, fmt.Errorf("failed to create daemon socket directory: %w", err)
+	}
+	if err := validatePrivateDir(dir, daemonSocketLabel); err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Lstat(socketPath); err == nil {
+		if info.Mode().Type() != os.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", socketPath)
+		}
+		if conn, err := net.Dial("unix", socketPath); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another daemon is already listening on %s", socketPath)
+		}
+		if err := os.Remove(socketPath); err != nil {
+			return nil, fmt.Errorf("failed to remove stale daemon socket: %w", err)
+		}
+	}
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on daemon socket: %w", err)
+	}
+	mode := os.FileMode(0o600)
+	if shared {
+		mode = 0o666
+	}
+	if err := os.Chmod(socketPath, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set daemon socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// serveDaemon は接続ごとにnewServerで作成したMCPサーバーで応答します
+// 接続元のUIDがallowedに含まれない接続は何も読まずに閉じる。ctxがキャンセルされると待ち受けを止め、
+// 処理中の接続が終わるのを待ってから nil を返す
+func serveDaemon(ctx context.Context, listener *net.UnixListener, allowed []uint32, newServer func() *mcp.Server) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	for {
+		conn, err := listener.AcceptUnix()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		uid, pid, err := peerCredentials(conn)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Rejected connection: %v\n", err)
+			conn.Close()
+			continue
+		}
+		if !slices.Contains(allowed, uid) {
+			fmt.Fprintf(os.Stderr, "Rejected connection from uid %d (pid %d): not allowed\n", uid, pid)
+			conn.Close()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// ServeContextはctxのキャンセル後、処理中のメッセージに応答してから戻る
+			defer conn.Close()
+			if err := newServer().ServeContext(ctx, conn, conn); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Connection from uid %d (pid %d) failed: %v\n", uid, pid, err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/mcp"
+)
+
+// daemonSocketFor はクライアントモードで接続するdaemonのソケットを返します
+// -socket が指定されていなければ環境変数 ESA_LLM_SCOPED_GUARD_SOCKET を使い、どちらもなければ空（ローカルで実行する）
+func daemonSocketFor(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return os.Getenv(daemonSocketEnv)
+}
+
+// callDaemon はdaemonに接続してツールを1回呼び出し、結果をresultにデコードします
+// ctxがキャンセルされると接続を閉じて中断する（daemon側で処理中の書き込みは止まらない）
+func callDaemon(ctx context.Context, socketPath, tool string, arguments, result interface{}) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("daemon request interrupted: %w", ctx.Err())
+		}
+		return guard.WithKind(guard.ErrorKindConfig, fmt.Errorf("failed to connect to daemon at %s: %w", socketPath, err))
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client := mcp.NewClient(conn)
+	err = client.Initialize()
+	if err == nil {
+		err = client.CallTool(tool, arguments, result)
+	}
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("daemon request interrupted: %w", ctx.Err())
+	}
+	return err
+}
+
+// validateViaDaemon はJSONファイルの検証をdaemonに依頼します
+func validateViaDaemon(rep *reporter, socketPath, jsonPath string) {
+	input, err := guard.ReadPostInputFromFile(jsonPath)
+	if err != nil {
+		rep.fail(fmt.Errorf("failed to read JSON file: %w", err))
+	}
+
+	ctx, cancel := commandContext("validate", nil)
+	defer cancel()
+	if err := callDaemon(ctx, socketPath, "validate", input, nil); err != nil {
+		rep.fail(err)
+	}
+	if rep.isJSON() {
+		writeJSON(&guard.Result{Status: "ok", Command: "validate"})
+	}
+}
+
+// diffViaDaemon は既存記事との差分をdaemonに依頼します
+func diffViaDaemon(rep *reporter, socketPath, jsonPath string) {
+	input, err := guard.ReadPostInputFromFile(jsonPath)
+	if err != nil {
+		rep.fail(fmt.Errorf("failed to read JSON file: %w", err))
+	}
+
+	ctx, cancel := commandContext("diff", nil)
+	defer cancel()
+	var out struct {
+		Diff string `json:"diff"`
+	}
+	if err := callDaemon(ctx, socketPath, "diff", input, &out); err != nil {
+		rep.fail(err)
+	}
+
+	if rep.isJSON() {
+		result := &guard.Result{Status: "ok", Command: "diff", Diff: &out.Diff}
+		if input.PostNumber != nil {
+			result.PostNumber = *input.PostNumber
+		}
+		writeJSON(result)
+		return
+	}
+	fmt.Print(out.Diff)
+}
+
+// postViaDaemon は記事の作成/更新をdaemonに依頼し、結果をローカルのJSONファイルに書き戻します
+func postViaDaemon(rep *reporter, socketPath, jsonPath string) {
+	input, err := guard.ReadPostInputFromFile(jsonPath)
+	if err != nil {
+		rep.fail(fmt.Errorf("failed to read JSON file: %w", err))
+	}
+
+	ctx, cancel := commandContext("post", nil)
+	defer cancel()
+	var out struct {
+		PostNumber     int      `json:"post_number"`
+		URL            string   `json:"url"`
+		Created        bool     `json:"created"`
+		RevisionNumber int      `json:"revision_number"`
+		Team           string   `json:"team"`
+		Warnings       []string `json:"warnings"`
+	}
+	if err := callDaemon(ctx, socketPath, "post", input, &out); err != nil {
+		if input.CreateNew && errors.Is(err, ctx.Err()) {
+			err = fmt.Errorf("%w (the post may have been created, check with list before retrying)", err)
+		}
+		rep.fail(err)
+	}
+
+	// 書き戻しはローカルで行う（daemonはエージェントのファイルに触れない）
+	input.Team = out.Team
+	result := guard.ApplyPostResult(jsonPath, input, &guard.PostResult{
+		Post:     &esa.Post{Number: out.PostNumber, URL: out.URL, RevisionNumber: out.RevisionNumber},
+		Created:  out.Created,
+		Warnings: out.Warnings,
+	})
+	if rep.isJSON() {
+		writeJSON(result)
+		return
+	}
+	guard.PrintPostResult(result)
+}
+
+// fetchViaDaemon は記事の埋め込みJSONの取得をdaemonに依頼します
+func fetchViaDaemon(rep *reporter, socketPath string, postNumber int) {
+	ctx, cancel := commandContext("fetch", nil)
+	defer cancel()
+	var input guard.PostInput
+	if err := callDaemon(ctx, socketPath, "fetch", map[string]int{"post_number": postNumber}, &input); err != nil {
+		rep.fail(err)
+	}
+
+	if rep.isJSON() {
+		writeJSON(&guard.Result{Status: "ok", Command: "fetch", PostNumber: postNumber, JSON: &input})
+		return
+	}
+	output, err := guard.FormatFetched(&input)
+	if err != nil {
+		rep.fail(err)
+	}
+	fmt.Print(output)
+}
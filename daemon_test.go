@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/mcp"
+)
+
+const daemonTestInput = `{"create_new":true,"name":"Test Post","category":"LLM/Tasks/2026/01/28","body":{"background":"b","tasks":[{"id":"task-1","title":"Task 1: Test","status":"not_started","summary":["s"],"description":"d"}]}}`
+
+// startTestDaemon はt.TempDir()のソケットでdaemonを起動し、ソケットのパスを返す
+func startTestDaemon(t *testing.T, allowed []uint32) string {
+	t.Helper()
+	if !peerCredSupported {
+		t.Skip("peer credential check is not supported on this platform")
+	}
+	socketPath := filepath.Join(t.TempDir(), "run", "daemon.sock")
+	listener, err := listenDaemonSocket(socketPath, false)
+	if err != nil {
+		t.Fatalf("listenDaemonSocket() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serveDaemon(ctx, listener, allowed, func() *mcp.Server {
+			return mcp.NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), esa.NewEsaClient("test-team", "test-token"))
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("serveDaemon() error = %v", err)
+		}
+	})
+	return socketPath
+}
+
+func TestServeDaemon(t *testing.T) {
+	socketPath := startTestDaemon(t, []uint32{uint32(os.Getuid())})
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("socket permissions = %o, want 600", info.Mode().Perm())
+	}
+
+	if err := callDaemon(context.Background(), socketPath, "validate", json.RawMessage(daemonTestInput), nil); err != nil {
+		t.Errorf("callDaemon(validate) error = %v", err)
+	}
+
+	// daemonの検証エラーは種類を保ったまま返る
+	input := strings.Replace(daemonTestInput, "LLM/Tasks", "Other", 1)
+	err = callDaemon(context.Background(), socketPath, "diff", json.RawMessage(input), nil)
+	if guard.ErrorKindOf(err) != guard.ErrorKindValidation || !strings.Contains(err.Error(), "no rule permits create") {
+		t.Errorf("callDaemon(diff) error = %v, want category not allowed", err)
+	}
+}
+
+func TestServeDaemon_RejectsOtherUsers(t *testing.T) {
+	// 自分以外のUIDだけを許可する
+	socketPath := startTestDaemon(t, []uint32{uint32(os.Getuid()) + 1})
+
+	err := callDaemon(context.Background(), socketPath, "validate", json.RawMessage(daemonTestInput), nil)
+	if err == nil {
+		t.Fatal("callDaemon() error = nil, want connection closed")
+	}
+}
+
+func TestCallDaemon_NotRunning(t *testing.T) {
+	err := callDaemon(context.Background(), filepath.Join(t.TempDir(), "missing.sock"), "validate", json.RawMessage(daemonTestInput), nil)
+	if guard.ErrorKindOf(err) != guard.ErrorKindConfig || !strings.Contains(err.Error(), "failed to connect to daemon") {
+		t.Errorf("callDaemon() error = %v, want config error", err)
+	}
+}
+
+func TestListenDaemonSocket(t *testing.T) {
+	t.Run("古いソケットは置き換える", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "daemon.sock")
+		stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stale.SetUnlinkOnClose(false)
+		stale.Close()
+
+		listener, err := listenDaemonSocket(socketPath, false)
+		if err != nil {
+			t.Fatalf("listenDaemonSocket() error = %v", err)
+		}
+		defer listener.Close()
+
+		// 待ち受け中のソケットは奪わない
+		if _, err := listenDaemonSocket(socketPath, false); err == nil || !strings.Contains(err.Error(), "already listening") {
+			t.Errorf("second listenDaemonSocket() error = %v, want already listening", err)
+		}
+	})
+
+	t.Run("ソケット以外のファイルは消さない", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "daemon.sock")
+		if err := os.WriteFile(socketPath, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := listenDaemonSocket(socketPath, false); err == nil || !strings.Contains(err.Error(), "not a socket") {
+			t.Errorf("listenDaemonSocket() error = %v, want not a socket", err)
+		}
+	})
+
+	t.Run("world-writableなディレクトリ", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.Chmod(dir, 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := listenDaemonSocket(filepath.Join(dir, "daemon.sock"), false); err == nil || !strings.Contains(err.Error(), "group or world writable") {
+			t.Errorf("listenDaemonSocket() error = %v, want directory error", err)
+		}
+	})
+}
+
+func TestParseAllowedUIDs(t *testing.T) {
+	const self = 1000
+	tests := []struct {
+		name          string
+		value         string
+		allowSameUser bool
+		want          []uint32
+		wantErr       bool
+	}{
+		{"エージェントのUID", "5000, 5001", false, []uint32{5000, 5001}, false},
+		{"同じユーザーを明示的に許可", "", true, []uint32{self}, false},
+		{"同じユーザーと別のUID", "5000", true, []uint32{self, 5000}, false},
+		{"指定なしは誰も許可しない", "", false, nil, true},
+		{"自分のUIDは明示的な許可なしには指定できない", "1000", false, nil, true},
+		{"数値でない", "alice", false, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAllowedUIDs(tt.value, self, tt.allowSameUser)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAllowedUIDs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseAllowedUIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/hook"
+)
+
+func runHook(args []string) {
+	fs := flag.NewFlagSet("hook", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var showHelp bool
+	fs.BoolVar(&showHelp, "help", false, "Show help message")
+	fs.Parse(args)
+
+	if showHelp {
+		fs.Usage()
+		os.Exit(0)
+	}
+
+	// 判定できない場合も拒否を出力して終了コード0で終わる
+	// （0以外で終了するとClaude Codeはツールの実行を止めないため、fail closedにならない）
+	event, err := hook.ReadEvent(os.Stdin)
+	if err != nil {
+		writeHookOutput(hook.Deny(err))
+		return
+	}
+	config, err := loadUserConfig()
+	if err != nil {
+		writeHookOutput(hook.Deny(err))
+		return
+	}
+
+	ctx, cancel := commandContext("hook", config)
+	defer cancel()
+	// トークンは既存記事の取得が必要なツール（更新・移動・削除）のときだけ取得する
+	getPost := func(postNumber int) (*esa.Post, error) {
+		accessToken, err := resolveAccessToken(config)
+		if err != nil {
+			return nil, err
+		}
+		client, err := newEsaClient(config, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		return client.GetPost(ctx, postNumber)
+	}
+
+	output := hook.Evaluate(event, config.Esa.TeamName, config.RepositoryTag(), config.Policy(), getPost)
+	if output == nil {
+		return
+	}
+	writeHookOutput(output)
+}
+
+// writeHookOutput はフックの判定を標準出力に書き込みます
+func writeHookOutput(output *hook.Output) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write hook output: %v\n", err)
+		// 判定を出力できない場合は終了コード2でツールの実行を止める
+		os.Exit(2)
+	}
+}
@@ -2,20 +2,33 @@ package esa
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// defaultBaseURL はesa.io APIのベースURL
+const defaultBaseURL = "https://api.esa.io"
+
+// maxResponseSize はesa.io APIのレスポンスとして読み込む最大サイズ（超えた場合はエラー）
+const maxResponseSize = 10 * 1024 * 1024
+
 // EsaClient はesa.io APIクライアント
 type EsaClient struct {
 	teamName    string
 	accessToken string
+	baseURL     string
 	httpClient  *http.Client
+	retryPolicy RetryPolicy
+	now         func() time.Time
 }
 
 // NewEsaClient は新しいEsaClientを作成します
@@ -40,54 +53,132 @@ func NewEsaClient(teamName, accessToken string) *EsaClient {
 	return &EsaClient{
 		teamName:    teamName,
 		accessToken: accessToken,
+		baseURL:     defaultBaseURL,
 		httpClient:  client,
+		retryPolicy: DefaultRetryPolicy(),
+		now:         time.Now,
 	}
 }
 
-// CreatePost は新規記事を作成します
-func (c *EsaClient) CreatePost(post *PostInput) (*Post, error) {
-	url := fmt.Sprintf("https://api.esa.io/v1/teams/%s/posts", c.teamName)
-	return c.doRequestWithRetry("POST", url, post)
+// SetBaseURL はAPIのベースURLを設定します（既定は https://api.esa.io）
+// ValidateBaseURL を通らないURLはエラー
+func (c *EsaClient) SetBaseURL(baseURL string) error {
+	if err := ValidateBaseURL(baseURL); err != nil {
+		return err
+	}
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	return nil
 }
 
-// UpdatePost は既存記事を更新します
-func (c *EsaClient) UpdatePost(postNumber int, post *PostInput) (*Post, error) {
-	url := fmt.Sprintf("https://api.esa.io/v1/teams/%s/posts/%d", c.teamName, postNumber)
-	return c.doRequestWithRetry("PATCH", url, post)
+// ValidateBaseURL はAPIのベースURLとして使えるかを検証します
+// アクセストークンを平文で送らないよう https のみ許可し、http はループバック（localhost・127.0.0.0/8・::1）に限る
+func ValidateBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Host == "" || u.Opaque != "" {
+		return fmt.Errorf("base URL must be an absolute URL: %s", baseURL)
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("base URL must not contain credentials, query or fragment: %s", baseURL)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if isLoopbackHost(u.Hostname()) {
+			return nil
+		}
+		return fmt.Errorf("http base URL is only allowed for loopback hosts (use https): %s", baseURL)
+	default:
+		return fmt.Errorf("base URL scheme must be https (or http for loopback hosts): %s", baseURL)
+	}
 }
 
-// GetPost は記事を取得します
-func (c *EsaClient) GetPost(postNumber int) (*Post, error) {
-	url := fmt.Sprintf("https://api.esa.io/v1/teams/%s/posts/%d", c.teamName, postNumber)
-	return c.doRequestWithRetry("GET", url, nil)
+// isLoopbackHost はhostがループバックアドレスかを返します（名前解決はしない）
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
 
-// doRequestWithRetry はリトライ付きでHTTPリクエストを実行します
-func (c *EsaClient) doRequestWithRetry(method, url string, payload interface{}) (*Post, error) {
-	maxRetries := 3
-	backoff := 1 * time.Second
+// SetRetryPolicy はリトライ方針を設定します（ゼロ値のフィールドは既定値）
+func (c *EsaClient) SetRetryPolicy(policy RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+// CreatePost は新規記事を作成します
+// ctxがキャンセルされると実行中のリクエストとリトライの待機を中断する（他のメソッドも同じ）
+// POSTは冪等でないため、リトライするのはリクエストが処理されていないことが明らかなレート制限だけで、
+// サーバーが処理したか分からない失敗（タイムアウトや5xx）の後は再送しない。
+// esa.ioの検索は作成直後の記事をすぐには返さず、本文も正規化されうるため、検索で作成済みかを確かめることもしない
+func (c *EsaClient) CreatePost(ctx context.Context, post *PostInput) (*Post, error) {
+	endpoint := fmt.Sprintf("%s/v1/teams/%s/posts", c.baseURL, c.teamName)
 
+	var created Post
 	var lastErr error
-	for i := 0; i < maxRetries; i++ {
-		post, err := c.doRequest(method, url, payload)
-		if err == nil {
-			return post, nil
+	err := c.withRetry(ctx, isRetryableCreate, func() error {
+		lastErr = c.doRequestInto(ctx, "POST", endpoint, post, &created)
+		return lastErr
+	})
+	if err != nil {
+		if isAmbiguousFailure(lastErr) {
+			return nil, fmt.Errorf("no response confirmed the create, so the post may have been created (check with list before retrying): %w", err)
 		}
+		return nil, err
+	}
+	return &created, nil
+}
 
-		lastErr = err
+// UpdatePost は既存記事を更新します
+func (c *EsaClient) UpdatePost(ctx context.Context, postNumber int, post *PostInput) (*Post, error) {
+	url := fmt.Sprintf("%s/v1/teams/%s/posts/%d", c.baseURL, c.teamName, postNumber)
+	return c.doRequestWithRetry(ctx, "PATCH", url, post)
+}
 
-		// 最後の試行でない場合はバックオフ
-		if i < maxRetries-1 {
-			time.Sleep(backoff)
-			backoff *= 2 // 指数バックオフ
-		}
+// GetPost は記事を取得します
+func (c *EsaClient) GetPost(ctx context.Context, postNumber int) (*Post, error) {
+	url := fmt.Sprintf("%s/v1/teams/%s/posts/%d", c.baseURL, c.teamName, postNumber)
+	return c.doRequestWithRetry(ctx, "GET", url, nil)
+}
+
+// ListPosts は記事を検索します（qはesa.ioの検索クエリ、pageは1から）
+func (c *EsaClient) ListPosts(ctx context.Context, q string, page, perPage int) (*PostList, error) {
+	params := url.Values{}
+	params.Set("q", q)
+	params.Set("page", strconv.Itoa(page))
+	params.Set("per_page", strconv.Itoa(perPage))
+	endpoint := fmt.Sprintf("%s/v1/teams/%s/posts?%s", c.baseURL, c.teamName, params.Encode())
+
+	var list PostList
+	if err := c.doRequestIntoWithRetry(ctx, "GET", endpoint, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// doRequestWithRetry はリトライ付きでHTTPリクエストを実行します
+func (c *EsaClient) doRequestWithRetry(ctx context.Context, method, url string, payload interface{}) (*Post, error) {
+	var post Post
+	if err := c.doRequestIntoWithRetry(ctx, method, url, payload, &post); err != nil {
+		return nil, err
 	}
+	return &post, nil
+}
 
-	return nil, fmt.Errorf("request failed after %d retries: %w", maxRetries, lastErr)
+// doRequestIntoWithRetry はリトライ付きでHTTPリクエストを実行し、レスポンスをresultにデコードします
+// 冪等なリクエスト（GET/PATCH）専用で、リトライするのはレート制限・サーバーエラー・通信エラーのみ
+func (c *EsaClient) doRequestIntoWithRetry(ctx context.Context, method, url string, payload interface{}, result interface{}) error {
+	return c.withRetry(ctx, isRetryable, func() error {
+		return c.doRequestInto(ctx, method, url, payload, result)
+	})
 }
 
-// doRequest はHTTPリクエストを実行します
-func (c *EsaClient) doRequest(method, url string, payload interface{}) (*Post, error) {
+// doRequestInto はHTTPリクエストを実行し、レスポンスをresultにデコードします
+func (c *EsaClient) doRequestInto(ctx context.Context, method, url string, payload interface{}, result interface{}) error {
 	var body io.Reader
 	if payload != nil {
 		// esa.io APIは {"post": {...}} 形式を要求
@@ -96,14 +187,14 @@ func (c *EsaClient) doRequest(method, url string, payload interface{}) (*Post, e
 		}
 		jsonData, err := json.Marshal(wrapped)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request: %w", err)
+			return fmt.Errorf("failed to marshal request: %w", err)
 		}
 		body = bytes.NewReader(jsonData)
 	}
 
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -111,39 +202,85 @@ func (c *EsaClient) doRequest(method, url string, payload interface{}) (*Post, e
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return &transportError{err: fmt.Errorf("request failed: %w", err)}
 	}
 	defer resp.Body.Close()
 
-	// レスポンスボディを制限付きで読み込み（10MB上限+1バイトで超過検知）
-	const maxResponseSize = 10 * 1024 * 1024
-	limitedReader := io.LimitReader(resp.Body, maxResponseSize+1)
-	respBody, err := io.ReadAll(limitedReader)
+	respBody, err := readResponseBody(resp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// レスポンスサイズが上限を超えている場合はエラー（fail closed）
-	if len(respBody) > maxResponseSize {
-		return nil, fmt.Errorf("response body exceeds %d bytes (got at least %d bytes)", maxResponseSize, len(respBody))
+		return err
 	}
 
 	// ステータスコードチェック
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// エラーメッセージをサニタイズ（最大500文字、制御文字除去）
-		errMsg := sanitizeErrorMessage(string(respBody))
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, errMsg)
+		// エラーメッセージはサニタイズする（最大500文字、制御文字除去）
+		return newAPIError(resp, respBody, c.now())
 	}
 
 	// レスポンスをパース
-	var result struct {
-		Post `json:",inline"`
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
+
+// Response はForwardで受け取ったesa.io APIのレスポンス
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Forward はesa.io APIにリクエストをそのまま送り、ステータスにかかわらずレスポンスを返します（proxy用）
+// pathは /v1/ から始まるAPIのパス。TLS・リダイレクト禁止・レスポンスサイズの上限は他のメソッドと同じで、
+// アクセストークンはこのクライアントのものを付ける。リトライはしない
+func (c *EsaClient) Forward(ctx context.Context, method, path, rawQuery string, body []byte) (*Response, error) {
+	if !strings.HasPrefix(path, "/v1/") {
+		return nil, fmt.Errorf("invalid API path: %s", path)
+	}
+	endpoint := c.baseURL + path
+	if rawQuery != "" {
+		endpoint += "?" + rawQuery
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &transportError{err: fmt.Errorf("request failed: %w", err)}
 	}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	defer resp.Body.Close()
+
+	respBody, err := readResponseBody(resp)
+	if err != nil {
+		return nil, err
 	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: respBody}, nil
+}
 
-	return &result.Post, nil
+// readResponseBody はレスポンスボディを上限付きで読み込みます（上限を超えた場合はエラー、fail closed）
+func readResponseBody(resp *http.Response) ([]byte, error) {
+	// 上限+1バイトまで読んで超過を検知
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, &transportError{err: fmt.Errorf("failed to read response: %w", err)}
+	}
+	if len(respBody) > maxResponseSize {
+		return nil, fmt.Errorf("response body exceeds %d bytes (got at least %d bytes)", maxResponseSize, len(respBody))
+	}
+	return respBody, nil
 }
 
 // sanitizeErrorMessage はエラーメッセージをサニタイズします
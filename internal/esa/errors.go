@@ -0,0 +1,170 @@
+package esa
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// APIErrorのステータスコードと比較するためのエラー
+// errors.Is(err, esa.ErrNotFound) のように使う
+var (
+	ErrNotFound     = errors.New("esa: not found")           // 404
+	ErrUnauthorized = errors.New("esa: unauthorized")        // 401
+	ErrForbidden    = errors.New("esa: forbidden")           // 403
+	ErrRateLimited  = errors.New("esa: rate limit exceeded") // 429
+)
+
+// ErrorClass はAPIエラーの分類（リトライするかどうかの判断に使う）
+type ErrorClass string
+
+const (
+	ErrorClassNotFound    ErrorClass = "not_found"    // 404
+	ErrorClassForbidden   ErrorClass = "forbidden"    // 401 / 403
+	ErrorClassRateLimited ErrorClass = "rate_limited" // 429
+	ErrorClassServerError ErrorClass = "server_error" // 5xx
+	ErrorClassClientError ErrorClass = "client_error" // 上記以外の4xx（バリデーションエラーなど）
+)
+
+// APIError はesa.io APIがエラーステータスを返したことを表す
+// errors.As で取り出すか、errors.Is で ErrNotFound などと比較する
+type APIError struct {
+	StatusCode int
+	// Code はesa.ioのエラーコード（レスポンスの "error"、例: "not_found"。JSONでない場合は空）
+	Code string
+	// Message はサニタイズ済みのエラーメッセージ（レスポンスの "message"、なければレスポンスボディ）
+	Message string
+	// RetryAfter はサーバーが指示した再試行までの待ち時間（Retry-After / X-RateLimit-Reset、指示がなければ0）
+	RetryAfter time.Duration
+	// RateLimit はレスポンスのレート制限情報（X-RateLimit-* ヘッダーがなければnil）
+	RateLimit *RateLimit
+}
+
+// RateLimit はX-RateLimit-*ヘッダーのレート制限情報
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("API error (status %d, %s): %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Message)
+}
+
+// Is はステータスコードに対応する ErrNotFound などとの比較を可能にします
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// Class はエラーの分類を返します
+func (e *APIError) Class() ErrorClass {
+	switch {
+	case e.StatusCode == http.StatusNotFound:
+		return ErrorClassNotFound
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrorClassForbidden
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrorClassRateLimited
+	case e.StatusCode >= 500:
+		return ErrorClassServerError
+	default:
+		return ErrorClassClientError
+	}
+}
+
+// transportError はレスポンスを受け取れなかった（接続断・タイムアウトなど）ことを表す
+// サーバーがリクエストを処理したかどうかは分からない
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+// isTransportError はerrがレスポンスを受け取れなかったエラーかを返します
+func isTransportError(err error) bool {
+	var te *transportError
+	return errors.As(err, &te)
+}
+
+// newAPIError はエラーレスポンスからAPIErrorを作成します
+// esa.ioのエラーレスポンス（{"error": "...", "message": "..."}）でなければボディ全体をメッセージにする
+func newAPIError(resp *http.Response, body []byte, now time.Time) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    sanitizeErrorMessage(string(body)),
+		RetryAfter: retryAfter(resp.Header, now),
+		RateLimit:  parseRateLimit(resp.Header),
+	}
+
+	var payload struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error != "" {
+		apiErr.Code = sanitizeErrorMessage(payload.Error)
+		if payload.Message != "" {
+			apiErr.Message = sanitizeErrorMessage(payload.Message)
+		}
+	}
+	return apiErr
+}
+
+// parseRateLimit はX-RateLimit-*ヘッダーを読み取ります（ヘッダーが揃っていなければnil）
+func parseRateLimit(header http.Header) *RateLimit {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return nil
+	}
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return nil
+	}
+	rl := &RateLimit{Limit: limit, Remaining: remaining}
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rl.Reset = time.Unix(reset, 0)
+	}
+	return rl
+}
+
+// retryAfter はレスポンスヘッダーから再試行までの待ち時間を求めます
+// Retry-After（秒数またはHTTP日付）を優先し、なければレート制限を使い切った場合の X-RateLimit-Reset（UNIX時刻）を使う
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	if v := header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return nonNegative(t.Sub(now))
+		}
+	}
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return nonNegative(time.Unix(reset, 0).Sub(now))
+		}
+	}
+	return 0
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
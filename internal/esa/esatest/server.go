@@ -0,0 +1,812 @@
+// Package esatest はテスト用のインメモリなesa.io APIサーバーを提供します
+//
+// 記事（作成・取得・更新・削除・検索）、リビジョン履歴、コメントを実装し、
+// 認証エラー（401）、記事がない（404）、不正なリクエスト（400）、レート制限（429）などを
+// esa.io APIと同じ形式（{"error": "...", "message": "..."}）で返す。
+// httptest.NewServer(esatest.NewServer(team, token)) のように使い、
+// クライアントには esa.EsaClient.SetBaseURL でサーバーのURLを設定する。
+package esatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRateLimit はレート制限の既定値（esa.io APIと同じ15分あたり300リクエスト）
+	DefaultRateLimit = 300
+	// DefaultRateLimitWindow はレート制限の既定の期間
+	DefaultRateLimitWindow = 15 * time.Minute
+
+	// maxRequestSize はリクエストボディの最大サイズ
+	maxRequestSize = 10 * 1024 * 1024
+
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// Post はサーバーが保持する記事（esa.io APIの記事レスポンスと同じ形式）
+type Post struct {
+	Number         int       `json:"number"`
+	Name           string    `json:"name"`
+	FullName       string    `json:"full_name"`
+	WIP            bool      `json:"wip"`
+	BodyMD         string    `json:"body_md"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Message        string    `json:"message"`
+	URL            string    `json:"url"`
+	Tags           []string  `json:"tags"`
+	Category       string    `json:"category"`
+	RevisionNumber int       `json:"revision_number"`
+	CommentsCount  int       `json:"comments_count"`
+}
+
+// Revision は記事の作成・更新ごとに記録する履歴
+type Revision struct {
+	Number    int       `json:"number"`
+	Name      string    `json:"name"`
+	Category  string    `json:"category"`
+	BodyMD    string    `json:"body_md"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Comment は記事へのコメント
+type Comment struct {
+	ID         int       `json:"id"`
+	PostNumber int       `json:"post_number"`
+	BodyMD     string    `json:"body_md"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	URL        string    `json:"url"`
+}
+
+// Server はインメモリなesa.io APIサーバー（http.Handler）
+// 1つのチームだけを扱い、アクセストークンが一致しないリクエストは401を返す
+type Server struct {
+	mu sync.Mutex
+
+	team  string
+	token string
+	now   func() time.Time
+
+	posts       map[int]*Post
+	revisions   map[int][]Revision
+	comments    map[int]*Comment
+	nextPost    int
+	nextComment int
+
+	rateLimit   int
+	rateWindow  time.Duration
+	remaining   int
+	rateResetAt time.Time
+
+	mux *http.ServeMux
+}
+
+// NewServer はteamのAPIをaccessTokenで受け付けるサーバーを作成します
+func NewServer(team, accessToken string) *Server {
+	s := &Server{
+		team:        team,
+		token:       accessToken,
+		now:         time.Now,
+		posts:       make(map[int]*Post),
+		revisions:   make(map[int][]Revision),
+		comments:    make(map[int]*Comment),
+		nextPost:    1,
+		nextComment: 1,
+		rateLimit:   DefaultRateLimit,
+		rateWindow:  DefaultRateLimitWindow,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/teams/{team}/posts", s.handleListPosts)
+	mux.HandleFunc("POST /v1/teams/{team}/posts", s.handleCreatePost)
+	mux.HandleFunc("GET /v1/teams/{team}/posts/{number}", s.handleGetPost)
+	mux.HandleFunc("PATCH /v1/teams/{team}/posts/{number}", s.handleUpdatePost)
+	mux.HandleFunc("DELETE /v1/teams/{team}/posts/{number}", s.handleDeletePost)
+	mux.HandleFunc("GET /v1/teams/{team}/posts/{number}/revisions", s.handleListRevisions)
+	mux.HandleFunc("GET /v1/teams/{team}/posts/{number}/comments", s.handleListComments)
+	mux.HandleFunc("POST /v1/teams/{team}/posts/{number}/comments", s.handleCreateComment)
+	mux.HandleFunc("GET /v1/teams/{team}/comments/{id}", s.handleGetComment)
+	mux.HandleFunc("PATCH /v1/teams/{team}/comments/{id}", s.handleUpdateComment)
+	mux.HandleFunc("DELETE /v1/teams/{team}/comments/{id}", s.handleDeleteComment)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+	})
+	s.mux = mux
+	return s
+}
+
+// SetNow は作成日時・更新日時とレート制限に使う現在時刻の関数を設定します
+func (s *Server) SetNow(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// SetRateLimit はwindowあたりのリクエスト数の上限を設定し、残り回数をリセットします
+func (s *Server) SetRateLimit(limit int, window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = limit
+	s.rateWindow = window
+	s.rateResetAt = time.Time{}
+}
+
+// AddPost はAPIを通さずに記事を追加し、追加した記事を返します（ガード管理外の記事などの準備用）
+// Numberが0なら採番し、RevisionNumberが0なら1にする
+func (s *Server) AddPost(p Post) Post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.Number == 0 {
+		p.Number = s.nextPost
+	}
+	if p.Number >= s.nextPost {
+		s.nextPost = p.Number + 1
+	}
+	if p.RevisionNumber == 0 {
+		p.RevisionNumber = 1
+	}
+	now := s.now()
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = now
+	}
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = p.CreatedAt
+	}
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
+	p.FullName = fullName(p.Category, p.Name)
+	stored := p
+	s.posts[p.Number] = &stored
+	s.revisions[p.Number] = append(s.revisions[p.Number], revisionOf(&stored))
+	return stored
+}
+
+// Post は記事を返します
+func (s *Server) Post(number int) (Post, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.posts[number]
+	if !ok {
+		return Post{}, false
+	}
+	return clonePost(p), true
+}
+
+// Posts はすべての記事を番号順に返します
+func (s *Server) Posts() []Post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	posts := make([]Post, 0, len(s.posts))
+	for _, p := range s.posts {
+		posts = append(posts, clonePost(p))
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].Number < posts[j].Number })
+	return posts
+}
+
+// Revisions は記事のリビジョン履歴を古い順に返します
+func (s *Server) Revisions(number int) []Revision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.revisions[number])
+}
+
+// ServeHTTP はリクエストを処理します
+// 認証・チーム・レート制限を確認してから各APIのハンドラーに渡す
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	if !s.allowRequest(w) {
+		writeError(w, http.StatusTooManyRequests, "too_many_requests", "Rate limit exceeded")
+		return
+	}
+	if team, ok := teamOf(r.URL.Path); ok && team != s.team {
+		writeError(w, http.StatusNotFound, "not_found", "Team not found")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized はAuthorizationヘッダーのアクセストークンが一致するかを返します
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.token != "" && token == s.token
+}
+
+// allowRequest はレート制限を1回分消費し、X-RateLimit-* ヘッダーを設定します
+// 上限に達している場合は Retry-After を設定して false を返す
+func (s *Server) allowRequest(w http.ResponseWriter) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.rateResetAt.IsZero() || !now.Before(s.rateResetAt) {
+		s.remaining = s.rateLimit
+		s.rateResetAt = now.Add(s.rateWindow)
+	}
+
+	header := w.Header()
+	header.Set("X-RateLimit-Limit", strconv.Itoa(s.rateLimit))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(s.rateResetAt.Unix(), 10))
+	if s.remaining <= 0 {
+		header.Set("X-RateLimit-Remaining", "0")
+		header.Set("Retry-After", strconv.Itoa(int(s.rateResetAt.Sub(now).Seconds()+1)))
+		return false
+	}
+	s.remaining--
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(s.remaining))
+	return true
+}
+
+// teamOf は /v1/teams/{team}/... のパスからチーム名を取り出します
+func teamOf(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, "/v1/teams/")
+	if !ok {
+		return "", false
+	}
+	team, _, _ := strings.Cut(rest, "/")
+	return team, team != ""
+}
+
+// postRequest は記事の作成・更新リクエスト（{"post": {...}}）
+// 更新では指定したフィールドだけを変更するため、すべてポインタで受け取る
+type postRequest struct {
+	Post *struct {
+		Name     *string   `json:"name"`
+		BodyMD   *string   `json:"body_md"`
+		Tags     *[]string `json:"tags"`
+		Category *string   `json:"category"`
+		WIP      *bool     `json:"wip"`
+		Message  *string   `json:"message"`
+	} `json:"post"`
+}
+
+// commentRequest はコメントの作成・更新リクエスト（{"comment": {...}}）
+type commentRequest struct {
+	Comment *struct {
+		BodyMD *string `json:"body_md"`
+	} `json:"comment"`
+}
+
+func (s *Server) handleListPosts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, ok := positiveParam(w, query.Get("page"), 1, "page")
+	if !ok {
+		return
+	}
+	perPage, ok := positiveParam(w, query.Get("per_page"), defaultPerPage, "per_page")
+	if !ok {
+		return
+	}
+	perPage = min(perPage, maxPerPage)
+	filter := parseSearchQuery(query.Get("q"))
+
+	s.mu.Lock()
+	var matched []Post
+	for _, p := range s.posts {
+		if filter.match(p) {
+			matched = append(matched, clonePost(p))
+		}
+	}
+	s.mu.Unlock()
+
+	sortPosts(matched, query.Get("sort"), query.Get("order"))
+
+	start := min((page-1)*perPage, len(matched))
+	end := min(start+perPage, len(matched))
+	resp := map[string]interface{}{
+		"posts":        append([]Post{}, matched[start:end]...),
+		"prev_page":    nil,
+		"next_page":    nil,
+		"total_count":  len(matched),
+		"page":         page,
+		"per_page":     perPage,
+		"max_per_page": maxPerPage,
+	}
+	if page > 1 {
+		resp["prev_page"] = page - 1
+	}
+	if end < len(matched) {
+		resp["next_page"] = page + 1
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleCreatePost(w http.ResponseWriter, r *http.Request) {
+	var req postRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.Post == nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "post parameter is required")
+		return
+	}
+	if req.Post.Name == nil || strings.TrimSpace(*req.Post.Name) == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "Name can't be blank")
+		return
+	}
+
+	p := Post{Name: *req.Post.Name, Tags: []string{}, WIP: true}
+	if req.Post.BodyMD != nil {
+		p.BodyMD = *req.Post.BodyMD
+	}
+	if req.Post.Category != nil {
+		p.Category = *req.Post.Category
+	}
+	if req.Post.Tags != nil {
+		p.Tags = slices.Clone(*req.Post.Tags)
+	}
+	if req.Post.WIP != nil {
+		p.WIP = *req.Post.WIP
+	}
+	if req.Post.Message != nil {
+		p.Message = *req.Post.Message
+	}
+
+	s.mu.Lock()
+	p.Number = s.nextPost
+	s.nextPost++
+	p.CreatedAt = s.now()
+	p.UpdatedAt = p.CreatedAt
+	p.RevisionNumber = 1
+	p.FullName = fullName(p.Category, p.Name)
+	p.URL = fmt.Sprintf("%s/posts/%d", origin(r), p.Number)
+	s.posts[p.Number] = &p
+	s.revisions[p.Number] = append(s.revisions[p.Number], revisionOf(&p))
+	created := clonePost(&p)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) handleGetPost(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	p, ok := s.lookupPost(w, r)
+	var post Post
+	if ok {
+		post = clonePost(p)
+	}
+	s.mu.Unlock()
+	if ok {
+		writeJSON(w, http.StatusOK, post)
+	}
+}
+
+func (s *Server) handleUpdatePost(w http.ResponseWriter, r *http.Request) {
+	var req postRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.Post == nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "post parameter is required")
+		return
+	}
+	if req.Post.Name != nil && strings.TrimSpace(*req.Post.Name) == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "Name can't be blank")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.lookupPost(w, r)
+	if !ok {
+		return
+	}
+	if req.Post.Name != nil {
+		p.Name = *req.Post.Name
+	}
+	if req.Post.BodyMD != nil {
+		p.BodyMD = *req.Post.BodyMD
+	}
+	if req.Post.Category != nil {
+		p.Category = *req.Post.Category
+	}
+	if req.Post.Tags != nil {
+		p.Tags = slices.Clone(*req.Post.Tags)
+	}
+	if req.Post.WIP != nil {
+		p.WIP = *req.Post.WIP
+	}
+	p.Message = ""
+	if req.Post.Message != nil {
+		p.Message = *req.Post.Message
+	}
+	p.FullName = fullName(p.Category, p.Name)
+	p.UpdatedAt = s.now()
+	p.RevisionNumber++
+	if p.URL == "" {
+		p.URL = fmt.Sprintf("%s/posts/%d", origin(r), p.Number)
+	}
+	s.revisions[p.Number] = append(s.revisions[p.Number], revisionOf(p))
+
+	writeJSON(w, http.StatusOK, clonePost(p))
+}
+
+func (s *Server) handleDeletePost(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.lookupPost(w, r)
+	if !ok {
+		return
+	}
+	delete(s.posts, p.Number)
+	delete(s.revisions, p.Number)
+	for id, c := range s.comments {
+		if c.PostNumber == p.Number {
+			delete(s.comments, id)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListRevisions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.lookupPost(w, r)
+	if !ok {
+		return
+	}
+	// 新しい順に返す
+	revisions := slices.Clone(s.revisions[p.Number])
+	slices.Reverse(revisions)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"revisions":   revisions,
+		"total_count": len(revisions),
+	})
+}
+
+func (s *Server) handleListComments(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.lookupPost(w, r)
+	if !ok {
+		return
+	}
+	comments := []Comment{}
+	for _, c := range s.comments {
+		if c.PostNumber == p.Number {
+			comments = append(comments, *c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"comments":    comments,
+		"total_count": len(comments),
+	})
+}
+
+func (s *Server) handleCreateComment(w http.ResponseWriter, r *http.Request) {
+	var req commentRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.Comment == nil || req.Comment.BodyMD == nil || strings.TrimSpace(*req.Comment.BodyMD) == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "Body md can't be blank")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.lookupPost(w, r)
+	if !ok {
+		return
+	}
+	c := &Comment{
+		ID:         s.nextComment,
+		PostNumber: p.Number,
+		BodyMD:     *req.Comment.BodyMD,
+		CreatedAt:  s.now(),
+	}
+	s.nextComment++
+	c.UpdatedAt = c.CreatedAt
+	c.URL = fmt.Sprintf("%s/posts/%d#comment-%d", origin(r), p.Number, c.ID)
+	s.comments[c.ID] = c
+	p.CommentsCount++
+
+	writeJSON(w, http.StatusCreated, *c)
+}
+
+func (s *Server) handleGetComment(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupComment(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, *c)
+}
+
+func (s *Server) handleUpdateComment(w http.ResponseWriter, r *http.Request) {
+	var req commentRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.Comment == nil || req.Comment.BodyMD == nil || strings.TrimSpace(*req.Comment.BodyMD) == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "Body md can't be blank")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupComment(w, r)
+	if !ok {
+		return
+	}
+	c.BodyMD = *req.Comment.BodyMD
+	c.UpdatedAt = s.now()
+	writeJSON(w, http.StatusOK, *c)
+}
+
+func (s *Server) handleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.lookupComment(w, r)
+	if !ok {
+		return
+	}
+	delete(s.comments, c.ID)
+	if p, ok := s.posts[c.PostNumber]; ok {
+		p.CommentsCount--
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lookupPost はパスの記事番号の記事を返します（なければ404を書き込んでfalse）
+// s.mu を保持して呼ぶ
+func (s *Server) lookupPost(w http.ResponseWriter, r *http.Request) (*Post, bool) {
+	number, err := strconv.Atoi(r.PathValue("number"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return nil, false
+	}
+	p, ok := s.posts[number]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return nil, false
+	}
+	return p, true
+}
+
+// lookupComment はパスのIDのコメントを返します（なければ404を書き込んでfalse）
+// s.mu を保持して呼ぶ
+func (s *Server) lookupComment(w http.ResponseWriter, r *http.Request) (*Comment, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return nil, false
+	}
+	c, ok := s.comments[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return nil, false
+	}
+	return c, true
+}
+
+// searchFilter はesa.ioの検索クエリのうち、サーバーが解釈する条件
+type searchFilter struct {
+	in         []string // in: カテゴリとその配下
+	on         []string // on: カテゴリ直下のみ
+	categories []string // category: カテゴリ名の部分一致
+	names      []string // name: 記事名の部分一致
+	tags       []string // tag: タグの完全一致
+	wip        *bool    // wip:true / wip:false
+	keywords   []string // 記事名・本文の部分一致
+}
+
+// parseSearchQuery は検索クエリを空白で区切って解釈します（"..." で囲んだ値は空白を含められる）
+// 解釈できない修飾子はキーワードとして扱う
+func parseSearchQuery(q string) searchFilter {
+	var f searchFilter
+	for _, token := range splitQuery(q) {
+		key, value, hasKey := strings.Cut(token, ":")
+		value = strings.Trim(value, `"`)
+		if !hasKey {
+			f.keywords = append(f.keywords, strings.Trim(token, `"`))
+			continue
+		}
+		switch key {
+		case "in":
+			f.in = append(f.in, strings.Trim(value, "/"))
+		case "on":
+			f.on = append(f.on, strings.Trim(value, "/"))
+		case "category":
+			f.categories = append(f.categories, value)
+		case "name", "title":
+			f.names = append(f.names, value)
+		case "tag":
+			f.tags = append(f.tags, value)
+		case "wip":
+			wip := value == "true"
+			f.wip = &wip
+		default:
+			f.keywords = append(f.keywords, strings.Trim(token, `"`))
+		}
+	}
+	return f
+}
+
+// splitQuery はクエリを空白で区切ります（"..." の中の空白では区切らない）
+func splitQuery(q string) []string {
+	var tokens []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range q {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t' || r == '　') && !quoted:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// match は記事がすべての条件を満たすかを返します
+func (f searchFilter) match(p *Post) bool {
+	for _, c := range f.in {
+		if p.Category != c && !strings.HasPrefix(p.Category, c+"/") {
+			return false
+		}
+	}
+	for _, c := range f.on {
+		if p.Category != c {
+			return false
+		}
+	}
+	for _, c := range f.categories {
+		if !strings.Contains(p.Category, c) {
+			return false
+		}
+	}
+	for _, n := range f.names {
+		if !strings.Contains(p.Name, n) {
+			return false
+		}
+	}
+	for _, t := range f.tags {
+		if !slices.Contains(p.Tags, t) {
+			return false
+		}
+	}
+	if f.wip != nil && p.WIP != *f.wip {
+		return false
+	}
+	for _, k := range f.keywords {
+		if !strings.Contains(p.Name, k) && !strings.Contains(p.BodyMD, k) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortPosts はsort（updated・created・number、既定はupdated）とorder（desc・asc、既定はdesc）で並べ替えます
+func sortPosts(posts []Post, sortKey, order string) {
+	less := func(a, b Post) bool {
+		switch sortKey {
+		case "created":
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		case "number":
+		default:
+			if !a.UpdatedAt.Equal(b.UpdatedAt) {
+				return a.UpdatedAt.Before(b.UpdatedAt)
+			}
+		}
+		return a.Number < b.Number
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if order == "asc" {
+			return less(posts[i], posts[j])
+		}
+		return less(posts[j], posts[i])
+	})
+}
+
+// positiveParam はクエリパラメータを正の整数として読み取ります（空ならdef、不正なら400を書き込んでfalse）
+func positiveParam(w http.ResponseWriter, value string, def int, name string) (int, bool) {
+	if value == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("%s must be a positive integer", name))
+		return 0, false
+	}
+	return n, true
+}
+
+// decodeRequest はJSONのリクエストボディを読み取ります（不正なら400を書き込んでfalse）
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "failed to read request body")
+		return false
+	}
+	if len(data) > maxRequestSize {
+		writeError(w, http.StatusRequestEntityTooLarge, "request_entity_too_large", "Request body is too large")
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON")
+		return false
+	}
+	return true
+}
+
+// writeJSON はステータスコードとJSONのレスポンスを書き込みます
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError はesa.io APIと同じ形式のエラーレスポンスを書き込みます
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{"error": code, "message": message})
+}
+
+// origin はリクエストを受けたサーバーのURL（記事のURLに使う）を返します
+func origin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// fullName はesa.ioの full_name（カテゴリ/記事名）を返します
+func fullName(category, name string) string {
+	if category == "" {
+		return name
+	}
+	return category + "/" + name
+}
+
+// revisionOf は記事の現在の内容をリビジョンとして返します
+func revisionOf(p *Post) Revision {
+	return Revision{
+		Number:    p.RevisionNumber,
+		Name:      p.Name,
+		Category:  p.Category,
+		BodyMD:    p.BodyMD,
+		Message:   p.Message,
+		CreatedAt: p.UpdatedAt,
+	}
+}
+
+// clonePost は呼び出し側が変更しても影響しない記事のコピーを返します
+func clonePost(p *Post) Post {
+	c := *p
+	c.Tags = slices.Clone(p.Tags)
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	return c
+}
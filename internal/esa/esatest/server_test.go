@@ -0,0 +1,215 @@
+package esatest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+// newClient はfakeサーバーを起動し、そこに接続するクライアントを返す
+func newClient(t *testing.T, token string) (*Server, *esa.EsaClient) {
+	t.Helper()
+	fake := NewServer("test-team", "test-token")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := esa.NewEsaClient("test-team", token)
+	if err := client.SetBaseURL(server.URL); err != nil {
+		t.Fatalf("SetBaseURL() error = %v", err)
+	}
+	client.SetRetryPolicy(esa.RetryPolicy{MaxAttempts: 1})
+	return fake, client
+}
+
+func TestServer_PostLifecycle(t *testing.T) {
+	fake, client := newClient(t, "test-token")
+
+	created, err := client.CreatePost(context.Background(), &esa.PostInput{Name: "Plan", Category: "LLM/Tasks/2026/01/28", Tags: []string{"repo"}, BodyMD: "first"})
+	if err != nil {
+		t.Fatalf("CreatePost() error = %v", err)
+	}
+	if created.Number != 1 || created.RevisionNumber != 1 || !strings.HasSuffix(created.URL, "/posts/1") {
+		t.Errorf("created = %+v", created)
+	}
+
+	updated, err := client.UpdatePost(context.Background(), created.Number, &esa.PostInput{Name: "Plan", Category: "LLM/Tasks/2026/01/28", BodyMD: "second"})
+	if err != nil {
+		t.Fatalf("UpdatePost() error = %v", err)
+	}
+	if updated.RevisionNumber != 2 || updated.BodyMD != "second" {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	got, err := client.GetPost(context.Background(), created.Number)
+	if err != nil {
+		t.Fatalf("GetPost() error = %v", err)
+	}
+	if got.BodyMD != "second" || got.Category != "LLM/Tasks/2026/01/28" {
+		t.Errorf("got = %+v", got)
+	}
+
+	revisions := fake.Revisions(created.Number)
+	if len(revisions) != 2 || revisions[0].BodyMD != "first" || revisions[1].Number != 2 {
+		t.Errorf("revisions = %+v", revisions)
+	}
+}
+
+func TestServer_Search(t *testing.T) {
+	fake, client := newClient(t, "test-token")
+	fake.AddPost(Post{Name: "API design", Category: "LLM/Tasks/2026/01/28", BodyMD: "x"})
+	fake.AddPost(Post{Name: "Other", Category: "LLM/Tasks/2026/01/29", BodyMD: "API"})
+	fake.AddPost(Post{Name: "API notes", Category: "Docs/Design", BodyMD: "x"})
+	fake.AddPost(Post{Name: "Prefix", Category: "LLM/Tasks2", BodyMD: "API"})
+
+	tests := []struct {
+		name string
+		q    string
+		want []int
+	}{
+		{"カテゴリ配下", `in:"LLM/Tasks"`, []int{2, 1}},
+		{"カテゴリ配下とキーワード", `in:"LLM/Tasks" API`, []int{2, 1}},
+		{"カテゴリ直下", `on:"Docs/Design"`, []int{3}},
+		{"記事名", `name:notes`, []int{3}},
+		{"該当なし", `in:"Nothing"`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := client.ListPosts(context.Background(), tt.q, 1, 20)
+			if err != nil {
+				t.Fatalf("ListPosts() error = %v", err)
+			}
+			var got []int
+			for _, p := range list.Posts {
+				got = append(got, p.Number)
+			}
+			if len(got) != len(tt.want) || list.TotalCount != len(tt.want) {
+				t.Fatalf("posts = %v (total %d), want %v", got, list.TotalCount, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("posts = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_Paging(t *testing.T) {
+	fake, client := newClient(t, "test-token")
+	for range 3 {
+		fake.AddPost(Post{Name: "Post", Category: "LLM/Tasks"})
+	}
+
+	list, err := client.ListPosts(context.Background(), `in:"LLM/Tasks"`, 1, 2)
+	if err != nil {
+		t.Fatalf("ListPosts() error = %v", err)
+	}
+	if len(list.Posts) != 2 || list.NextPage == nil || *list.NextPage != 2 {
+		t.Errorf("page 1 = %+v", list)
+	}
+	list, err = client.ListPosts(context.Background(), `in:"LLM/Tasks"`, 2, 2)
+	if err != nil {
+		t.Fatalf("ListPosts() error = %v", err)
+	}
+	if len(list.Posts) != 1 || list.NextPage != nil {
+		t.Errorf("page 2 = %+v", list)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	t.Run("存在しない記事は404", func(t *testing.T) {
+		_, client := newClient(t, "test-token")
+		_, err := client.GetPost(context.Background(), 99)
+		if !errors.Is(err, esa.ErrNotFound) {
+			t.Errorf("GetPost() error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("トークンが違えば401", func(t *testing.T) {
+		_, client := newClient(t, "wrong-token")
+		_, err := client.GetPost(context.Background(), 1)
+		if !errors.Is(err, esa.ErrUnauthorized) {
+			t.Errorf("GetPost() error = %v, want ErrUnauthorized", err)
+		}
+	})
+
+	t.Run("記事名がなければ400", func(t *testing.T) {
+		_, client := newClient(t, "test-token")
+		_, err := client.CreatePost(context.Background(), &esa.PostInput{Category: "LLM/Tasks"})
+		var apiErr *esa.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != "bad_request" {
+			t.Errorf("CreatePost() error = %v, want 400 bad_request", err)
+		}
+	})
+
+	t.Run("レート制限を超えると429", func(t *testing.T) {
+		fake, client := newClient(t, "test-token")
+		fake.SetRateLimit(1, time.Hour)
+		if _, err := client.ListPosts(context.Background(), "", 1, 20); err != nil {
+			t.Fatalf("ListPosts() error = %v", err)
+		}
+		_, err := client.ListPosts(context.Background(), "", 1, 20)
+		var apiErr *esa.APIError
+		if !errors.Is(err, esa.ErrRateLimited) || !errors.As(err, &apiErr) {
+			t.Fatalf("ListPosts() error = %v, want ErrRateLimited", err)
+		}
+		if apiErr.RateLimit == nil || apiErr.RateLimit.Limit != 1 || apiErr.RateLimit.Remaining != 0 || apiErr.RetryAfter <= 0 {
+			t.Errorf("APIError = %+v (rate limit %+v)", apiErr, apiErr.RateLimit)
+		}
+	})
+}
+
+func TestServer_Comments(t *testing.T) {
+	fake := NewServer("test-team", "test-token")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	post := fake.AddPost(Post{Name: "Plan", Category: "LLM/Tasks"})
+
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+"/v1/teams/test-team"+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer test-token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"コメントを作成", "POST", "/posts/1/comments", `{"comment": {"body_md": "LGTM"}}`, http.StatusCreated},
+		{"本文が空のコメント", "POST", "/posts/1/comments", `{"comment": {"body_md": ""}}`, http.StatusBadRequest},
+		{"存在しない記事へのコメント", "POST", "/posts/9/comments", `{"comment": {"body_md": "LGTM"}}`, http.StatusNotFound},
+		{"コメント一覧", "GET", "/posts/1/comments", "", http.StatusOK},
+		{"コメントを更新", "PATCH", "/comments/1", `{"comment": {"body_md": "Fixed"}}`, http.StatusOK},
+		{"コメントを削除", "DELETE", "/comments/1", "", http.StatusNoContent},
+		{"削除したコメント", "GET", "/comments/1", "", http.StatusNotFound},
+		{"不正なJSON", "POST", "/posts/1/comments", `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if resp := do(tt.method, tt.path, tt.body); resp.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.wantStatus)
+		}
+	}
+
+	if p, _ := fake.Post(post.Number); p.CommentsCount != 0 {
+		t.Errorf("comments_count = %d, want 0", p.CommentsCount)
+	}
+}
@@ -1,13 +1,19 @@
 package esa
 
+import "context"
+
 // EsaClientInterface はesa.io APIクライアントのインターフェース
+// ctxがキャンセルされると実行中のリクエストとリトライの待機を中断する
 type EsaClientInterface interface {
 	// CreatePost は新規記事を作成します
-	CreatePost(post *PostInput) (*Post, error)
+	CreatePost(ctx context.Context, post *PostInput) (*Post, error)
 
 	// UpdatePost は既存記事を更新します
-	UpdatePost(postNumber int, post *PostInput) (*Post, error)
+	UpdatePost(ctx context.Context, postNumber int, post *PostInput) (*Post, error)
 
 	// GetPost は記事を取得します（カテゴリ検証用）
-	GetPost(postNumber int) (*Post, error)
+	GetPost(ctx context.Context, postNumber int) (*Post, error)
+
+	// ListPosts は記事を検索します（qはesa.ioの検索クエリ、pageは1から）
+	ListPosts(ctx context.Context, q string, page, perPage int) (*PostList, error)
 }
@@ -0,0 +1,128 @@
+package esa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RetryPolicy はAPIリクエストのリトライ方針
+// ゼロ値のフィールドは DefaultRetryPolicy の値を使う
+type RetryPolicy struct {
+	// MaxAttempts は最初の試行を含む最大試行回数
+	MaxAttempts int
+	// BaseDelay は1回目のリトライまでの待ち時間（以降は倍々に増やす）
+	BaseDelay time.Duration
+	// MaxDelay は1回の待ち時間の上限。サーバーがこれより長い待ち時間を指示した場合はリトライしない
+	MaxDelay time.Duration
+	// Sleep は待機に使う関数（テストで実際に待たないよう差し替える）
+	// ctxがキャンセルされたら待機を中断してctxのエラーを返す
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// DefaultRetryPolicy は既定のリトライ方針を返します
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   1 * time.Second,
+		MaxDelay:    30 * time.Second,
+		Sleep:       sleepContext,
+	}
+}
+
+// sleepContext はdだけ待機します（ctxがキャンセルされたら中断する）
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withDefaults はゼロ値のフィールドを既定値で埋めたポリシーを返します
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	def := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = def.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = def.MaxDelay
+	}
+	if p.Sleep == nil {
+		p.Sleep = def.Sleep
+	}
+	return p
+}
+
+// isRetryable は冪等なリクエスト（GET/PATCH）をリトライしてよいエラーかを返します
+// レート制限・サーバーエラー・レスポンスを受け取れなかった場合のみリトライし、
+// 4xx（バリデーションエラー、権限エラー、記事がない）やレスポンスの解析エラーはリトライしない
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		class := apiErr.Class()
+		return class == ErrorClassRateLimited || class == ErrorClassServerError
+	}
+	return isTransportError(err)
+}
+
+// isAmbiguousFailure はリクエストがサーバーで処理されたか分からない失敗かを返します
+// レート制限はリクエストが処理されていないことが明らかなため含めない
+func isAmbiguousFailure(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Class() == ErrorClassServerError
+	}
+	return isTransportError(err)
+}
+
+// isRetryableCreate は新規作成（POST）をリトライしてよいエラーかを返します
+// POSTは冪等でないため、リクエストが処理されていないことが明らかなレート制限のみリトライする
+func isRetryableCreate(err error) bool {
+	return isRetryable(err) && !isAmbiguousFailure(err)
+}
+
+// withRetry はリトライ方針に従って、retryableがリトライしてよいとするエラーの間attemptを繰り返します
+// ctxがキャンセルされたらリトライせずに終了する
+func (c *EsaClient) withRetry(ctx context.Context, retryable func(error) bool, attempt func() error) error {
+	policy := c.retryPolicy.withDefaults()
+	backoff := policy.BaseDelay
+
+	var err error
+	n := 1
+	for ; ; n++ {
+		err = attempt()
+		if err == nil {
+			return nil
+		}
+		if n >= policy.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+			break
+		}
+
+		// サーバーが待ち時間を指示していればそれに従う（長すぎる場合は諦める）
+		wait := backoff
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			if apiErr.RetryAfter > policy.MaxDelay {
+				break
+			}
+			wait = apiErr.RetryAfter
+		}
+		if sleepErr := policy.Sleep(ctx, min(wait, policy.MaxDelay)); sleepErr != nil {
+			return fmt.Errorf("retry canceled after %d attempts (last error: %v): %w", n, err, sleepErr)
+		}
+		backoff *= 2 // 指数バックオフ
+	}
+
+	if n == 1 {
+		return err
+	}
+	return fmt.Errorf("request failed after %d attempts: %w", n, err)
+}
@@ -0,0 +1,397 @@
+package esa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNow はテストで使う固定の現在時刻
+var fakeNow = time.Date(2026, 1, 28, 12, 0, 0, 0, time.UTC)
+
+// newTestClient はserverに接続し、実際には待たないクライアントを返す（待ち時間はsleptに記録する）
+func newTestClient(server *httptest.Server, slept *[]time.Duration) *EsaClient {
+	client := NewEsaClient("test-team", "test-token")
+	client.baseURL = server.URL
+	client.now = func() time.Time { return fakeNow }
+	client.SetRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		Sleep: func(_ context.Context, d time.Duration) error {
+			*slept = append(*slept, d)
+			return nil
+		},
+	})
+	return client
+}
+
+// scriptedResponse はテストサーバーが返すレスポンス
+type scriptedResponse struct {
+	status int
+	header map[string]string
+	body   string
+	drop   bool // レスポンスを返さずに接続を切る
+}
+
+// scriptedHandler はレスポンスを順に返すハンドラーで、受け取ったリクエストを "METHOD path" で記録する
+func scriptedHandler(t *testing.T, requests *[]string, responses ...scriptedResponse) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		*requests = append(*requests, r.Method+" "+r.URL.Path)
+		if len(responses) == 0 {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		resp := responses[0]
+		responses = responses[1:]
+		if resp.drop {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Fatalf("hijack failed: %v", err)
+			}
+			conn.Close()
+			return
+		}
+		for k, v := range resp.header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(resp.status)
+		fmt.Fprint(w, resp.body)
+	}
+}
+
+const okPostBody = `{"number": 1, "name": "Test", "category": "LLM/Tasks", "body_md": "## Test"}`
+
+func TestGetPost_RetryPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		responses    []scriptedResponse
+		wantErr      bool
+		wantAttempts int
+		wantSleeps   []time.Duration
+	}{
+		{
+			name:         "サーバーエラーは指数バックオフでリトライ",
+			responses:    []scriptedResponse{{status: 500}, {status: 502}, {status: 200, body: okPostBody}},
+			wantAttempts: 3,
+			wantSleeps:   []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:         "404はリトライしない",
+			responses:    []scriptedResponse{{status: 404, body: `{"error":"not_found"}`}},
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "403はリトライしない",
+			responses:    []scriptedResponse{{status: 403}},
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Retry-Afterに従う",
+			responses:    []scriptedResponse{{status: 429, header: map[string]string{"Retry-After": "5"}}, {status: 200, body: okPostBody}},
+			wantAttempts: 2,
+			wantSleeps:   []time.Duration{5 * time.Second},
+		},
+		{
+			name: "X-RateLimit-Resetまで待つ",
+			responses: []scriptedResponse{
+				{status: 429, header: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": fmt.Sprint(fakeNow.Add(10 * time.Second).Unix())}},
+				{status: 200, body: okPostBody},
+			},
+			wantAttempts: 2,
+			wantSleeps:   []time.Duration{10 * time.Second},
+		},
+		{
+			name:         "指示された待ち時間が長すぎる場合は諦める",
+			responses:    []scriptedResponse{{status: 429, header: map[string]string{"Retry-After": "600"}}},
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "通信エラーはリトライ",
+			responses:    []scriptedResponse{{drop: true}, {status: 200, body: okPostBody}},
+			wantAttempts: 2,
+			wantSleeps:   []time.Duration{time.Second},
+		},
+		{
+			name:         "最大試行回数まで失敗",
+			responses:    []scriptedResponse{{status: 503}, {status: 503}, {status: 503}},
+			wantErr:      true,
+			wantAttempts: 3,
+			wantSleeps:   []time.Duration{time.Second, 2 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			server := httptest.NewServer(scriptedHandler(t, &requests, tt.responses...))
+			defer server.Close()
+			var slept []time.Duration
+			client := newTestClient(server, &slept)
+
+			post, err := client.GetPost(context.Background(), 1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetPost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && post.Number != 1 {
+				t.Errorf("post = %+v", post)
+			}
+			if len(requests) != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", len(requests), tt.wantAttempts)
+			}
+			if !slices.Equal(slept, tt.wantSleeps) {
+				t.Errorf("sleeps = %v, want %v", slept, tt.wantSleeps)
+			}
+		})
+	}
+}
+
+func TestGetPost_APIError(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(scriptedHandler(t, &requests, scriptedResponse{status: 404, body: "not\x00found"}))
+	defer server.Close()
+	var slept []time.Duration
+
+	_, err := newTestClient(server, &slept).GetPost(context.Background(), 1)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != 404 || apiErr.Class() != ErrorClassNotFound || apiErr.Message != "notfound" {
+		t.Errorf("unexpected APIError: %+v", apiErr)
+	}
+	if err.Error() != "API error (status 404): notfound" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
+
+func TestGetPost_APIErrorPayload(t *testing.T) {
+	reset := fakeNow.Add(15 * time.Minute).Unix()
+	var requests []string
+	server := httptest.NewServer(scriptedHandler(t, &requests, scriptedResponse{
+		status: 404,
+		header: map[string]string{"X-RateLimit-Limit": "300", "X-RateLimit-Remaining": "299", "X-RateLimit-Reset": fmt.Sprint(reset)},
+		body:   `{"error":"not_found","message":"Not\u001b[31m found"}`,
+	}))
+	defer server.Close()
+	var slept []time.Duration
+
+	_, err := newTestClient(server, &slept).GetPost(context.Background(), 1)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want *APIError", err)
+	}
+	if apiErr.Code != "not_found" || apiErr.Message != "Not[31m found" {
+		t.Errorf("unexpected APIError: %+v", apiErr)
+	}
+	if apiErr.RateLimit == nil || apiErr.RateLimit.Limit != 300 || apiErr.RateLimit.Remaining != 299 || apiErr.RateLimit.Reset.Unix() != reset {
+		t.Errorf("RateLimit = %+v", apiErr.RateLimit)
+	}
+	if err.Error() != "API error (status 404, not_found): Not[31m found" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
+
+func TestAPIError_Is(t *testing.T) {
+	sentinels := []error{ErrNotFound, ErrUnauthorized, ErrForbidden, ErrRateLimited}
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{404, ErrNotFound},
+		{401, ErrUnauthorized},
+		{403, ErrForbidden},
+		{429, ErrRateLimited},
+		{500, nil},
+		{400, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.status), func(t *testing.T) {
+			err := fmt.Errorf("failed to get post: %w", &APIError{StatusCode: tt.status})
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					t.Errorf("errors.Is(%v) = %v", sentinel, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCreatePost_RetryPolicy(t *testing.T) {
+	input := &PostInput{Name: "Test", Category: "LLM/Tasks", BodyMD: "## Test"}
+
+	tests := []struct {
+		name         string
+		responses    []scriptedResponse
+		wantErr      string
+		wantNumber   int
+		wantRequests []string
+	}{
+		{
+			// 作成済みでも検索に反映されるまで時間がかかるため、確認せずに再送すると重複しうる
+			name:         "5xxの後は作成済みか分からないため再送しない",
+			responses:    []scriptedResponse{{status: 500}, {status: 201, body: okPostBody}},
+			wantErr:      "the post may have been created",
+			wantRequests: []string{"POST /v1/teams/test-team/posts"},
+		},
+		{
+			name:         "通信エラーの後は作成済みか分からないため再送しない",
+			responses:    []scriptedResponse{{drop: true}, {status: 201, body: okPostBody}},
+			wantErr:      "the post may have been created",
+			wantRequests: []string{"POST /v1/teams/test-team/posts"},
+		},
+		{
+			name:         "レート制限は再送する",
+			responses:    []scriptedResponse{{status: 429, header: map[string]string{"Retry-After": "1"}}, {status: 201, body: okPostBody}},
+			wantNumber:   1,
+			wantRequests: []string{"POST /v1/teams/test-team/posts", "POST /v1/teams/test-team/posts"},
+		},
+		{
+			name:         "バリデーションエラーはリトライしない",
+			responses:    []scriptedResponse{{status: 400, body: `{"error":"bad_request"}`}},
+			wantErr:      "API error (status 400, bad_request)",
+			wantRequests: []string{"POST /v1/teams/test-team/posts"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			server := httptest.NewServer(scriptedHandler(t, &requests, tt.responses...))
+			defer server.Close()
+			var slept []time.Duration
+
+			post, err := newTestClient(server, &slept).CreatePost(context.Background(), input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CreatePost() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("CreatePost() error = %v", err)
+			} else if post.Number != tt.wantNumber {
+				t.Errorf("post number = %d, want %d", post.Number, tt.wantNumber)
+			}
+			if !slices.Equal(requests, tt.wantRequests) {
+				t.Errorf("requests = %q, want %q", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestGetPostContext_CanceledDuringBackoff(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(scriptedHandler(t, &requests, scriptedResponse{status: 503}))
+	defer server.Close()
+	var slept []time.Duration
+	client := newTestClient(server, &slept)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.SetRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		Sleep: func(ctx context.Context, d time.Duration) error {
+			cancel() // 待機中にCtrl-Cされた
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	_, err := client.GetPost(ctx, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetPostContext() error = %v, want context.Canceled", err)
+	}
+	if len(requests) != 1 {
+		t.Errorf("attempts = %d, want 1", len(requests))
+	}
+}
+
+func TestCreatePostContext_CanceledInFlight(t *testing.T) {
+	received := make(chan struct{})
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		io.Copy(io.Discard, r.Body) // ボディを読み終えるまで切断を検知できない
+		close(received)
+		<-r.Context().Done() // 応答しない
+	}))
+	defer server.Close()
+	var slept []time.Duration
+	client := newTestClient(server, &slept)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-received
+		cancel()
+	}()
+
+	_, err := client.CreatePost(ctx, &PostInput{Name: "Test", Category: "LLM/Tasks", BodyMD: "## Test"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CreatePostContext() error = %v, want context.Canceled", err)
+	}
+	if !strings.Contains(err.Error(), "the post may have been created") {
+		t.Errorf("error = %v", err)
+	}
+	// 中断後は作成済みの確認も再送もしない
+	if len(requests) != 1 || len(slept) != 0 {
+		t.Errorf("requests = %q, sleeps = %v", requests, slept)
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(scriptedHandler(t, &requests))
+	defer server.Close()
+	var slept []time.Duration
+	client := newTestClient(server, &slept)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.GetPost(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetPost() error = %v, want context.Canceled", err)
+	}
+	if _, err := client.ListPosts(ctx, "", 1, 20); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListPosts() error = %v, want context.Canceled", err)
+	}
+	if len(requests) != 0 {
+		t.Errorf("requests = %q, want none", requests)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{name: "秒数", header: http.Header{"Retry-After": {"3"}}, want: 3 * time.Second},
+		{name: "HTTP日付", header: http.Header{"Retry-After": {fakeNow.Add(7 * time.Second).Format(http.TimeFormat)}}, want: 7 * time.Second},
+		{name: "過去の日付", header: http.Header{"Retry-After": {fakeNow.Add(-time.Minute).Format(http.TimeFormat)}}, want: 0},
+		{name: "残りがあればX-RateLimit-Resetは使わない", header: http.Header{"X-Ratelimit-Remaining": {"10"}, "X-Ratelimit-Reset": {fmt.Sprint(fakeNow.Add(time.Minute).Unix())}}, want: 0},
+		{name: "指示なし", header: http.Header{}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryAfter(tt.header, fakeNow); got != tt.want {
+				t.Errorf("retryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
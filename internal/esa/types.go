@@ -1,5 +1,7 @@
 package esa
 
+import "time"
+
 // PostInput はesa.io APIへの投稿リクエスト
 type PostInput struct {
 	Name     string   `json:"name"`
@@ -19,4 +21,16 @@ type Post struct {
 	BodyMD   string   `json:"body_md"`
 	WIP      bool     `json:"wip"`
 	URL      string   `json:"url"`
+
+	// リビジョン情報（楽観的排他制御に使う）
+	RevisionNumber int       `json:"revision_number"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// PostList はesa.io APIの記事一覧レスポンス
+type PostList struct {
+	Posts      []Post `json:"posts"`
+	NextPage   *int   `json:"next_page"` // 次のページがない場合はnil
+	TotalCount int    `json:"total_count"`
 }
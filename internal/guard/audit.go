@@ -0,0 +1,323 @@
+package guard
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+const (
+	// AuditOperationCreate は記事の新規作成
+	AuditOperationCreate = "create"
+	// AuditOperationUpdate は既存記事の更新
+	AuditOperationUpdate = "update"
+	// AuditOperationDelete は記事の削除（proxy経由のみ）
+	AuditOperationDelete = "delete"
+
+	// DefaultAuditLimit は audit で表示する記録数の既定値
+	DefaultAuditLimit = 50
+
+	// auditRedacted は監査ログから取り除いた秘密情報の代わりに書く文字列
+	auditRedacted = "[REDACTED]"
+)
+
+// AuditRecord は監査ログ（JSONL）の1行で、esa.ioへの書き込み1回を表す
+// 本文そのものは記録せず、送信した本文のSHA-256だけを残す
+type AuditRecord struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Operation  string    `json:"operation"` // create・update・delete のいずれか
+	PostNumber int       `json:"post_number,omitempty"`
+	Category   string    `json:"category"`
+	Repo       string    `json:"repo,omitempty"` // 記事に付けたリポジトリ名のタグ
+	BodySHA256 string    `json:"body_sha256"`
+	Changes    []string  `json:"changes,omitempty"` // 埋め込みJSONの意味的な変更の要約
+	Status     string    `json:"status"`            // "ok" または "error"
+	Error      string    `json:"error,omitempty"`
+	Revision   int       `json:"revision_number,omitempty"`
+}
+
+// AuditLog はesa.ioへの書き込みを監査ログに追記します
+// 複数のgoroutine（MCPサーバーなど）から同時に使える
+type AuditLog struct {
+	mu      sync.Mutex
+	w       io.Writer
+	command string
+	secrets []string
+	now     func() time.Time
+}
+
+// NewAuditLog はwに追記する監査ログを作成します
+// secretsに渡した文字列（アクセストークンなど）はエラーメッセージに含まれていても記録しない
+func NewAuditLog(w io.Writer, command string, secrets ...string) *AuditLog {
+	var nonEmpty []string
+	for _, s := range secrets {
+		if s != "" {
+			nonEmpty = append(nonEmpty, s)
+		}
+	}
+	return &AuditLog{w: w, command: command, secrets: nonEmpty, now: time.Now}
+}
+
+// Record は1件の書き込みを監査ログに追記します
+// 1行を1回のWriteで書き込むため、O_APPENDで開いたファイルなら他のプロセスの追記と混ざらない
+func (l *AuditLog) Record(record AuditRecord) error {
+	if l == nil {
+		return nil
+	}
+	if record.Time.IsZero() {
+		record.Time = l.now()
+	}
+	if record.Command == "" {
+		record.Command = l.command
+	}
+	record.Error = l.redact(record.Error)
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// redact は秘密情報を伏せ字に置き換えます
+func (l *AuditLog) redact(s string) string {
+	for _, secret := range l.secrets {
+		s = strings.ReplaceAll(s, secret, auditRedacted)
+	}
+	return s
+}
+
+// recordWrite はCreatePost/UpdatePostの結果を監査ログに記録します
+// 監査ログへの書き込みに失敗しても、esa.io上の書き込みは済んでいるため警告にとどめる
+func recordWrite(audit *AuditLog, operation string, postNumber int, input *PostInput, repoName string, bodyMD string, previous *PostInput, post *esa.Post, writeErr error) {
+	if audit == nil {
+		return
+	}
+
+	sum := sha256.Sum256([]byte(bodyMD))
+	record := AuditRecord{
+		Operation:  operation,
+		PostNumber: postNumber,
+		Category:   input.Category,
+		Repo:       repoName,
+		BodySHA256: hex.EncodeToString(sum[:]),
+		Changes:    summarizeChanges(previous, input),
+		Status:     "ok",
+	}
+	if writeErr != nil {
+		record.Status = "error"
+		record.Error = writeErr.Error()
+	} else if post != nil {
+		record.PostNumber = post.Number
+		record.Revision = post.RevisionNumber
+	}
+
+	if err := audit.Record(record); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// summarizeChanges は埋め込みJSONの変更を人が読める形に要約します
+// previousがnil（新規作成やガード管理外の記事の引き継ぎ）の場合は内容の概要を返す
+func summarizeChanges(previous, next *PostInput) []string {
+	if previous == nil {
+		return []string{fmt.Sprintf("%d tasks", len(next.Body.Tasks))}
+	}
+
+	var changes []string
+	if previous.Name != next.Name {
+		changes = append(changes, "name changed")
+	}
+	if previous.Category != next.Category {
+		changes = append(changes, fmt.Sprintf("category: %s -> %s", previous.Category, next.Category))
+	}
+	if previous.Body.Background != next.Body.Background {
+		changes = append(changes, "background changed")
+	}
+	if !slices.Equal(previous.Body.Instructions, next.Body.Instructions) {
+		changes = append(changes, "instructions changed")
+	}
+	if !slices.Equal(previous.Body.RelatedLinks, next.Body.RelatedLinks) {
+		changes = append(changes, "related_links changed")
+	}
+
+	before := make(map[string]Task, len(previous.Body.Tasks))
+	for _, task := range previous.Body.Tasks {
+		before[task.ID] = task
+	}
+	for _, task := range next.Body.Tasks {
+		old, ok := before[task.ID]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%s added", task.ID))
+			continue
+		}
+		delete(before, task.ID)
+		if old.Status != task.Status {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", task.ID, old.Status, task.Status))
+		}
+		if old.Title != task.Title || old.Description != task.Description ||
+			!slices.Equal(old.Summary, task.Summary) || !slices.Equal(old.DependsOn, task.DependsOn) ||
+			!slices.Equal(old.GitHubURLs, task.GitHubURLs) {
+			changes = append(changes, fmt.Sprintf("%s edited", task.ID))
+		}
+	}
+	for _, task := range previous.Body.Tasks {
+		if _, ok := before[task.ID]; ok {
+			changes = append(changes, fmt.Sprintf("%s removed", task.ID))
+		}
+	}
+	return changes
+}
+
+// AuditFilter は監査ログの絞り込み条件
+type AuditFilter struct {
+	PostNumber int       // 0より大きければその記事の記録のみ
+	Category   string    // このカテゴリ配下の記録のみ（空ならすべて）
+	Repo       string    // このリポジトリの記録のみ（空ならすべて）
+	Status     string    // "ok" または "error" の記録のみ（空ならすべて）
+	Since      time.Time // この時刻以降の記録のみ（ゼロ値ならすべて）
+	Limit      int       // 新しいものから最大件数（0以下なら無制限）
+}
+
+// match は記録が条件に合うかを返します
+func (f AuditFilter) match(record AuditRecord) bool {
+	if f.PostNumber > 0 && record.PostNumber != f.PostNumber {
+		return false
+	}
+	if f.Category != "" {
+		if ok, err := IsAllowedCategory(record.Category, []string{f.Category}); err != nil || !ok {
+			return false
+		}
+	}
+	if f.Repo != "" && record.Repo != f.Repo {
+		return false
+	}
+	if f.Status != "" && record.Status != f.Status {
+		return false
+	}
+	if !f.Since.IsZero() && record.Time.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// ExecuteAudit は監査ログを絞り込んで表示します
+func ExecuteAudit(logPath string, filter AuditFilter) error {
+	result, err := AuditEntries(logPath, filter)
+	if err != nil {
+		return err
+	}
+
+	records := *result.AuditRecords
+	if len(records) == 0 {
+		fmt.Println("No audit records found.")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, r := range records {
+			post := "-"
+			if r.PostNumber > 0 {
+				post = fmt.Sprintf("#%d", r.PostNumber)
+			}
+			repo := r.Repo
+			if repo == "" {
+				repo = "-"
+			}
+			detail := strings.Join(r.Changes, ", ")
+			if r.Error != "" {
+				detail = r.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				r.Time.Local().Format(time.RFC3339), r.Status, r.Command, r.Operation, post, r.Category, repo, detail)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return nil
+}
+
+// AuditEntries は監査ログを絞り込み、コマンドの実行結果として返します
+// 読めない行（書き込み途中で中断した行など）は飛ばして警告に残す
+func AuditEntries(logPath string, filter AuditFilter) (*Result, error) {
+	if filter.Category != "" {
+		if _, err := NormalizeCategory(filter.Category); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.Open(logPath)
+	if os.IsNotExist(err) {
+		result := newResult("audit")
+		result.AuditRecords = &[]AuditRecord{}
+		return result, nil
+	}
+	if err != nil {
+		return nil, WithKind(ErrorKindIO, fmt.Errorf("failed to open audit log: %w", err))
+	}
+	defer file.Close()
+
+	records, warnings, err := ReadAuditLog(file, filter)
+	if err != nil {
+		return nil, WithKind(ErrorKindIO, err)
+	}
+
+	result := newResult("audit")
+	result.AuditRecords = &records
+	result.Warnings = warnings
+	return result, nil
+}
+
+// ReadAuditLog は監査ログを読み込み、条件に合う記録を古い順に返します
+func ReadAuditLog(r io.Reader, filter AuditFilter) ([]AuditRecord, []string, error) {
+	records := []AuditRecord{}
+	var warnings []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxInputSize)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var record AuditRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipped invalid audit record at line %d: %v", lineNumber, err))
+			continue
+		}
+		if filter.match(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[len(records)-filter.Limit:]
+	}
+	return records, warnings, nil
+}
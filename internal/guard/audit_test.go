@@ -0,0 +1,199 @@
+package guard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+// decodeAuditRecords は監査ログの各行をデコードする
+func decodeAuditRecords(t *testing.T, log *bytes.Buffer) []AuditRecord {
+	t.Helper()
+	records, warnings, err := ReadAuditLog(bytes.NewReader(log.Bytes()), AuditFilter{})
+	if err != nil || len(warnings) > 0 {
+		t.Fatalf("ReadAuditLog() error = %v, warnings = %v", err, warnings)
+	}
+	return records
+}
+
+func TestPost_RecordsAudit(t *testing.T) {
+	const token = "secret-token-value"
+
+	t.Run("更新は変更の要約を記録する", func(t *testing.T) {
+		var log bytes.Buffer
+		input := managedInput(123)
+		input.Body.Tasks[1].Status = TaskStatusCompleted
+		input.Body.Tasks = append(input.Body.Tasks, Task{ID: "task-3", Title: "Task 3: Third", Status: TaskStatusNotStarted, Summary: []string{"s3"}, Description: "d3"})
+		client := &mockEsaClientForExecute{
+			updatePostFunc: func(number int, input *esa.PostInput) (*esa.Post, error) {
+				return &esa.Post{Number: number, RevisionNumber: 8}, nil
+			},
+		}
+
+		_, err := Post(context.Background(), input, NewPolicy([]string{"Claude Code/開発日誌"}), client, PostOptions{Audit: NewAuditLog(&log, "post", token)})
+		if err != nil {
+			t.Fatalf("Post() error = %v", err)
+		}
+
+		records := decodeAuditRecords(t, &log)
+		if len(records) != 1 {
+			t.Fatalf("got %d records, want 1", len(records))
+		}
+		r := records[0]
+		if r.Command != "post" || r.Operation != AuditOperationUpdate || r.Status != "ok" || r.PostNumber != 123 || r.Revision != 8 {
+			t.Errorf("unexpected record: %+v", r)
+		}
+		if r.Category != "Claude Code/開発日誌/2026/01/28" || len(r.BodySHA256) != 64 || r.Time.IsZero() {
+			t.Errorf("unexpected record: %+v", r)
+		}
+		if want := []string{"task-2: not_started -> completed", "task-3 added"}; !slices.Equal(r.Changes, want) {
+			t.Errorf("Changes = %q, want %q", r.Changes, want)
+		}
+	})
+
+	t.Run("失敗した書き込みもトークンを伏せて記録する", func(t *testing.T) {
+		var log bytes.Buffer
+		input := managedInput(1)
+		input.PostNumber = nil
+		input.CreateNew = true
+		client := &mockEsaClientForExecute{
+			createPostFunc: func(input *esa.PostInput) (*esa.Post, error) {
+				return nil, fmt.Errorf("API error (status 500): Authorization: Bearer %s", token)
+			},
+		}
+
+		_, err := Post(context.Background(), input, NewPolicy([]string{"Claude Code/開発日誌"}), client, PostOptions{Audit: NewAuditLog(&log, "post", token)})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		if strings.Contains(log.String(), token) {
+			t.Fatalf("audit log contains the token: %s", log.String())
+		}
+		records := decodeAuditRecords(t, &log)
+		if len(records) != 1 {
+			t.Fatalf("got %d records, want 1", len(records))
+		}
+		r := records[0]
+		if r.Operation != AuditOperationCreate || r.Status != "error" || !strings.Contains(r.Error, "[REDACTED]") {
+			t.Errorf("unexpected record: %+v", r)
+		}
+	})
+
+	t.Run("書き込み前に拒否した場合は記録しない", func(t *testing.T) {
+		var log bytes.Buffer
+		_, err := Post(context.Background(), managedInput(123), NewPolicy([]string{"Other"}), &mockEsaClientForExecute{}, PostOptions{Audit: NewAuditLog(&log, "post", token)})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if log.Len() != 0 {
+			t.Errorf("audit log = %q, want empty", log.String())
+		}
+	})
+}
+
+func TestSummarizeChanges(t *testing.T) {
+	previous := managedInput(123)
+
+	tests := []struct {
+		name   string
+		modify func(*PostInput)
+		want   []string
+	}{
+		{name: "変更なし", modify: func(*PostInput) {}, want: nil},
+		{name: "記事名と背景", modify: func(in *PostInput) { in.Name = "New"; in.Body.Background = "New background" }, want: []string{"name changed", "background changed"}},
+		{name: "タスクの削除", modify: func(in *PostInput) { in.Body.Tasks = in.Body.Tasks[:1] }, want: []string{"task-2 removed"}},
+		{name: "タスクの編集", modify: func(in *PostInput) { in.Body.Tasks[0].Description = "changed" }, want: []string{"task-1 edited"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := managedInput(123)
+			tt.modify(next)
+			if got := summarizeChanges(previous, next); !slices.Equal(got, tt.want) {
+				t.Errorf("summarizeChanges() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadAuditLog(t *testing.T) {
+	base := time.Date(2026, 1, 28, 10, 0, 0, 0, time.UTC)
+	records := []AuditRecord{
+		{Time: base, Command: "post", Operation: AuditOperationCreate, PostNumber: 1, Category: "LLM/Tasks/2026/01/28", Repo: "app", Status: "ok"},
+		{Time: base.Add(time.Hour), Command: "task", Operation: AuditOperationUpdate, PostNumber: 2, Category: "LLM/Tasks-evil/2026/01/28", Repo: "app", Status: "ok"},
+		{Time: base.Add(2 * time.Hour), Command: "post", Operation: AuditOperationUpdate, PostNumber: 1, Category: "LLM/Tasks/2026/01/28", Repo: "lib", Status: "error", Error: "boom"},
+	}
+	var log bytes.Buffer
+	for i, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		log.Write(append(line, '\n'))
+		if i == 0 {
+			log.WriteString("{\"time\": \"truncated\n")
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   []int // 期待する記録の時刻（base からの時間）
+	}{
+		{name: "条件なし", filter: AuditFilter{}, want: []int{0, 1, 2}},
+		{name: "記事番号", filter: AuditFilter{PostNumber: 1}, want: []int{0, 2}},
+		{name: "カテゴリは境界で判定", filter: AuditFilter{Category: "LLM/Tasks"}, want: []int{0, 2}},
+		{name: "リポジトリ", filter: AuditFilter{Repo: "app"}, want: []int{0, 1}},
+		{name: "失敗のみ", filter: AuditFilter{Status: "error"}, want: []int{2}},
+		{name: "時刻", filter: AuditFilter{Since: base.Add(30 * time.Minute)}, want: []int{1, 2}},
+		{name: "新しいものから件数制限", filter: AuditFilter{Limit: 2}, want: []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := ReadAuditLog(bytes.NewReader(log.Bytes()), tt.filter)
+			if err != nil {
+				t.Fatalf("ReadAuditLog() error = %v", err)
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0], "line 2") {
+				t.Errorf("warnings = %q, want one warning for line 2", warnings)
+			}
+			var hours []int
+			for _, r := range got {
+				hours = append(hours, int(r.Time.Sub(base).Hours()))
+			}
+			if !slices.Equal(hours, tt.want) {
+				t.Errorf("records at %v, want %v", hours, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditEntries(t *testing.T) {
+	t.Run("ログがまだない場合は空配列", func(t *testing.T) {
+		result, err := AuditEntries(t.TempDir()+"/audit.jsonl", AuditFilter{})
+		if err != nil {
+			t.Fatalf("AuditEntries() error = %v", err)
+		}
+		if result.AuditRecords == nil || len(*result.AuditRecords) != 0 {
+			t.Errorf("AuditRecords = %v, want empty list", result.AuditRecords)
+		}
+	})
+
+	t.Run("不正なカテゴリ", func(t *testing.T) {
+		_, err := AuditEntries(t.TempDir()+"/audit.jsonl", AuditFilter{Category: "LLM/../Secret"})
+		var ve *ValidationError
+		if !errors.As(err, &ve) {
+			t.Errorf("AuditEntries() error = %v, want ValidationError", err)
+		}
+	})
+}
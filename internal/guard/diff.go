@@ -1,6 +1,7 @@
 package guard
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -9,78 +10,88 @@ import (
 )
 
 // ExecuteDiff は既存記事との差分を標準出力に出力する。
-func ExecuteDiff(jsonPath string, teamName string, allowedCategories []string, accessToken string) error {
-	client := esa.NewEsaClient(teamName, accessToken)
-	return executeDiffWithClient(jsonPath, allowedCategories, client)
+func ExecuteDiff(ctx context.Context, jsonPath string, policy *Policy, client esa.EsaClientInterface) error {
+	result, err := DiffFile(ctx, jsonPath, policy, client)
+	if err != nil {
+		return err
+	}
+	fmt.Print(*result.Diff)
+
+	return nil
 }
 
-func executeDiffWithClient(jsonPath string, allowedCategories []string, client esa.EsaClientInterface) error {
+// DiffFile はJSONファイルと既存記事との差分を実行結果として返す。
+func DiffFile(ctx context.Context, jsonPath string, policy *Policy, client esa.EsaClientInterface) (*Result, error) {
 	input, err := ReadPostInputFromFile(jsonPath)
 	if err != nil {
-		return fmt.Errorf("failed to read JSON file: %w", err)
+		return nil, fmt.Errorf("failed to read JSON file: %w", err)
 	}
 
-	TrimPostInput(input)
-	if err := ValidatePostInputSchema(input); err != nil {
-		return fmt.Errorf("schema validation failed: %w", err)
+	diff, err := Diff(ctx, input, policy, client)
+	if err != nil {
+		return nil, err
 	}
-	if err := ValidatePostInput(input); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+
+	result := newResult("diff")
+	result.Diff = &diff
+	if input.PostNumber != nil {
+		result.PostNumber = *input.PostNumber
+	}
+	return result, nil
+}
+
+// Diff はPostInputを検証し、既存記事（新規作成時は空）との差分をunified diff形式で返す。
+func Diff(ctx context.Context, input *PostInput, policy *Policy, client esa.EsaClientInterface) (string, error) {
+	if err := ValidateInput(input); err != nil {
+		return "", err
 	}
 
 	// Generate markdown with embedded JSON (same format as post)
 	newMarkdown, err := GenerateMarkdownWithJSON(input)
 	if err != nil {
-		return fmt.Errorf("failed to generate markdown: %w", err)
+		return "", fmt.Errorf("failed to generate markdown: %w", err)
 	}
 
 	// サイズチェック: 新しいMarkdownが大きすぎる場合は拒否（DoS対策）
 	if len(newMarkdown) > MaxInputSize {
-		return fmt.Errorf("new markdown too large (%d bytes, max %d bytes)", len(newMarkdown), MaxInputSize)
+		return "", fmt.Errorf("new markdown too large (%d bytes, max %d bytes)", len(newMarkdown), MaxInputSize)
 	}
 
 	var oldMarkdown string
 
 	if input.CreateNew {
-		// 新規作成の場合でもカテゴリが許可範囲内か検証
+		// 新規作成の場合でもカテゴリへの作成が許可されているか検証
 		normalized, err := NormalizeCategory(input.Category)
 		if err != nil {
-			return fmt.Errorf("category normalization failed: %w", err)
-		}
-		allowed, err := IsAllowedCategory(normalized, allowedCategories)
-		if err != nil {
-			return fmt.Errorf("failed to check category: %w", err)
+			return "", fmt.Errorf("category normalization failed: %w", err)
 		}
-		if !allowed {
-			return fmt.Errorf("category not allowed: %s", input.Category)
+		if err := policy.Check("category", normalized, OperationCreate); err != nil {
+			return "", err
 		}
 
 		// 新規作成の場合は空文字列との差分
 		oldMarkdown = ""
 	} else {
 		// 既存記事を取得
-		existingPost, err := client.GetPost(*input.PostNumber)
+		existingPost, err := client.GetPost(ctx, *input.PostNumber)
 		if err != nil {
-			return fmt.Errorf("failed to get existing post: %w", err)
+			return "", WithKind(ErrorKindAPI, fmt.Errorf("failed to get existing post: %w", err))
 		}
 
 		// サイズチェック: 既存記事の本文が大きすぎる場合は拒否（DoS対策）
 		if len(existingPost.BodyMD) > MaxInputSize {
-			return fmt.Errorf("existing post body too large (%d bytes, max %d bytes)", len(existingPost.BodyMD), MaxInputSize)
+			return "", fmt.Errorf("existing post body too large (%d bytes, max %d bytes)", len(existingPost.BodyMD), MaxInputSize)
 		}
 
 		// セキュリティチェック: 既存記事のカテゴリが許可範囲内か検証
-		if err := ValidateUpdateRequest(existingPost.Category, input.Category, allowedCategories); err != nil {
-			return fmt.Errorf("category validation failed: %w", err)
+		if err := ValidateUpdateRequest(existingPost.Category, input.Category, policy); err != nil {
+			return "", fmt.Errorf("category validation failed: %w", err)
 		}
 
 		oldMarkdown = existingPost.BodyMD
 	}
 
-	diff := generateUnifiedDiff(oldMarkdown, newMarkdown)
-	fmt.Print(diff)
-
-	return nil
+	return generateUnifiedDiff(oldMarkdown, newMarkdown), nil
 }
 
 func generateUnifiedDiff(oldText, newText string) string {
@@ -1,6 +1,7 @@
 package guard
 
 import (
+	"context"
 	"io"
 	"os"
 	"path/filepath"
@@ -14,18 +15,22 @@ type mockEsaClient struct {
 	getPostFunc func(number int) (*esa.Post, error)
 }
 
-func (m *mockEsaClient) CreatePost(input *esa.PostInput) (*esa.Post, error) {
+func (m *mockEsaClient) CreatePost(ctx context.Context, input *esa.PostInput) (*esa.Post, error) {
 	return nil, nil
 }
 
-func (m *mockEsaClient) UpdatePost(number int, input *esa.PostInput) (*esa.Post, error) {
+func (m *mockEsaClient) UpdatePost(ctx context.Context, number int, input *esa.PostInput) (*esa.Post, error) {
 	return nil, nil
 }
 
-func (m *mockEsaClient) GetPost(number int) (*esa.Post, error) {
+func (m *mockEsaClient) GetPost(ctx context.Context, number int) (*esa.Post, error) {
 	return m.getPostFunc(number)
 }
 
+func (m *mockEsaClient) ListPosts(ctx context.Context, q string, page, perPage int) (*esa.PostList, error) {
+	return nil, nil
+}
+
 func TestExecuteDiff_WithPostNumber(t *testing.T) {
 	tmpDir := t.TempDir()
 	tmpFile := filepath.Join(tmpDir, "update.json")
@@ -63,12 +68,12 @@ func TestExecuteDiff_WithPostNumber(t *testing.T) {
 		},
 	}
 
-	allowedCategories := []string{"LLM/Tasks"}
+	policy := NewPolicy([]string{"LLM/Tasks"})
 
 	var output string
 	var execErr error
 	output = captureStdout(func() {
-		execErr = executeDiffWithClient(tmpFile, allowedCategories, mockClient)
+		execErr = ExecuteDiff(context.Background(), tmpFile, policy, mockClient)
 	})
 
 	if execErr != nil {
@@ -111,7 +116,7 @@ func TestExecuteDiff_CreateNew(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	allowedCategories := []string{"LLM/Tasks"}
+	policy := NewPolicy([]string{"LLM/Tasks"})
 	mockClient := &mockEsaClient{}
 
 	// 標準出力をキャプチャ
@@ -119,7 +124,7 @@ func TestExecuteDiff_CreateNew(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := executeDiffWithClient(tmpFile, allowedCategories, mockClient)
+	err := ExecuteDiff(context.Background(), tmpFile, policy, mockClient)
 
 	w.Close()
 	os.Stdout = oldStdout
@@ -155,9 +160,9 @@ func TestExecuteDiff_InvalidJSON(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	allowedCategories := []string{"LLM/Tasks"}
+	policy := NewPolicy([]string{"LLM/Tasks"})
 	mockClient := &mockEsaClient{}
-	err := executeDiffWithClient(tmpFile, allowedCategories, mockClient)
+	err := ExecuteDiff(context.Background(), tmpFile, policy, mockClient)
 	if err == nil {
 		t.Error("expected error for invalid JSON")
 	}
@@ -201,8 +206,8 @@ func TestExecuteDiff_CategoryNotAllowed(t *testing.T) {
 		},
 	}
 
-	allowedCategories := []string{"LLM/Tasks"}
-	err := executeDiffWithClient(tmpFile, allowedCategories, mockClient)
+	policy := NewPolicy([]string{"LLM/Tasks"})
+	err := ExecuteDiff(context.Background(), tmpFile, policy, mockClient)
 	if err == nil {
 		t.Fatal("expected error for category not allowed")
 	}
@@ -249,8 +254,8 @@ func TestExecuteDiff_CategoryChangeAttempt(t *testing.T) {
 		},
 	}
 
-	allowedCategories := []string{"LLM/Tasks"}
-	err := executeDiffWithClient(tmpFile, allowedCategories, mockClient)
+	policy := NewPolicy([]string{"LLM/Tasks"})
+	err := ExecuteDiff(context.Background(), tmpFile, policy, mockClient)
 	if err == nil {
 		t.Fatal("expected error for category change attempt")
 	}
@@ -308,14 +313,14 @@ func TestExecuteDiff_IdenticalContent(t *testing.T) {
 		},
 	}
 
-	allowedCategories := []string{"LLM/Tasks"}
+	policy := NewPolicy([]string{"LLM/Tasks"})
 
 	// 標準出力をキャプチャ
 	oldStdout := os.Stdout
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err = executeDiffWithClient(tmpFile, allowedCategories, mockClient)
+	err = ExecuteDiff(context.Background(), tmpFile, policy, mockClient)
 
 	w.Close()
 	os.Stdout = oldStdout
@@ -405,14 +410,14 @@ Task description
 		},
 	}
 
-	allowedCategories := []string{"LLM/Tasks"}
+	policy := NewPolicy([]string{"LLM/Tasks"})
 
 	// 標準出力をキャプチャ
 	oldStdout := os.Stdout
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := executeDiffWithClient(tmpFile, allowedCategories, mockClient)
+	err := ExecuteDiff(context.Background(), tmpFile, policy, mockClient)
 
 	w.Close()
 	os.Stdout = oldStdout
@@ -518,14 +523,14 @@ Task description
 		},
 	}
 
-	allowedCategories := []string{"LLM/Tasks"}
+	policy := NewPolicy([]string{"LLM/Tasks"})
 
 	// 標準出力をキャプチャ
 	oldStdout := os.Stdout
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := executeDiffWithClient(tmpFile, allowedCategories, mockClient)
+	err := ExecuteDiff(context.Background(), tmpFile, policy, mockClient)
 
 	w.Close()
 	os.Stdout = oldStdout
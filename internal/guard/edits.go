@@ -0,0 +1,395 @@
+package guard
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// EditMode はesa上で人が編集した内容が見つかった場合の扱い
+type EditMode string
+
+const (
+	EditModeAbort  EditMode = "abort"  // 更新を中止する（既定）
+	EditModeForce  EditMode = "force"  // 人の編集を破棄して上書きする
+	EditModeImport EditMode = "import" // 人の編集を入力JSONに取り込んでから更新する
+)
+
+// ParseEditMode は文字列をEditModeに変換します（空文字列は abort）
+func ParseEditMode(s string) (EditMode, error) {
+	switch EditMode(s) {
+	case "", EditModeAbort:
+		return EditModeAbort, nil
+	case EditModeForce, EditModeImport:
+		return EditMode(s), nil
+	default:
+		return "", fmt.Errorf("invalid edit mode %q (must be abort, force, or import)", s)
+	}
+}
+
+// HumanEdits は最後にガードが書き込んだ後、esa上で人が編集した内容
+type HumanEdits struct {
+	Sections []string // 編集されたセクションの見出し（例: "## サマリー", "### Task 1: 調査"）
+	Diff     string   // 埋め込みJSONから再生成した本文と実際の本文の差分
+}
+
+// DetectHumanEdits は埋め込みJSONから本文を再生成し、実際の本文と比較します。
+// 差分がなければ nil を返します。
+func DetectHumanEdits(bodyMD string) (*HumanEdits, error) {
+	embedded, err := ExtractEmbeddedJSON(bodyMD)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := normalizeBodyText(GenerateMarkdown(&embedded.Body))
+	actual := normalizeBodyText(markdownAfterEmbeddedJSON(bodyMD))
+	if expected == actual {
+		return nil, nil
+	}
+
+	return &HumanEdits{
+		Sections: editedSections(expected, actual),
+		Diff:     generateUnifiedDiff(expected+"\n", actual+"\n"),
+	}, nil
+}
+
+// ImportHumanEdits はesa上の人の編集をinputに取り込んだPostInputを返します。
+// 取り込めるのは背景・関連リンク・開発指針と、各タスクのステータス（サマリーのチェックボックスを含む）・要約・詳細です。
+// それ以外の編集（見出しや依存関係の変更、タスクの追加など）がある場合や、
+// inputが同じ項目を別の内容に変更している場合はエラーを返します（fail closed）。
+func ImportHumanEdits(input *PostInput, bodyMD string) (*PostInput, error) {
+	embedded, err := ExtractEmbeddedJSON(bodyMD)
+	if err != nil {
+		return nil, err
+	}
+
+	human, err := parseHumanEditedBody(&embedded.Body, normalizeBodyText(markdownAfterEmbeddedJSON(bodyMD)))
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := mergeHumanEdits(&embedded.Body, human, &input.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := *input
+	result.Body = *merged
+	return &result, nil
+}
+
+// markdownAfterEmbeddedJSON は埋め込みJSONコメントより後ろのマークダウンを返します
+func markdownAfterEmbeddedJSON(bodyMD string) string {
+	idx := strings.Index(bodyMD, ClosingTag)
+	if idx == -1 {
+		return bodyMD
+	}
+	return strings.TrimLeft(bodyMD[idx+len(ClosingTag):], "\r\n")
+}
+
+// normalizeBodyText はesa側で変わりうる改行コードと末尾の改行を揃えます
+func normalizeBodyText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.TrimRight(s, "\n")
+}
+
+// mdSection は見出しで区切ったマークダウンの1セクション
+type mdSection struct {
+	heading string
+	content string
+}
+
+// splitSections はマークダウンを "## " / "### " の見出し行で区切ります
+func splitSections(md string) []mdSection {
+	var sections []mdSection
+	current := mdSection{}
+	var sb strings.Builder
+	for _, line := range strings.Split(md, "\n") {
+		if strings.HasPrefix(line, "## ") || strings.HasPrefix(line, "### ") {
+			current.content = sb.String()
+			sections = append(sections, current)
+			current = mdSection{heading: line}
+			sb.Reset()
+			continue
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	current.content = sb.String()
+	sections = append(sections, current)
+	return sections
+}
+
+// editedSections は期待する本文と実際の本文で内容が異なるセクションの見出しを返します
+func editedSections(expected, actual string) []string {
+	expectedContents := make(map[string]string)
+	for _, s := range splitSections(expected) {
+		expectedContents[s.heading] = strings.TrimRight(s.content, "\n")
+	}
+
+	var edited []string
+	seen := make(map[string]bool)
+	for _, s := range splitSections(actual) {
+		seen[s.heading] = true
+		want, ok := expectedContents[s.heading]
+		if !ok {
+			edited = append(edited, sectionLabel(s.heading)+" (added)")
+		} else if want != strings.TrimRight(s.content, "\n") {
+			edited = append(edited, sectionLabel(s.heading))
+		}
+	}
+	for _, s := range splitSections(expected) {
+		if !seen[s.heading] {
+			edited = append(edited, sectionLabel(s.heading)+" (removed)")
+		}
+	}
+	return edited
+}
+
+// sectionLabel は報告用のセクション名を返します
+func sectionLabel(heading string) string {
+	if heading == "" {
+		return "(before first heading)"
+	}
+	return heading
+}
+
+// parseHumanEditedBody は人が編集した本文を解析し、取り込み可能な項目を反映したBodyを返します。
+// 解析結果から本文を再生成して元の本文と一致しない場合は、取り込めない編集があるとみなします。
+func parseHumanEditedBody(base *Body, actual string) (*Body, error) {
+	parsed := cloneBody(base)
+	checked := make([]bool, 0, len(base.Tasks))
+	var taskSections []mdSection
+
+	sections := splitSections(actual)
+	for i := 0; i < len(sections); i++ {
+		s := sections[i]
+		switch s.heading {
+		case "## サマリー":
+			for _, line := range strings.Split(s.content, "\n") {
+				if strings.HasPrefix(line, "- [x] ") {
+					checked = append(checked, true)
+				} else if strings.HasPrefix(line, "- [ ] ") {
+					checked = append(checked, false)
+				}
+			}
+		case "## 背景":
+			parsed.Background, parsed.RelatedLinks = parseBackgroundSection(s.content)
+		case "## 開発指針":
+			parsed.Instructions = nil
+			for _, line := range strings.Split(s.content, "\n") {
+				if item, ok := strings.CutPrefix(line, "- "); ok {
+					parsed.Instructions = append(parsed.Instructions, item)
+				}
+			}
+		case "## タスク":
+			for i+1 < len(sections) && strings.HasPrefix(sections[i+1].heading, "### ") {
+				i++
+				taskSections = append(taskSections, sections[i])
+			}
+		}
+	}
+
+	if len(taskSections) != len(base.Tasks) || len(checked) != len(base.Tasks) {
+		return nil, notImportableError(fmt.Sprintf("number of tasks changed in esa (expected %d)", len(base.Tasks)))
+	}
+	for i, s := range taskSections {
+		if s.heading != "### "+base.Tasks[i].Title {
+			return nil, notImportableError(fmt.Sprintf("task heading changed in esa: %s", s.heading))
+		}
+		parseTaskSection(&parsed.Tasks[i], s.content)
+	}
+
+	// 解析結果から再生成した本文が実際の本文と一致することを確認する
+	// チェックボックスはステータス行と独立して編集されうるため、実際の状態を反映して比較する
+	rendered := applySummaryCheckboxes(normalizeBodyText(GenerateMarkdown(parsed)), checked)
+	if rendered != actual {
+		return nil, notImportableError(fmt.Sprintf("edits in sections that cannot be imported: %s",
+			strings.Join(editedSections(rendered, actual), ", ")))
+	}
+
+	// チェックボックスが切り替えられたタスクはステータスに反映する
+	for i := range parsed.Tasks {
+		wasCompleted := base.Tasks[i].Status == TaskStatusCompleted
+		if checked[i] == wasCompleted {
+			continue
+		}
+		if checked[i] {
+			parsed.Tasks[i].Status = TaskStatusCompleted
+		} else {
+			parsed.Tasks[i].Status = TaskStatusInProgress
+		}
+	}
+
+	return parsed, nil
+}
+
+// parseBackgroundSection は背景セクションから背景と関連リンクを取り出します
+func parseBackgroundSection(content string) (string, []string) {
+	content = strings.TrimRight(content, "\n")
+	rest, ok := strings.CutPrefix(content, "関連リンク:\n")
+	if !ok {
+		return strings.TrimPrefix(content, "\n"), nil
+	}
+
+	var links []string
+	lines := strings.Split(rest, "\n")
+	for i, line := range lines {
+		link, ok := strings.CutPrefix(line, "- ")
+		if !ok {
+			// 関連リンクと背景の間は空行で区切られている
+			return strings.TrimPrefix(strings.Join(lines[i:], "\n"), "\n"), links
+		}
+		links = append(links, link)
+	}
+	return "", links
+}
+
+// parseTaskSection はタスクセクションからステータス・要約・詳細を取り出してtaskに反映します
+func parseTaskSection(task *Task, content string) {
+	lines := strings.Split(content, "\n")
+	inSummary := false
+	task.Summary = nil
+	for i, line := range lines {
+		if status, ok := strings.CutPrefix(line, "- Status: `"); ok {
+			task.Status = TaskStatus(strings.TrimSuffix(status, "`"))
+			continue
+		}
+		if line == "- 要約:" {
+			inSummary = true
+			continue
+		}
+		if inSummary {
+			if item, ok := strings.CutPrefix(line, "  - "); ok {
+				task.Summary = append(task.Summary, item)
+				continue
+			}
+			inSummary = false
+		}
+		if line == "<details><summary>詳細を開く</summary>" {
+			description := strings.Join(lines[i+1:], "\n")
+			if end := strings.LastIndex(description, "</details>"); end != -1 {
+				description = description[:end]
+			}
+			task.Description = strings.Trim(description, "\n")
+			return
+		}
+	}
+}
+
+// applySummaryCheckboxes はサマリーセクションのチェックボックスをcheckedの状態に書き換えます
+func applySummaryCheckboxes(md string, checked []bool) string {
+	lines := strings.Split(md, "\n")
+	inSummary := false
+	n := 0
+	for i, line := range lines {
+		if strings.HasPrefix(line, "## ") {
+			inSummary = line == "## サマリー"
+			continue
+		}
+		if !inSummary || n >= len(checked) {
+			continue
+		}
+		if title, ok := cutCheckbox(line); ok {
+			if checked[n] {
+				lines[i] = "- [x] " + title
+			} else {
+				lines[i] = "- [ ] " + title
+			}
+			n++
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cutCheckbox はチェックボックス行からタイトルを取り出します
+func cutCheckbox(line string) (string, bool) {
+	if title, ok := strings.CutPrefix(line, "- [x] "); ok {
+		return title, true
+	}
+	return strings.CutPrefix(line, "- [ ] ")
+}
+
+// mergeHumanEdits はbase（最後にガードが書き込んだ内容）を起点に、human（esa上の編集）とours（入力JSON）を3方向マージします
+func mergeHumanEdits(base, human, ours *Body) (*Body, error) {
+	merged := cloneBody(ours)
+	var conflicts []string
+
+	mergeString := func(name string, b, h string, o *string) {
+		if h == b || h == *o {
+			return
+		}
+		if *o == b {
+			*o = h
+			return
+		}
+		conflicts = append(conflicts, name)
+	}
+	mergeStrings := func(name string, b, h []string, o *[]string) {
+		if slices.Equal(h, b) || slices.Equal(h, *o) {
+			return
+		}
+		if slices.Equal(*o, b) {
+			*o = slices.Clone(h)
+			return
+		}
+		conflicts = append(conflicts, name)
+	}
+
+	mergeString("background", base.Background, human.Background, &merged.Background)
+	mergeStrings("related_links", base.RelatedLinks, human.RelatedLinks, &merged.RelatedLinks)
+	mergeStrings("instructions", base.Instructions, human.Instructions, &merged.Instructions)
+
+	oursIndex := make(map[string]int)
+	for i, t := range merged.Tasks {
+		oursIndex[t.ID] = i
+	}
+	for i, b := range base.Tasks {
+		h := human.Tasks[i]
+		j, ok := oursIndex[b.ID]
+		if !ok {
+			if h.Status != b.Status || !slices.Equal(h.Summary, b.Summary) || h.Description != b.Description {
+				conflicts = append(conflicts, fmt.Sprintf("task %s (edited in esa but removed from input)", b.ID))
+			}
+			continue
+		}
+		o := &merged.Tasks[j]
+		status := string(o.Status)
+		mergeString(fmt.Sprintf("task %s status", b.ID), string(b.Status), string(h.Status), &status)
+		o.Status = TaskStatus(status)
+		mergeStrings(fmt.Sprintf("task %s summary", b.ID), b.Summary, h.Summary, &o.Summary)
+		mergeString(fmt.Sprintf("task %s description", b.ID), b.Description, h.Description, &o.Description)
+	}
+
+	if len(conflicts) > 0 {
+		return nil, notImportableError(fmt.Sprintf("edits in esa conflict with the input JSON: %s", strings.Join(conflicts, ", ")))
+	}
+	return merged, nil
+}
+
+// cloneBody はBodyのディープコピーを返します
+func cloneBody(body *Body) *Body {
+	c := *body
+	c.RelatedLinks = slices.Clone(body.RelatedLinks)
+	c.Instructions = slices.Clone(body.Instructions)
+	c.Tasks = make([]Task, len(body.Tasks))
+	for i, t := range body.Tasks {
+		t.Summary = slices.Clone(t.Summary)
+		t.GitHubURLs = slices.Clone(t.GitHubURLs)
+		t.DependsOn = slices.Clone(t.DependsOn)
+		c.Tasks[i] = t
+	}
+	return &c
+}
+
+// humanEditsError は人の編集を検出して更新を中止したことを示すエラーを作成します
+func humanEditsError(postNumber int, edits *HumanEdits) error {
+	return WithKind(ErrorKindConflict, NewValidationError(ErrCodeHumanEditsDetected, fmt.Sprintf(
+		"post %d was edited in esa since the last guard write (sections: %s); use -edits import to merge the edits, -edits force to overwrite them, or check them with diff",
+		postNumber, strings.Join(edits.Sections, ", "))))
+}
+
+// notImportableError は人の編集を取り込めないことを示すエラーを作成します
+func notImportableError(message string) error {
+	return WithKind(ErrorKindConflict, NewValidationError(ErrCodeHumanEditsNotImportable, "cannot import human edits: "+message))
+}
@@ -0,0 +1,176 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+func TestDetectHumanEdits(t *testing.T) {
+	original := managedBody(123)
+
+	tests := []struct {
+		name         string
+		bodyMD       string
+		wantSections []string
+	}{
+		{"編集なし", original, nil},
+		{"改行コードと末尾改行の違いは無視", crlfAfterEmbeddedJSON(original) + "\r\n", nil},
+		{"チェックボックス", strings.Replace(original, "- [ ] Task 2: Second task", "- [x] Task 2: Second task", 1), []string{"## サマリー"}},
+		{"タスクの詳細", strings.Replace(original, "\nd2\n", "\nd2 fixed\n", 1), []string{"### Task 2: Second task"}},
+		{"セクション追加", original + "\n\n## メモ\n手書き", []string{"## メモ (added)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits, err := DetectHumanEdits(tt.bodyMD)
+			if err != nil {
+				t.Fatalf("DetectHumanEdits() error = %v", err)
+			}
+			if tt.wantSections == nil {
+				if edits != nil {
+					t.Fatalf("expected no edits, got %+v", edits)
+				}
+				return
+			}
+			if edits == nil {
+				t.Fatal("expected edits")
+			}
+			if !slices.Equal(edits.Sections, tt.wantSections) {
+				t.Errorf("Sections = %v, want %v", edits.Sections, tt.wantSections)
+			}
+			if edits.Diff == "" {
+				t.Error("expected diff")
+			}
+		})
+	}
+}
+
+func TestImportHumanEdits(t *testing.T) {
+	edited := managedBody(123)
+	edited = strings.Replace(edited, "- [ ] Task 2: Second task", "- [x] Task 2: Second task", 1)
+	edited = strings.Replace(edited, "\nd\n", "\nd (typo fixed)\n", 1)
+
+	// 入力JSONは背景だけを変更している
+	input := managedInput(123)
+	input.Body.Background = "Updated background"
+
+	merged, err := ImportHumanEdits(input, edited)
+	if err != nil {
+		t.Fatalf("ImportHumanEdits() error = %v", err)
+	}
+	if merged.Body.Background != "Updated background" {
+		t.Errorf("Background = %q, want input's change", merged.Body.Background)
+	}
+	if merged.Body.Tasks[0].Description != "d (typo fixed)" {
+		t.Errorf("Description = %q, want imported edit", merged.Body.Tasks[0].Description)
+	}
+	if merged.Body.Tasks[1].Status != TaskStatusCompleted {
+		t.Errorf("Status = %q, want completed from checkbox", merged.Body.Tasks[1].Status)
+	}
+	if input.Body.Tasks[1].Status != TaskStatusNotStarted {
+		t.Error("input should not be modified")
+	}
+}
+
+func TestImportHumanEdits_Rejected(t *testing.T) {
+	original := managedBody(123)
+
+	conflicting := managedInput(123)
+	conflicting.Body.Tasks[0].Description = "d (changed by input)"
+
+	tests := []struct {
+		name   string
+		bodyMD string
+		input  *PostInput
+	}{
+		{"入力と同じ項目を別の内容に編集", strings.Replace(original, "\nd\n", "\nd (changed in esa)\n", 1), conflicting},
+		{"見出しの変更", strings.Replace(original, "### Task 1: Test task", "### Task 1: Renamed", 1), managedInput(123)},
+		{"取り込めないセクションの追加", original + "\n\n## メモ\n手書き", managedInput(123)},
+		{"依存関係グラフの編集", strings.Replace(original, "graph TD", "graph LR", 1), managedInput(123)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ImportHumanEdits(tt.input, tt.bodyMD)
+			if !errors.Is(err, ErrHumanEditsNotImportable) {
+				t.Errorf("expected ErrHumanEditsNotImportable, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPostFile_HumanEditModes(t *testing.T) {
+	edited := strings.Replace(managedBody(123), "- [ ] Task 2: Second task", "- [x] Task 2: Second task", 1)
+
+	tests := []struct {
+		mode        EditMode
+		wantErr     error
+		wantUpdated bool
+	}{
+		{EditModeAbort, ErrHumanEditsDetected, false},
+		{EditModeForce, nil, true},
+		{EditModeImport, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			jsonPath := writeUpdateJSON(t, "")
+			var capturedBodyMD string
+			client := &mockEsaClientForExecute{
+				getPostFunc: func(number int) (*esa.Post, error) {
+					return &esa.Post{Number: number, Category: "Claude Code/開発日誌/2026/01/28", BodyMD: edited}, nil
+				},
+				updatePostFunc: func(number int, input *esa.PostInput) (*esa.Post, error) {
+					capturedBodyMD = input.BodyMD
+					return &esa.Post{Number: number}, nil
+				},
+			}
+
+			// 入力JSONを最後にガードが書き込んだ内容と同じにする
+			if err := updateJSONAfterUpdate(jsonPath, 0, &managedInput(123).Body); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := PostFile(context.Background(), jsonPath, NewPolicy([]string{"Claude Code/開発日誌"}), client, PostOptions{EditMode: tt.mode})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				if capturedBodyMD != "" {
+					t.Error("UpdatePost should not be called")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PostFile() error = %v", err)
+			}
+			if !slices.Equal(result.EditedSections, []string{"## サマリー"}) {
+				t.Errorf("EditedSections = %v", result.EditedSections)
+			}
+
+			imported := strings.Contains(capturedBodyMD, "- [x] Task 2: Second task")
+			if imported != (tt.mode == EditModeImport) {
+				t.Errorf("checkbox imported = %v, want %v", imported, tt.mode == EditModeImport)
+			}
+
+			saved, err := ReadPostInputFromFile(jsonPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (saved.Body.Tasks[1].Status == TaskStatusCompleted) != (tt.mode == EditModeImport) {
+				t.Errorf("JSON file status = %v (mode %s)", saved.Body.Tasks[1].Status, tt.mode)
+			}
+			if tt.mode == EditModeForce && len(result.Warnings) == 0 {
+				t.Error("force should warn about overwritten edits")
+			}
+		})
+	}
+}
+
+// crlfAfterEmbeddedJSON は埋め込みJSONより後ろの改行をCRLFに変換する
+func crlfAfterEmbeddedJSON(bodyMD string) string {
+	idx := strings.Index(bodyMD, ClosingTag) + len(ClosingTag)
+	return bodyMD[:idx] + strings.ReplaceAll(bodyMD[idx:], "\n", "\r\n")
+}
@@ -15,9 +15,15 @@ import (
 //
 //	## サマリー
 //	...
+//
+// revision_number is not embedded: it would always be one revision behind the
+// post that contains it.
 func GenerateMarkdownWithJSON(input *PostInput) (string, error) {
+	embeddedInput := *input
+	embeddedInput.RevisionNumber = nil
+
 	// Marshal to compact JSON (no pretty print)
-	jsonBytes, err := json.Marshal(input)
+	jsonBytes, err := json.Marshal(&embeddedInput)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal JSON: %w", err) // fail closed
 	}
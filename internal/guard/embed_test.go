@@ -136,3 +136,29 @@ func TestGenerateMarkdownWithJSON_ExceedsMaxSize(t *testing.T) {
 		t.Errorf("Expected error message about embedded markdown size, got: %v", err)
 	}
 }
+
+func TestGenerateMarkdownWithJSON_OmitsRevisionNumber(t *testing.T) {
+	postNumber := 1
+	revision := 3
+	input := &PostInput{
+		PostNumber:     &postNumber,
+		RevisionNumber: &revision,
+		Name:           "Test",
+		Category:       "LLM/Tasks/2026/01/28",
+		Body: Body{
+			Background: "bg",
+			Tasks:      []Task{{ID: "task-1", Title: "Task 1: Test", Status: TaskStatusNotStarted, Summary: []string{"s"}, Description: "d"}},
+		},
+	}
+
+	result, err := GenerateMarkdownWithJSON(input)
+	if err != nil {
+		t.Fatalf("GenerateMarkdownWithJSON() error = %v", err)
+	}
+	if strings.Contains(result, "revision_number") {
+		t.Errorf("embedded JSON should not contain revision_number:\n%s", result)
+	}
+	if input.RevisionNumber == nil || *input.RevisionNumber != 3 {
+		t.Error("input should not be modified")
+	}
+}
@@ -10,6 +10,7 @@ const (
 	ErrCodeCategoryNotAllowed        ValidationErrorCode = "category_not_allowed"
 	ErrCodeCategoryChangeNotAllowed  ValidationErrorCode = "category_change_not_allowed"
 	ErrCodeCategoryInvalidDateSuffix ValidationErrorCode = "category_invalid_date_suffix"
+	ErrCodeReadNotAllowed            ValidationErrorCode = "read_not_allowed"
 
 	// Field errors
 	ErrCodeFieldEmpty         ValidationErrorCode = "field_empty"
@@ -33,6 +34,22 @@ const (
 	ErrCodeMissingRequired   ValidationErrorCode = "missing_required"
 	ErrCodeInvalidValue      ValidationErrorCode = "invalid_value"
 
+	// Patch errors
+	ErrCodePatchForbiddenField ValidationErrorCode = "patch_forbidden_field"
+
+	// Search errors
+	ErrCodeQueryNotAllowed ValidationErrorCode = "query_not_allowed"
+
+	// Concurrency errors
+	ErrCodeRevisionConflict        ValidationErrorCode = "revision_conflict"
+	ErrCodeHumanEditsDetected      ValidationErrorCode = "human_edits_detected"
+	ErrCodeHumanEditsNotImportable ValidationErrorCode = "human_edits_not_importable"
+
+	// Ownership errors
+	ErrCodePostNotManaged       ValidationErrorCode = "post_not_managed"
+	ErrCodeRepositoryTagMissing ValidationErrorCode = "repository_tag_missing"
+	ErrCodeTeamMismatch         ValidationErrorCode = "team_mismatch"
+
 	// File errors
 	ErrCodeFileSizeExceeded ValidationErrorCode = "file_size_exceeded"
 	ErrCodeNotRegularFile   ValidationErrorCode = "not_regular_file"
@@ -110,6 +127,7 @@ var (
 	ErrCategoryNotAllowed        = &ValidationError{code: ErrCodeCategoryNotAllowed, index: -1}
 	ErrCategoryChangeNotAllowed  = &ValidationError{code: ErrCodeCategoryChangeNotAllowed, index: -1}
 	ErrCategoryInvalidDateSuffix = &ValidationError{code: ErrCodeCategoryInvalidDateSuffix, index: -1}
+	ErrReadNotAllowed            = &ValidationError{code: ErrCodeReadNotAllowed, index: -1}
 
 	// Field errors
 	ErrFieldEmpty         = &ValidationError{code: ErrCodeFieldEmpty, index: -1}
@@ -133,6 +151,22 @@ var (
 	ErrMissingRequired   = &ValidationError{code: ErrCodeMissingRequired, index: -1}
 	ErrInvalidValue      = &ValidationError{code: ErrCodeInvalidValue, index: -1}
 
+	// Patch errors
+	ErrPatchForbiddenField = &ValidationError{code: ErrCodePatchForbiddenField, index: -1}
+
+	// Search errors
+	ErrQueryNotAllowed = &ValidationError{code: ErrCodeQueryNotAllowed, index: -1}
+
+	// Concurrency errors
+	ErrRevisionConflict        = &ValidationError{code: ErrCodeRevisionConflict, index: -1}
+	ErrHumanEditsDetected      = &ValidationError{code: ErrCodeHumanEditsDetected, index: -1}
+	ErrHumanEditsNotImportable = &ValidationError{code: ErrCodeHumanEditsNotImportable, index: -1}
+
+	// Ownership errors
+	ErrPostNotManaged       = &ValidationError{code: ErrCodePostNotManaged, index: -1}
+	ErrRepositoryTagMissing = &ValidationError{code: ErrCodeRepositoryTagMissing, index: -1}
+	ErrTeamMismatch         = &ValidationError{code: ErrCodeTeamMismatch, index: -1}
+
 	// File errors
 	ErrFileSizeExceeded = &ValidationError{code: ErrCodeFileSizeExceeded, index: -1}
 	ErrNotRegularFile   = &ValidationError{code: ErrCodeNotRegularFile, index: -1}
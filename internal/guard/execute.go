@@ -1,91 +1,288 @@
 package guard
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
 )
 
+// PostOptions は投稿時の挙動を切り替えるオプション
+type PostOptions struct {
+	// Adopt はガードが作成していない既存記事（埋め込みJSONがない記事）の上書きを許可する
+	Adopt bool
+	// EditMode はesa上で人が編集した内容が見つかった場合の扱い（空は abort）
+	EditMode EditMode
+	// Audit は書き込みを記録する監査ログ（nilなら記録しない）
+	Audit *AuditLog
+	// RepositoryTag はリポジトリにカテゴリを割り当てている場合のリポジトリのタグ（空なら割り当てなし）
+	// 作成する記事にはこのタグを付け、更新はこのタグが付いた記事に限る
+	RepositoryTag string
+	// Team は設定のesa.ioチーム名（空なら確認しない）
+	// 入力JSONのteamがこれと異なれば拒否し、埋め込みJSONとJSONファイルにはこのチームを記録する
+	Team string
+}
+
+// PostResult は記事の作成/更新結果
+type PostResult struct {
+	Post    *esa.Post // esa.io APIから返された記事
+	Created bool      // 新規作成の場合はtrue
+	Adopted bool      // ガード管理外の記事を引き継いだ場合はtrue
+	// AdoptDiff は引き継いだ記事の元の本文と新しい本文の差分（Adopted の場合のみ）
+	AdoptDiff string
+	// HumanEdits はesa上で見つかった人の編集（force または import で更新した場合のみ）
+	HumanEdits *HumanEdits
+	// Imported は人の編集を取り込んだ入力（import で更新した場合のみ）
+	Imported *PostInput
+	// Warnings は書き込み自体は成功したが確認が必要なこと（更新中に別の更新が入った場合など）
+	Warnings []string
+}
+
 // ExecutePost はesa.io記事の作成/更新を実行します
-func ExecutePost(jsonPath string, teamName string, allowedCategories []string, accessToken string) error {
-	client := esa.NewEsaClient(teamName, accessToken)
-	return executePostWithClient(jsonPath, allowedCategories, client)
+// clientのcontextがキャンセルされるとesa.io APIの呼び出しを中断する（JSONファイルの書き戻しは中断しない）
+func ExecutePost(ctx context.Context, jsonPath string, policy *Policy, client esa.EsaClientInterface, opts PostOptions) error {
+	result, err := PostFile(ctx, jsonPath, policy, client, opts)
+	if err != nil {
+		return err
+	}
+
+	PrintPostResult(result)
+	return nil
 }
 
-// executePostWithClient はesa.io記事の作成/更新を実行します（テスト可能なバージョン）
-func executePostWithClient(jsonPath string, allowedCategories []string, client esa.EsaClientInterface) error {
-	// 1. JSONファイルの読み込みとバリデーション
+// PrintPostResult はpostの実行結果をテキストで出力します（警告は標準エラー出力）
+func PrintPostResult(result *Result) {
+	if result.Adopted {
+		fmt.Printf("Adopted unmanaged post %d. The post has been overwritten; replaced body:\n", result.PostNumber)
+		if result.Diff != nil {
+			fmt.Print(*result.Diff)
+		}
+	}
+	if result.EditsImported {
+		fmt.Printf("Imported edits made in esa: %s\n", strings.Join(result.EditedSections, ", "))
+	}
+	if !result.Created {
+		fmt.Printf("Updated post: %s (Number: %d)\n", result.URL, result.PostNumber)
+		if result.JSONFileUpdated {
+			if result.EditsImported {
+				fmt.Println("JSON file updated: imported edits written back")
+			} else {
+				fmt.Printf("JSON file updated: revision_number set to %d\n", result.RevisionNumber)
+			}
+		}
+	} else {
+		fmt.Printf("Created post: %s (Number: %d)\n", result.URL, result.PostNumber)
+		if result.JSONFileUpdated {
+			fmt.Printf("JSON file updated: create_new removed, post_number set to %d\n", result.PostNumber)
+		}
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+}
+
+// PostFile はJSONファイルの内容でesa.io記事を作成/更新し、実行結果を返します
+// 新規作成に成功した場合はJSONファイルを更新します
+func PostFile(ctx context.Context, jsonPath string, policy *Policy, client esa.EsaClientInterface, opts PostOptions) (*Result, error) {
+	// 1. JSONファイルの読み込み
 	input, err := ReadPostInputFromFile(jsonPath)
 	if err != nil {
-		return fmt.Errorf("failed to read JSON file: %w", err)
+		return nil, fmt.Errorf("failed to read JSON file: %w", err)
+	}
+
+	// 2. バリデーションと投稿
+	postResult, err := Post(ctx, input, policy, client, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// フィールドのトリミング（スキーマバリデーション前）
-	TrimPostInput(input)
+	// 3. 実行結果の作成とJSONファイルへの書き戻し
+	return ApplyPostResult(jsonPath, input, postResult), nil
+}
 
-	// JSONスキーマバリデーション
-	if err := ValidatePostInputSchema(input); err != nil {
-		return fmt.Errorf("schema validation failed: %w", err)
+// ApplyPostResult は記事の作成/更新結果をjsonPathのJSONファイルに書き戻し、実行結果を返します
+// inputはjsonPathから読み込んで投稿した入力。書き戻しの失敗は投稿自体は成功しているので警告にする
+// ctxは受け取らない。投稿後にctxがキャンセルされても、作成された記事番号を失わないようローカルへの書き戻しは必ず行う
+func ApplyPostResult(jsonPath string, input *PostInput, postResult *PostResult) *Result {
+	result := newResult("post")
+	result.PostNumber = postResult.Post.Number
+	result.URL = postResult.Post.URL
+	result.Created = postResult.Created
+	result.RevisionNumber = postResult.Post.RevisionNumber
+	result.Warnings = append(result.Warnings, postResult.Warnings...)
+	if postResult.Adopted {
+		result.Adopted = true
+		result.Diff = &postResult.AdoptDiff
+	}
+	if postResult.HumanEdits != nil {
+		result.EditedSections = postResult.HumanEdits.Sections
+		if postResult.Imported != nil {
+			result.EditsImported = true
+		} else {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("overwrote edits made in esa: %s", strings.Join(postResult.HumanEdits.Sections, ", ")))
+		}
 	}
 
-	// 詳細なバリデーション実行
-	if err := ValidatePostInput(input); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+	// リビジョンを記録している場合は、続けて更新できるよう新しいリビジョンに進める
+	revision := 0
+	if input.RevisionNumber != nil {
+		revision = postResult.Post.RevisionNumber
 	}
 
-	// 2. カテゴリ権限チェック
-	allowed, err := IsAllowedCategory(input.Category, allowedCategories)
-	if err != nil {
-		return fmt.Errorf("category validation failed: %w", err)
+	// 記事の作成/更新に成功した後の書き戻しはclientのcontextに関係なく最後まで行う
+	// （中断すると作成済みの記事をcreate_newのまま再投稿してしまう）。書き込み自体も一時ファイルのリネームで原子的
+	var updateErr error
+	if postResult.Created {
+		// 新規作成成功時にJSONファイルを自動更新
+		updateErr = updateJSONAfterCreate(jsonPath, postResult.Post.Number, postResult.Post.RevisionNumber, input.Team)
+	} else if revision > 0 || postResult.Imported != nil {
+		// 取り込んだ人の編集もJSONファイルに反映する
+		var body *Body
+		if postResult.Imported != nil {
+			body = &postResult.Imported.Body
+		}
+		updateErr = updateJSONAfterUpdate(jsonPath, revision, body)
+	} else {
+		return result
 	}
-	if !allowed {
-		return fmt.Errorf("category %s is not allowed", input.Category)
+
+	if updateErr != nil {
+		// 警告を出すが、投稿自体は成功しているのでエラーにしない
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("failed to update JSON file: %v", updateErr),
+			"You may need to manually update the JSON file to use diff/update commands.")
+	} else {
+		result.JSONFileUpdated = true
 	}
+	return result
+}
 
-	// 3. リポジトリ名を取得
-	repoName, err := getRepositoryName()
-	if err != nil {
-		repoName = "" // gitリポジトリじゃない場合は空
+// Post はPostInputを検証し、esa.io記事の作成/更新を行います
+func Post(ctx context.Context, input *PostInput, policy *Policy, client esa.EsaClientInterface, opts PostOptions) (*PostResult, error) {
+	// 1. バリデーション
+	if err := ValidateInput(input); err != nil {
+		return nil, err
 	}
 
-	// 4. esa.io APIクライアントで投稿
-	var postNumber int
+	// 2. カテゴリ権限チェック（新規作成は作成、更新は更新の権限。更新は既存記事のカテゴリも updatePost で確認する）
+	// リポジトリに割り当てたカテゴリがない場合など、書き込めるカテゴリがなければ拒否する
+	// （WritableCategories は limits を考慮しないため、カテゴリごとの判定は続く policy.Check で行う）
+	if len(policy.WritableCategories()) == 0 {
+		return nil, NewValidationError(ErrCodeCategoryNotAllowed, "no categories are allowed for writing in this repository").
+			WithField("category")
+	}
+	op := OperationUpdate
 	if input.CreateNew {
-		postNumber, err = createPost(client, input, repoName)
+		op = OperationCreate
+	}
+	if err := policy.Check("category", input.Category, op); err != nil {
+		return nil, err
+	}
+
+	// 3. チームの確認（別のチーム向けのJSONで書き込まない）
+	if opts.Team != "" {
+		if err := ValidateTeam(input.Team, opts.Team); err != nil {
+			return nil, err
+		}
+		input.Team = opts.Team
+	}
+
+	// 4. リポジトリ名を取得（リポジトリにカテゴリを割り当てている場合はそのタグを使う）
+	repoName := opts.RepositoryTag
+	if repoName == "" {
+		var err error
+		repoName, err = getRepositoryName()
 		if err != nil {
-			return err
+			repoName = "" // gitリポジトリじゃない場合は空
 		}
+	}
 
-		// 新規作成成功時にJSONファイルを自動更新
-		if err := updateJSONAfterCreate(jsonPath, postNumber); err != nil {
-			// 警告を出すが、投稿自体は成功しているのでエラーにしない
-			fmt.Fprintf(os.Stderr, "Warning: failed to update JSON file: %v\n", err)
-			fmt.Fprintf(os.Stderr, "You may need to manually update the JSON file to use diff/update commands.\n")
-		} else {
-			fmt.Printf("JSON file updated: create_new removed, post_number set to %d\n", postNumber)
+	// 5. esa.io APIクライアントで投稿
+	if input.CreateNew {
+		post, err := createPost(ctx, client, input, repoName, opts.Audit)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		err = updatePost(client, input, allowedCategories, repoName)
+		return &PostResult{Post: post, Created: true}, nil
 	}
-	return err
+
+	return updatePost(ctx, client, input, policy, repoName, opts)
 }
 
 // updatePost は既存記事を更新します
-func updatePost(client esa.EsaClientInterface, input *PostInput, allowedCategories []string, repoName string) error {
+func updatePost(ctx context.Context, client esa.EsaClientInterface, input *PostInput, policy *Policy, repoName string, opts PostOptions) (*PostResult, error) {
 	// 既存記事のカテゴリを検証
-	existingPost, err := client.GetPost(*input.PostNumber)
+	existingPost, err := client.GetPost(ctx, *input.PostNumber)
 	if err != nil {
-		return fmt.Errorf("failed to get existing post: %w", err)
+		return nil, WithKind(ErrorKindAPI, fmt.Errorf("failed to get existing post: %w", err))
 	}
 
 	// 更新リクエストの妥当性を検証
-	if err := ValidateUpdateRequest(existingPost.Category, input.Category, allowedCategories); err != nil {
-		return err
+	if err := ValidateUpdateRequest(existingPost.Category, input.Category, policy); err != nil {
+		return nil, err
+	}
+
+	// リポジトリにカテゴリを割り当てている場合は、そのリポジトリのタグが付いた記事に限る
+	if opts.RepositoryTag != "" {
+		if err := ValidateRepositoryTag(existingPost.Tags, opts.RepositoryTag, *input.PostNumber); err != nil {
+			return nil, err
+		}
+	}
+
+	// ガードが作成した記事であることを検証（人が書いた記事を上書きしない）
+	adopted := false
+	if err := ValidateManagedPost(existingPost.BodyMD, *input.PostNumber); err != nil {
+		if !opts.Adopt {
+			return nil, WithKind(ErrorKindContent, err)
+		}
+		adopted = true
+	}
+
+	// 既存記事の埋め込みJSONが別のチームを記録していれば、番号の取り違えとみなして拒否する
+	if !adopted && opts.Team != "" {
+		if embedded, err := ExtractEmbeddedJSON(existingPost.BodyMD); err == nil {
+			if err := ValidateTeam(embedded.Team, opts.Team); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 最後に確認したリビジョンから変更されていないことを検証
+	if err := checkRevision(input, existingPost); err != nil {
+		return nil, err
+	}
+
+	// 最後のガード書き込み以降にesa上で人が編集していないか検証
+	var edits *HumanEdits
+	if !adopted {
+		edits, err = DetectHumanEdits(existingPost.BodyMD)
+		if err != nil {
+			return nil, WithKind(ErrorKindContent, fmt.Errorf("failed to check human edits: %w", err))
+		}
+	}
+	var imported *PostInput
+	if edits != nil {
+		switch opts.EditMode {
+		case EditModeForce:
+			// 人の編集を破棄して上書きする（結果に編集箇所を残す）
+		case EditModeImport:
+			imported, err = ImportHumanEdits(input, existingPost.BodyMD)
+			if err != nil {
+				return nil, err
+			}
+			if err := ValidateInput(imported); err != nil {
+				return nil, fmt.Errorf("imported edits are invalid: %w", err)
+			}
+			input = imported
+		default:
+			return nil, humanEditsError(*input.PostNumber, edits)
+		}
 	}
 
 	// 既存のタグを保持し、現在のリポジトリ名がなければ追加
@@ -94,7 +291,7 @@ func updatePost(client esa.EsaClientInterface, input *PostInput, allowedCategori
 	// BodyからマークダウンGenerate（JSON埋め込み）
 	bodyMD, err := GenerateMarkdownWithJSON(input)
 	if err != nil {
-		return fmt.Errorf("failed to generate markdown with JSON: %w", err)
+		return nil, fmt.Errorf("failed to generate markdown with JSON: %w", err)
 	}
 
 	esaInput := &esa.PostInput{
@@ -105,16 +302,58 @@ func updatePost(client esa.EsaClientInterface, input *PostInput, allowedCategori
 		WIP:      false, // 常にShip It!
 	}
 
-	post, err := client.UpdatePost(*input.PostNumber, esaInput)
+	// 監査ログの変更要約のため、最後にガードが書き込んだ内容と比較する
+	var previous *PostInput
+	if !adopted {
+		previous, _ = ExtractEmbeddedJSON(existingPost.BodyMD)
+	}
+	post, err := client.UpdatePost(ctx, *input.PostNumber, esaInput)
+	recordWrite(opts.Audit, AuditOperationUpdate, *input.PostNumber, input, repoName, bodyMD, previous, post, err)
 	if err != nil {
-		return fmt.Errorf("failed to update post: %w", err)
+		return nil, WithKind(ErrorKindAPI, fmt.Errorf("failed to update post: %w", err))
+	}
+
+	result := &PostResult{Post: post, HumanEdits: edits, Imported: imported}
+
+	// GetPostからUpdatePostまでの間に別の更新が入った場合、リビジョンが2つ以上進む
+	// 更新自体は反映されているため成功として扱い（新しいリビジョンも書き戻す）、上書きした可能性を警告で知らせる
+	if existingPost.RevisionNumber > 0 && post.RevisionNumber > existingPost.RevisionNumber+1 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"post %d was modified concurrently: revision advanced from %d to %d during update, so changes made in between may have been overwritten; fetch the post and review it",
+			*input.PostNumber, existingPost.RevisionNumber, post.RevisionNumber))
+	}
+	if adopted {
+		result.Adopted = true
+		result.AdoptDiff = generateUnifiedDiff(existingPost.BodyMD, bodyMD)
+	}
+	return result, nil
+}
+
+// checkRevision は入力JSONに記録されたリビジョンとesa.io上のリビジョンを比較します
+// リビジョンが記録されていない場合は何もしない
+func checkRevision(input *PostInput, existingPost *esa.Post) error {
+	if input.RevisionNumber == nil {
+		return nil
+	}
+	// リビジョン情報が取得できない場合は比較できないため拒否する（fail closed）
+	if existingPost.RevisionNumber <= 0 {
+		return revisionConflictError(fmt.Sprintf("cannot verify revision of post %d: revision_number is missing in the API response", existingPost.Number))
+	}
+	if *input.RevisionNumber != existingPost.RevisionNumber {
+		return revisionConflictError(fmt.Sprintf(
+			"post %d has been modified since revision %d (current revision: %d, updated at %s); fetch the post and merge your changes",
+			existingPost.Number, *input.RevisionNumber, existingPost.RevisionNumber, existingPost.UpdatedAt.Format(time.RFC3339)))
 	}
-	fmt.Printf("Updated post: %s (Number: %d)\n", post.URL, post.Number)
 	return nil
 }
 
+// revisionConflictError はリビジョン競合エラーを作成します
+func revisionConflictError(message string) error {
+	return WithKind(ErrorKindConflict, NewValidationError(ErrCodeRevisionConflict, message).WithField("revision_number"))
+}
+
 // createPost は新規記事を作成します
-func createPost(client esa.EsaClientInterface, input *PostInput, repoName string) (int, error) {
+func createPost(ctx context.Context, client esa.EsaClientInterface, input *PostInput, repoName string, audit *AuditLog) (*esa.Post, error) {
 	// 現在のリポジトリ名のみをタグに設定
 	var tags []string
 	if repoName != "" {
@@ -124,7 +363,7 @@ func createPost(client esa.EsaClientInterface, input *PostInput, repoName string
 	// BodyからマークダウンGenerate（JSON埋め込み）
 	bodyMD, err := GenerateMarkdownWithJSON(input)
 	if err != nil {
-		return 0, fmt.Errorf("failed to generate markdown with JSON: %w", err)
+		return nil, fmt.Errorf("failed to generate markdown with JSON: %w", err)
 	}
 
 	esaInput := &esa.PostInput{
@@ -135,16 +374,45 @@ func createPost(client esa.EsaClientInterface, input *PostInput, repoName string
 		WIP:      false, // 常にShip It!
 	}
 
-	post, err := client.CreatePost(esaInput)
+	post, err := client.CreatePost(ctx, esaInput)
+	recordWrite(audit, AuditOperationCreate, 0, input, repoName, bodyMD, nil, post, err)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create post: %w", err)
+		return nil, WithKind(ErrorKindAPI, fmt.Errorf("failed to create post: %w", err))
 	}
-	fmt.Printf("Created post: %s (Number: %d)\n", post.URL, post.Number)
-	return post.Number, nil
+	return post, nil
 }
 
 // updateJSONAfterCreate は新規作成成功後にJSONファイルを更新します
-func updateJSONAfterCreate(jsonPath string, postNumber int) error {
+// teamが空でなければ、記事番号がどのチームのものかをteamに記録する
+func updateJSONAfterCreate(jsonPath string, postNumber int, revisionNumber int, team string) error {
+	return rewriteJSONFile(jsonPath, func(input *PostInput) {
+		// create_newをfalseに、post_numberを設定
+		input.CreateNew = false
+		input.PostNumber = &postNumber
+		if revisionNumber > 0 {
+			input.RevisionNumber = &revisionNumber
+		}
+		if team != "" {
+			input.Team = team
+		}
+	})
+}
+
+// updateJSONAfterUpdate は更新成功後にJSONファイルを更新します
+// revisionNumberが正ならrevision_numberを進め、bodyが指定されていれば本文を置き換えます
+func updateJSONAfterUpdate(jsonPath string, revisionNumber int, body *Body) error {
+	return rewriteJSONFile(jsonPath, func(input *PostInput) {
+		if revisionNumber > 0 {
+			input.RevisionNumber = &revisionNumber
+		}
+		if body != nil {
+			input.Body = *body
+		}
+	})
+}
+
+// rewriteJSONFile はJSONファイルを読み込み、modifyで変更した内容で原子的に書き換えます
+func rewriteJSONFile(jsonPath string, modify func(*PostInput)) error {
 	// 元のファイルのパーミッションを取得
 	fileInfo, err := os.Stat(jsonPath)
 	if err != nil {
@@ -157,10 +425,13 @@ func updateJSONAfterCreate(jsonPath string, postNumber int) error {
 		return err
 	}
 
-	// create_newをfalseに、post_numberを設定
-	input.CreateNew = false
-	input.PostNumber = &postNumber
+	modify(input)
 
+	return writeJSONFile(jsonPath, input, fileInfo.Mode().Perm())
+}
+
+// writeJSONFile はPostInputをJSONファイルに原子的に書き込みます
+func writeJSONFile(jsonPath string, input *PostInput, perm os.FileMode) error {
 	// JSONに変換
 	data, err := json.MarshalIndent(input, "", "  ")
 	if err != nil {
@@ -178,7 +449,7 @@ func updateJSONAfterCreate(jsonPath string, postNumber int) error {
 	defer os.Remove(tmpPath) // 失敗時のクリーンアップ
 
 	// パーミッションを設定して書き込み
-	if err := tmpFile.Chmod(fileInfo.Mode().Perm()); err != nil {
+	if err := tmpFile.Chmod(perm); err != nil {
 		tmpFile.Close()
 		return fmt.Errorf("failed to set temp file permissions: %w", err)
 	}
@@ -207,17 +478,11 @@ func updateJSONAfterCreate(jsonPath string, postNumber int) error {
 
 // getRepositoryName はGitリポジトリ名を取得します
 func getRepositoryName() (string, error) {
-	cmd := exec.Command("git", "config", "--get", "remote.origin.url")
-	output, err := cmd.Output()
+	url, err := getRemoteURL("")
 	if err != nil {
 		return "", fmt.Errorf("failed to get repository name: %w", err)
 	}
 
-	url := strings.TrimSpace(string(output))
-	if url == "" {
-		return "", fmt.Errorf("repository URL is empty")
-	}
-
 	// URLからリポジトリ名を抽出
 	// 例: https://github.com/user/repo.git → repo
 	// 例: git@github.com:user/repo.git → repo
@@ -235,3 +500,43 @@ func getRepositoryName() (string, error) {
 
 	return repoName, nil
 }
+
+// GetRepository はdirのGitリポジトリの origin から owner/repo を取得します（dirが空ならカレントディレクトリ）
+func GetRepository(dir string) (string, error) {
+	url, err := getRemoteURL(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get repository: %w", err)
+	}
+	return ParseRepository(url)
+}
+
+// ParseRepository はリモートのURLから owner/repo を取り出します
+// 例: https://github.com/owner/repo.git, git@github.com:owner/repo.git, ssh://git@github.com/owner/repo
+func ParseRepository(url string) (string, error) {
+	path := strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	// scp形式（git@host:owner/repo）のホスト部分を区切りとして扱う
+	if !strings.Contains(path, "://") {
+		path = strings.ReplaceAll(path, ":", "/")
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 || parts[len(parts)-2] == "" || parts[len(parts)-1] == "" {
+		return "", fmt.Errorf("repository URL has no owner/repo: %s", url)
+	}
+	return parts[len(parts)-2] + "/" + parts[len(parts)-1], nil
+}
+
+// getRemoteURL はdirのGitリポジトリの origin のURLを取得します（dirが空ならカレントディレクトリ）
+func getRemoteURL(dir string) (string, error) {
+	cmd := exec.Command("git", "config", "--get", "remote.origin.url")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	url := strings.TrimSpace(string(output))
+	if url == "" {
+		return "", fmt.Errorf("repository URL is empty")
+	}
+	return url, nil
+}
@@ -1,9 +1,12 @@
 package guard
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
@@ -14,23 +17,31 @@ type mockEsaClientForExecute struct {
 	createPostFunc func(*esa.PostInput) (*esa.Post, error)
 	updatePostFunc func(int, *esa.PostInput) (*esa.Post, error)
 	getPostFunc    func(int) (*esa.Post, error)
+	listPostsFunc  func(string, int, int) (*esa.PostList, error)
 }
 
-func (m *mockEsaClientForExecute) CreatePost(input *esa.PostInput) (*esa.Post, error) {
+func (m *mockEsaClientForExecute) ListPosts(ctx context.Context, q string, page, perPage int) (*esa.PostList, error) {
+	if m.listPostsFunc != nil {
+		return m.listPostsFunc(q, page, perPage)
+	}
+	return &esa.PostList{}, nil
+}
+
+func (m *mockEsaClientForExecute) CreatePost(ctx context.Context, input *esa.PostInput) (*esa.Post, error) {
 	if m.createPostFunc != nil {
 		return m.createPostFunc(input)
 	}
 	return &esa.Post{Number: 123}, nil
 }
 
-func (m *mockEsaClientForExecute) UpdatePost(number int, input *esa.PostInput) (*esa.Post, error) {
+func (m *mockEsaClientForExecute) UpdatePost(ctx context.Context, number int, input *esa.PostInput) (*esa.Post, error) {
 	if m.updatePostFunc != nil {
 		return m.updatePostFunc(number, input)
 	}
 	return &esa.Post{Number: number}, nil
 }
 
-func (m *mockEsaClientForExecute) GetPost(number int) (*esa.Post, error) {
+func (m *mockEsaClientForExecute) GetPost(ctx context.Context, number int) (*esa.Post, error) {
 	if m.getPostFunc != nil {
 		return m.getPostFunc(number)
 	}
@@ -38,9 +49,35 @@ func (m *mockEsaClientForExecute) GetPost(number int) (*esa.Post, error) {
 		Number:   number,
 		Category: "Claude Code/開発日誌/2026/01/28",
 		Tags:     []string{},
+		BodyMD:   managedBody(number),
 	}, nil
 }
 
+// managedBody はガードが書き込んだ記事本文（埋め込みJSON付き）を返す
+func managedBody(postNumber int) string {
+	bodyMD, err := GenerateMarkdownWithJSON(managedInput(postNumber))
+	if err != nil {
+		panic(err)
+	}
+	return bodyMD
+}
+
+// managedInput はガードが最後に書き込んだ内容を表す入力を返す
+func managedInput(postNumber int) *PostInput {
+	return &PostInput{
+		PostNumber: &postNumber,
+		Name:       "Test Post",
+		Category:   "Claude Code/開発日誌/2026/01/28",
+		Body: Body{
+			Background: "Test background",
+			Tasks: []Task{
+				{ID: "task-1", Title: "Task 1: Test task", Status: TaskStatusNotStarted, Summary: []string{"s"}, Description: "d"},
+				{ID: "task-2", Title: "Task 2: Second task", Status: TaskStatusNotStarted, Summary: []string{"s2"}, Description: "d2"},
+			},
+		},
+	}
+}
+
 // TestExecutePost_CreateNewUpdatesJSON tests that JSON file is automatically updated after successful post with create_new
 func TestExecutePost_CreateNewUpdatesJSON(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -76,10 +113,10 @@ func TestExecutePost_CreateNewUpdatesJSON(t *testing.T) {
 		},
 	}
 
-	allowedCategories := []string{"Claude Code/開発日誌"}
+	policy := NewPolicy([]string{"Claude Code/開発日誌"})
 
 	// ExecutePost実行（内部でJSON更新が行われるはず）
-	err := executePostWithClient(tmpFile, allowedCategories, mockClient)
+	err := ExecutePost(context.Background(), tmpFile, policy, mockClient, PostOptions{})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -138,6 +175,7 @@ func TestExecutePost_UpdateDoesNotChangeJSON(t *testing.T) {
 				Number:   123,
 				Category: "Claude Code/開発日誌/2026/01/28",
 				Tags:     []string{},
+				BodyMD:   managedBody(123),
 			}, nil
 		},
 		updatePostFunc: func(number int, input *esa.PostInput) (*esa.Post, error) {
@@ -145,10 +183,10 @@ func TestExecutePost_UpdateDoesNotChangeJSON(t *testing.T) {
 		},
 	}
 
-	allowedCategories := []string{"Claude Code/開発日誌"}
+	policy := NewPolicy([]string{"Claude Code/開発日誌"})
 
 	// ExecutePost実行（更新なのでJSONは変更されないはず）
-	err := executePostWithClient(tmpFile, allowedCategories, mockClient)
+	err := ExecutePost(context.Background(), tmpFile, policy, mockClient, PostOptions{})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -207,10 +245,10 @@ func TestExecutePost_CreateFailureDoesNotChangeJSON(t *testing.T) {
 		},
 	}
 
-	allowedCategories := []string{"Claude Code/開発日誌"}
+	policy := NewPolicy([]string{"Claude Code/開発日誌"})
 
 	// ExecutePost実行（失敗するのでJSONは変更されないはず）
-	err := executePostWithClient(tmpFile, allowedCategories, mockClient)
+	err := ExecutePost(context.Background(), tmpFile, policy, mockClient, PostOptions{})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -272,6 +310,7 @@ func TestExecutePost_EmbeddsJSONInMarkdown(t *testing.T) {
 				Number:   123,
 				Category: "Claude Code/開発日誌/2026/01/28",
 				Tags:     []string{},
+				BodyMD:   managedBody(123),
 			}, nil
 		},
 		updatePostFunc: func(number int, input *esa.PostInput) (*esa.Post, error) {
@@ -280,10 +319,10 @@ func TestExecutePost_EmbeddsJSONInMarkdown(t *testing.T) {
 		},
 	}
 
-	allowedCategories := []string{"Claude Code/開発日誌"}
+	policy := NewPolicy([]string{"Claude Code/開発日誌"})
 
 	// ExecutePost実行
-	err := executePostWithClient(tmpFile, allowedCategories, mockClient)
+	err := ExecutePost(context.Background(), tmpFile, policy, mockClient, PostOptions{})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -334,3 +373,205 @@ func TestExecutePost_EmbeddsJSONInMarkdown(t *testing.T) {
 		t.Errorf("body_md seems too short, expected markdown sections")
 	}
 }
+
+// writeUpdateJSON は revision_number 付きの更新用JSONファイルを作成する
+func writeUpdateJSON(t *testing.T, revision string) string {
+	t.Helper()
+	tmpFile := filepath.Join(t.TempDir(), "test.json")
+	revisionField := ""
+	if revision != "" {
+		revisionField = `"revision_number": ` + revision + `,`
+	}
+	inputJSON := `{
+		"post_number": 123,
+		` + revisionField + `
+		"name": "Test Post",
+		"category": "Claude Code/開発日誌/2026/01/28",
+		"body": {
+			"background": "Test background",
+			"tasks": [{"id": "task-1", "title": "Task 1: Test task", "status": "not_started", "summary": ["s"], "description": "d"}]
+		}
+	}`
+	if err := os.WriteFile(tmpFile, []byte(inputJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return tmpFile
+}
+
+func TestPostFile_RevisionConflict(t *testing.T) {
+	tests := []struct {
+		name           string
+		inputRevision  string
+		remoteRevision int
+		updatedTo      int
+		wantConflict   bool
+		wantUpdated    bool
+		wantWarning    bool
+	}{
+		{"記録したリビジョンと一致", "4", 4, 5, false, true, false},
+		{"記録したリビジョンより進んでいる", "4", 6, 7, true, false, false},
+		{"リビジョン情報が取得できない", "4", 0, 0, true, false, false},
+		{"リビジョン未記録なら比較しない", "", 6, 7, false, true, false},
+		{"更新中に別の更新が入った", "", 6, 8, false, true, true},
+		{"記録したリビジョンで更新中に別の更新が入った", "6", 6, 8, false, true, true},
+		{"変更がなくリビジョンが進まない", "", 6, 6, false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonPath := writeUpdateJSON(t, tt.inputRevision)
+			updated := false
+			client := &mockEsaClientForExecute{
+				getPostFunc: func(number int) (*esa.Post, error) {
+					return &esa.Post{Number: number, Category: "Claude Code/開発日誌/2026/01/28", BodyMD: managedBody(number), RevisionNumber: tt.remoteRevision}, nil
+				},
+				updatePostFunc: func(number int, input *esa.PostInput) (*esa.Post, error) {
+					updated = true
+					return &esa.Post{Number: number, RevisionNumber: tt.updatedTo}, nil
+				},
+			}
+
+			result, err := PostFile(context.Background(), jsonPath, NewPolicy([]string{"Claude Code/開発日誌"}), client, PostOptions{})
+			if tt.wantConflict {
+				if !errors.Is(err, ErrRevisionConflict) {
+					t.Fatalf("expected revision conflict, got %v", err)
+				}
+				if ErrorKindOf(err) != ErrorKindConflict {
+					t.Errorf("ErrorKindOf() = %v, want conflict", ErrorKindOf(err))
+				}
+			} else if err != nil {
+				t.Fatalf("PostFile() error = %v", err)
+			}
+			if updated != tt.wantUpdated {
+				t.Errorf("UpdatePost called = %v, want %v", updated, tt.wantUpdated)
+			}
+			if tt.wantConflict {
+				return
+			}
+
+			// 更新中に別の更新が入っても書き込みは反映されているので成功とし、警告で知らせる
+			if got := len(result.Warnings) > 0; got != tt.wantWarning {
+				t.Errorf("warnings = %v, want warning %v", result.Warnings, tt.wantWarning)
+			}
+			// 記録したリビジョンは更新後のリビジョンに進める（次の更新が古いリビジョンで拒否されないように）
+			if tt.inputRevision != "" {
+				input, err := ReadPostInputFromFile(jsonPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if input.RevisionNumber == nil || *input.RevisionNumber != tt.updatedTo {
+					t.Errorf("revision_number = %v, want %d", input.RevisionNumber, tt.updatedTo)
+				}
+			}
+		})
+	}
+}
+
+func TestPostFile_AdvancesRecordedRevision(t *testing.T) {
+	jsonPath := writeUpdateJSON(t, "4")
+	client := &mockEsaClientForExecute{
+		getPostFunc: func(number int) (*esa.Post, error) {
+			return &esa.Post{Number: number, Category: "Claude Code/開発日誌/2026/01/28", BodyMD: managedBody(number), RevisionNumber: 4}, nil
+		},
+		updatePostFunc: func(number int, input *esa.PostInput) (*esa.Post, error) {
+			return &esa.Post{Number: number, RevisionNumber: 5}, nil
+		},
+	}
+
+	result, err := PostFile(context.Background(), jsonPath, NewPolicy([]string{"Claude Code/開発日誌"}), client, PostOptions{})
+	if err != nil {
+		t.Fatalf("PostFile() error = %v", err)
+	}
+	if !result.JSONFileUpdated || result.RevisionNumber != 5 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	input, err := ReadPostInputFromFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input.RevisionNumber == nil || *input.RevisionNumber != 5 {
+		t.Errorf("RevisionNumber = %v, want 5", input.RevisionNumber)
+	}
+}
+
+func TestPostFile_UnmanagedPost(t *testing.T) {
+	tests := []struct {
+		name   string
+		bodyMD string
+	}{
+		{"埋め込みJSONなし", "## 手書きのメモ\n\n大事な内容\n"},
+		{"別の記事の埋め込みJSON", managedBody(456)},
+		{"post_numberもcreate_newもない", fmt.Sprintf("%s{}%s\n\n", Sentinel, ClosingTag)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonPath := writeUpdateJSON(t, "")
+			var capturedBodyMD string
+			client := &mockEsaClientForExecute{
+				getPostFunc: func(number int) (*esa.Post, error) {
+					return &esa.Post{Number: number, Category: "Claude Code/開発日誌/2026/01/28", BodyMD: tt.bodyMD}, nil
+				},
+				updatePostFunc: func(number int, input *esa.PostInput) (*esa.Post, error) {
+					capturedBodyMD = input.BodyMD
+					return &esa.Post{Number: number}, nil
+				},
+			}
+
+			// -adoptなしでは更新を拒否する
+			_, err := PostFile(context.Background(), jsonPath, NewPolicy([]string{"Claude Code/開発日誌"}), client, PostOptions{})
+			if !errors.Is(err, ErrPostNotManaged) {
+				t.Fatalf("expected ErrPostNotManaged, got %v", err)
+			}
+			if capturedBodyMD != "" {
+				t.Fatal("UpdatePost should not be called without -adopt")
+			}
+
+			// -adoptありでは差分付きで引き継ぐ
+			result, err := PostFile(context.Background(), jsonPath, NewPolicy([]string{"Claude Code/開発日誌"}), client, PostOptions{Adopt: true})
+			if err != nil {
+				t.Fatalf("PostFile() with Adopt error = %v", err)
+			}
+			if !result.Adopted || result.Diff == nil {
+				t.Fatalf("expected adopted result with diff, got %+v", result)
+			}
+			if !strings.HasPrefix(capturedBodyMD, Sentinel) {
+				t.Error("adopted post should be rewritten with embedded JSON")
+			}
+			if !strings.Contains(*result.Diff, "\n+{\"post_number\":123") {
+				t.Errorf("diff should show the replaced body, got:\n%s", *result.Diff)
+			}
+		})
+	}
+}
+
+func TestValidateManagedPost_CreatedByGuard(t *testing.T) {
+	// 新規作成時の埋め込みJSONはpost_numberを持たない
+	bodyMD := fmt.Sprintf("%s{\"create_new\":true}%s\n\n## サマリー\n", Sentinel, ClosingTag)
+	if err := ValidateManagedPost(bodyMD, 123); err != nil {
+		t.Errorf("ValidateManagedPost() error = %v", err)
+	}
+}
+
+func TestParseRepository(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{"https://github.com/owner/repo.git", "owner/repo", false},
+		{"https://github.com/owner/repo", "owner/repo", false},
+		{"git@github.com:owner/repo.git", "owner/repo", false},
+		{"ssh://git@github.com/owner/repo.git", "owner/repo", false},
+		{"ssh://git@github.com:22/owner/repo", "owner/repo", false},
+		{"repo", "", true},
+		{"https://github.com/", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRepository(tt.url)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRepository(%q) = %q, %v; want %q", tt.url, got, err, tt.want)
+		}
+	}
+}
@@ -3,9 +3,13 @@ package guard
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
+// ErrSentinelNotFound is returned when the document does not start with the embedded JSON sentinel
+var ErrSentinelNotFound = errors.New("sentinel not found at start of document")
+
 // ExtractEmbeddedJSON extracts JSON from Markdown (parse only, no schema validation)
 func ExtractEmbeddedJSON(markdown string) (*PostInput, error) {
 	data := []byte(markdown)
@@ -17,7 +21,7 @@ func ExtractEmbeddedJSON(markdown string) (*PostInput, error) {
 
 	// 2. Check if document starts with sentinel (exact match, no BOM/whitespace allowed)
 	if !bytes.HasPrefix(data, []byte(Sentinel)) {
-		return nil, fmt.Errorf("sentinel not found at start of document")
+		return nil, ErrSentinelNotFound
 	}
 
 	// 3. Find first closing tag "\n-->"
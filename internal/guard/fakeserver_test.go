@@ -0,0 +1,374 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa/esatest"
+)
+
+// TestFakeServer_PostDiffFetchFlow はインメモリのesa.ioサーバーに対して post → diff → fetch → 更新 の流れを確認する
+func TestFakeServer_PostDiffFetchFlow(t *testing.T) {
+	fake := esatest.NewServer("test-team", "test-token")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := esa.NewEsaClient("test-team", "test-token")
+	if err := client.SetBaseURL(server.URL); err != nil {
+		t.Fatalf("SetBaseURL() error = %v", err)
+	}
+	policy := NewPolicy([]string{"Claude Code/開発日誌"})
+
+	jsonPath := filepath.Join(t.TempDir(), "post.json")
+	inputJSON := `{
+		"create_new": true,
+		"name": "Test Post",
+		"category": "Claude Code/開発日誌/2026/01/28",
+		"body": {
+			"background": "Test background",
+			"tasks": [
+				{"id": "task-1", "title": "Task 1: Test task", "status": "not_started", "summary": ["Task summary"], "description": "Task description"}
+			]
+		}
+	}`
+	if err := os.WriteFile(jsonPath, []byte(inputJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. 新規作成するとJSONファイルに記事番号とリビジョンが書き戻される
+	result, err := PostFile(context.Background(), jsonPath, policy, client, PostOptions{})
+	if err != nil {
+		t.Fatalf("PostFile() error = %v", err)
+	}
+	if !result.Created || result.PostNumber != 1 || !result.JSONFileUpdated {
+		t.Fatalf("result = %+v", result)
+	}
+	input, err := ReadPostInputFromFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input.CreateNew || input.PostNumber == nil || *input.PostNumber != 1 || input.RevisionNumber == nil || *input.RevisionNumber != 1 {
+		t.Fatalf("JSON after create = %+v", input)
+	}
+
+	// 2. JSONファイルのままなら差分は埋め込みJSONの create_new → post_number の書き換えだけ
+	diff, err := DiffFile(context.Background(), jsonPath, policy, client)
+	if err != nil {
+		t.Fatalf("DiffFile() error = %v", err)
+	}
+	if !strings.Contains(*diff.Diff, `+{"post_number":1,`) || strings.Contains(*diff.Diff, "## タスク") {
+		t.Errorf("diff after create = %q", *diff.Diff)
+	}
+
+	// 3. ステータスを変えて更新するとリビジョンが進み、JSONファイルにも書き戻される
+	input.Body.Tasks[0].Status = TaskStatusInProgress
+	if err := writeJSONFile(jsonPath, input, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, err = PostFile(context.Background(), jsonPath, policy, client, PostOptions{})
+	if err != nil {
+		t.Fatalf("PostFile() update error = %v", err)
+	}
+	if result.Created || result.RevisionNumber != 2 || !result.JSONFileUpdated {
+		t.Errorf("update result = %+v", result)
+	}
+	if revisions := fake.Revisions(1); len(revisions) != 2 {
+		t.Errorf("revisions = %d, want 2", len(revisions))
+	}
+
+	// 4. fetchで更新後の埋め込みJSONと最新のリビジョンを取り出せる
+	fetched, err := FetchPost(context.Background(), 1, policy, client)
+	if err != nil {
+		t.Fatalf("FetchPost() error = %v", err)
+	}
+	if fetched.JSON.Body.Tasks[0].Status != TaskStatusInProgress || *fetched.JSON.RevisionNumber != 2 {
+		t.Errorf("fetched = %+v", fetched.JSON)
+	}
+
+	// 5. 別の更新が入った後は競合で止まる
+	post, _ := fake.Post(1)
+	if _, err := client.UpdatePost(context.Background(), 1, &esa.PostInput{Name: post.Name, Category: post.Category, BodyMD: post.BodyMD}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = PostFile(context.Background(), jsonPath, policy, client, PostOptions{})
+	if ErrorKindOf(err) != ErrorKindConflict {
+		t.Errorf("PostFile() after concurrent update error = %v, want conflict", err)
+	}
+
+	// 6. 存在しない記事の取得はnot_found
+	_, err = FetchPost(context.Background(), 99, policy, client)
+	if !errors.Is(err, esa.ErrNotFound) || ErrorKindOf(err) != ErrorKindNotFound {
+		t.Errorf("FetchPost(99) error = %v, want not_found", err)
+	}
+}
+
+// TestFakeServer_RepositoryTag はリポジトリにカテゴリを割り当てた場合、別のリポジトリのタグの記事を更新できないことを確認する
+func TestFakeServer_RepositoryTag(t *testing.T) {
+	fake := esatest.NewServer("test-team", "test-token")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := esa.NewEsaClient("test-team", "test-token")
+	if err := client.SetBaseURL(server.URL); err != nil {
+		t.Fatalf("SetBaseURL() error = %v", err)
+	}
+	policy := NewPolicy([]string{"LLM/Tasks"})
+
+	input, err := DecodePostInput([]byte(`{
+		"create_new": true,
+		"name": "Test Post",
+		"category": "LLM/Tasks/2026/01/28",
+		"body": {
+			"background": "Test background",
+			"tasks": [
+				{"id": "task-1", "title": "Task 1: Test task", "status": "not_started", "summary": ["Task summary"], "description": "Task description"}
+			]
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 作成した記事にはリポジトリのタグが付く
+	result, err := Post(context.Background(), input, policy, client, PostOptions{RepositoryTag: "repo-a"})
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if !slices.Contains(result.Post.Tags, "repo-a") {
+		t.Errorf("tags = %v, want repo-a", result.Post.Tags)
+	}
+
+	input.CreateNew = false
+	input.PostNumber = &result.Post.Number
+
+	// 別のリポジトリからは更新できない
+	_, err = Post(context.Background(), input, policy, client, PostOptions{RepositoryTag: "repo-b"})
+	if !errors.Is(err, ErrRepositoryTagMissing) {
+		t.Errorf("Post() from another repository error = %v, want repository_tag_missing", err)
+	}
+
+	// 同じリポジトリからは更新できる
+	if _, err := Post(context.Background(), input, policy, client, PostOptions{RepositoryTag: "repo-a"}); err != nil {
+		t.Errorf("Post() from the same repository error = %v", err)
+	}
+
+	// 書き込めるカテゴリがなければ作成も拒否する
+	input.CreateNew = true
+	input.PostNumber = nil
+	if _, err := Post(context.Background(), input, nil, client, PostOptions{}); !errors.Is(err, ErrCategoryNotAllowed) {
+		t.Errorf("Post() without allowed categories error = %v, want category_not_allowed", err)
+	}
+}
+
+func TestFakeServer_Team(t *testing.T) {
+	fake := esatest.NewServer("test-team", "test-token")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := esa.NewEsaClient("test-team", "test-token")
+	if err := client.SetBaseURL(server.URL); err != nil {
+		t.Fatalf("SetBaseURL() error = %v", err)
+	}
+	policy := NewPolicy([]string{"LLM/Tasks"})
+
+	jsonPath := filepath.Join(t.TempDir(), "post.json")
+	inputJSON := `{
+		"create_new": true,
+		"name": "Test Post",
+		"category": "LLM/Tasks/2026/01/28",
+		"body": {
+			"background": "Test background",
+			"tasks": [
+				{"id": "task-1", "title": "Task 1: Test task", "status": "not_started", "summary": ["Task summary"], "description": "Task description"}
+			]
+		}
+	}`
+	if err := os.WriteFile(jsonPath, []byte(inputJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 作成するとチームが埋め込みJSONとJSONファイルに記録される
+	result, err := PostFile(context.Background(), jsonPath, policy, client, PostOptions{Team: "test-team"})
+	if err != nil {
+		t.Fatalf("PostFile() error = %v", err)
+	}
+	input, err := ReadPostInputFromFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input.Team != "test-team" {
+		t.Errorf("team in JSON file = %q, want test-team", input.Team)
+	}
+	post, _ := fake.Post(result.PostNumber)
+	if embedded, err := ExtractEmbeddedJSON(post.BodyMD); err != nil || embedded.Team != "test-team" {
+		t.Errorf("embedded team = %+v, %v, want test-team", embedded, err)
+	}
+
+	// 別のチームのプロファイルでは、同じ記事番号でも更新しない
+	if _, err := PostFile(context.Background(), jsonPath, policy, client, PostOptions{Team: "other-team"}); !errors.Is(err, ErrTeamMismatch) {
+		t.Errorf("PostFile() with another team error = %v, want team_mismatch", err)
+	}
+	if _, err := PostFile(context.Background(), jsonPath, policy, client, PostOptions{Team: "test-team"}); err != nil {
+		t.Errorf("PostFile() with the same team error = %v", err)
+	}
+
+	// JSONファイルにチームがなくても、埋め込みJSONが別のチームを記録していれば更新しない
+	input.Team = ""
+	input.RevisionNumber = nil
+	if _, err := Post(context.Background(), input, policy, client, PostOptions{Team: "other-team"}); !errors.Is(err, ErrTeamMismatch) {
+		t.Errorf("Post() over another team's post error = %v, want team_mismatch", err)
+	}
+}
+
+// TestFakeServer_PatchAfterCreate は作成直後（埋め込みJSONが create_new のまま）の記事にパッチを当てられることを確認する
+func TestFakeServer_PatchAfterCreate(t *testing.T) {
+	fake := esatest.NewServer("test-team", "test-token")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := esa.NewEsaClient("test-team", "test-token")
+	if err := client.SetBaseURL(server.URL); err != nil {
+		t.Fatalf("SetBaseURL() error = %v", err)
+	}
+	policy := NewPolicy([]string{"LLM/Tasks"})
+
+	input, err := DecodePostInput([]byte(`{
+		"create_new": true,
+		"name": "Test Post",
+		"category": "LLM/Tasks/2026/01/28",
+		"body": {
+			"background": "Test background",
+			"tasks": [
+				{"id": "task-1", "title": "Task 1: Test task", "status": "not_started", "summary": ["Task summary"], "description": "Task description"}
+			]
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := Post(context.Background(), input, policy, client, PostOptions{})
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+
+	patch := `[{"op":"replace","path":"/body/tasks/0/status","value":"in_progress"}]`
+	result, patched, err := Patch(context.Background(), created.Post.Number, []byte(patch), policy, client, PostOptions{})
+	if err != nil {
+		t.Fatalf("Patch() after create error = %v", err)
+	}
+	if result.Post.RevisionNumber != 2 || patched.Body.Tasks[0].Status != TaskStatusInProgress {
+		t.Errorf("result = %+v, patched = %+v", result.Post, patched.Body.Tasks[0])
+	}
+
+	// 更新後の埋め込みJSONは記事番号を持つ
+	post, _ := fake.Post(created.Post.Number)
+	embedded, err := ExtractEmbeddedJSON(post.BodyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embedded.CreateNew || embedded.PostNumber == nil || *embedded.PostNumber != created.Post.Number {
+		t.Errorf("embedded JSON after patch = %+v", embedded)
+	}
+}
+
+// TestFakeServer_TaskAfterCreate は作成直後（埋め込みJSONが create_new のまま）の記事のタスクを記事番号で更新できることを確認する
+func TestFakeServer_TaskAfterCreate(t *testing.T) {
+	fake := esatest.NewServer("test-team", "test-token")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := esa.NewEsaClient("test-team", "test-token")
+	if err := client.SetBaseURL(server.URL); err != nil {
+		t.Fatalf("SetBaseURL() error = %v", err)
+	}
+	policy := NewPolicy([]string{"LLM/Tasks"})
+
+	jsonPath := filepath.Join(t.TempDir(), "post.json")
+	inputJSON := `{
+		"create_new": true,
+		"name": "Test Post",
+		"category": "LLM/Tasks/2026/01/28",
+		"body": {
+			"background": "Test background",
+			"tasks": [
+				{"id": "task-1", "title": "Task 1: Test task", "status": "not_started", "summary": ["Task summary"], "description": "Task description"}
+			]
+		}
+	}`
+	if err := os.WriteFile(jsonPath, []byte(inputJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	created, err := PostFile(context.Background(), jsonPath, policy, client, PostOptions{})
+	if err != nil {
+		t.Fatalf("PostFile() error = %v", err)
+	}
+
+	edit := SetTaskStatus("task-1", TaskStatusInReview, "https://github.com/example/repo/pull/1")
+	result, err := EditTasks(context.Background(), TaskTarget{PostNumber: created.PostNumber}, edit, policy, client, PostOptions{})
+	if err != nil {
+		t.Fatalf("EditTasks() after create error = %v", err)
+	}
+	if result.RevisionNumber != 2 || !slices.Equal(result.ChangedTasks, []string{"task-1"}) {
+		t.Errorf("result = %+v", result)
+	}
+
+	post, _ := fake.Post(created.PostNumber)
+	embedded, err := ExtractEmbeddedJSON(post.BodyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embedded.Body.Tasks[0].Status != TaskStatusInReview || embedded.PostNumber == nil || *embedded.PostNumber != created.PostNumber {
+		t.Errorf("embedded JSON after task edit = %+v", embedded)
+	}
+}
+
+// TestFakeServer_ScaffoldFromCreatedPost は作成直後（埋め込みJSONが create_new のまま）の記事を元に雛形を作れることを確認する
+func TestFakeServer_ScaffoldFromCreatedPost(t *testing.T) {
+	fake := esatest.NewServer("test-team", "test-token")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := esa.NewEsaClient("test-team", "test-token")
+	if err := client.SetBaseURL(server.URL); err != nil {
+		t.Fatalf("SetBaseURL() error = %v", err)
+	}
+	policy := NewPolicy([]string{"LLM/Tasks"})
+
+	input, err := DecodePostInput([]byte(`{
+		"create_new": true,
+		"name": "Test Post",
+		"category": "LLM/Tasks/2026/01/28",
+		"body": {
+			"background": "Test background",
+			"tasks": [
+				{"id": "task-1", "title": "Task 1: Test task", "status": "in_progress", "summary": ["Task summary"], "description": "Task description"}
+			]
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := Post(context.Background(), input, policy, client, PostOptions{})
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+
+	opts := ScaffoldOptions{
+		BaseCategory: "LLM/Tasks",
+		FromPost:     created.Post.Number,
+		Now:          time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC),
+		Location:     time.UTC,
+	}
+	scaffold, err := Scaffold(context.Background(), opts, policy, client)
+	if err != nil {
+		t.Fatalf("Scaffold() from a created post error = %v", err)
+	}
+	if !scaffold.CreateNew || scaffold.PostNumber != nil || scaffold.Name != "Test Post" || scaffold.Category != "LLM/Tasks/2026/02/01" {
+		t.Errorf("scaffold = %+v", scaffold)
+	}
+	if scaffold.Body.Background != "Test background" || scaffold.Body.Tasks[0].Status != TaskStatusNotStarted {
+		t.Errorf("scaffold body = %+v", scaffold.Body)
+	}
+}
@@ -1,17 +1,17 @@
 package guard
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
 )
 
 // ExecuteFetch fetches a post from esa.io and outputs embedded JSON in pretty-print format
-func ExecuteFetch(postNumber int, teamName string, accessToken string) error {
-	client := esa.NewEsaClient(teamName, accessToken)
-	output, err := executeFetchWithClient(postNumber, client)
+func ExecuteFetch(ctx context.Context, postNumber int, policy *Policy, client esa.EsaClientInterface) error {
+	output, err := executeFetchWithClient(ctx, postNumber, policy, client)
 	if err != nil {
 		return err
 	}
@@ -21,50 +21,102 @@ func ExecuteFetch(postNumber int, teamName string, accessToken string) error {
 }
 
 // executeFetchWithClient fetches a post and extracts embedded JSON (testable version)
-func executeFetchWithClient(postNumber int, client esa.EsaClientInterface) (string, error) {
-	// 1. Get post from esa.io API
-	post, err := client.GetPost(postNumber)
+func executeFetchWithClient(ctx context.Context, postNumber int, policy *Policy, client esa.EsaClientInterface) (string, error) {
+	input, err := Fetch(ctx, postNumber, policy, client)
 	if err != nil {
-		return "", fmt.Errorf("failed to get post: %w", err)
+		return "", err
+	}
+
+	return FormatFetched(input)
+}
+
+// FormatFetched pretty-prints fetched embedded JSON for text output
+func FormatFetched(input *PostInput) (string, error) {
+	prettyJSON, err := json.MarshalIndent(input, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	return string(prettyJSON), nil
+}
+
+// FetchPost fetches a post and returns its embedded JSON as a command result
+func FetchPost(ctx context.Context, postNumber int, policy *Policy, client esa.EsaClientInterface) (*Result, error) {
+	input, err := Fetch(ctx, postNumber, policy, client)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newResult("fetch")
+	result.PostNumber = postNumber
+	result.JSON = input
+	return result, nil
+}
+
+// Fetch gets a post from esa.io and returns its embedded JSON.
+// Posts the policy does not allow reading are rejected with read_not_allowed.
+func Fetch(ctx context.Context, postNumber int, policy *Policy, client esa.EsaClientInterface) (*PostInput, error) {
+	// 1. Get post from esa.io API and check read scope before looking at the body
+	post, err := getReadablePost(ctx, postNumber, policy, client)
+	if err != nil {
+		return nil, err
 	}
 
 	// 2. Check body size (10MB max)
 	if len(post.BodyMD) > MaxInputSize {
-		return "", fmt.Errorf("post body exceeds %d bytes limit", MaxInputSize)
+		return nil, WithKind(ErrorKindContent, fmt.Errorf("post body exceeds %d bytes limit", MaxInputSize))
 	}
 
 	// 3. Check if body is empty
 	if post.BodyMD == "" {
-		return "", fmt.Errorf("post body is empty")
+		return nil, WithKind(ErrorKindContent, fmt.Errorf("post body is empty"))
 	}
 
 	// 4. Extract embedded JSON (parse-only, no schema validation)
 	input, err := ExtractEmbeddedJSON(post.BodyMD)
 	if err != nil {
 		// Convert extraction errors to plan-specified error messages
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "sentinel not found") {
-			return "", fmt.Errorf("no embedded JSON found in post %d", postNumber)
+		if errors.Is(err, ErrSentinelNotFound) {
+			return nil, WithKind(ErrorKindContent, fmt.Errorf("no embedded JSON found in post %d", postNumber))
 		}
 		// For other errors (closing tag not found, parse errors, size errors, etc.)
-		return "", fmt.Errorf("invalid JSON in post %d: %s", postNumber, errMsg)
+		return nil, WithKind(ErrorKindContent, fmt.Errorf("invalid JSON in post %d: %s", postNumber, err))
 	}
 
 	// 5. Check post_number consistency (fail closed security check)
 	// fetch command only targets existing posts (post_number required).
-	// nil post_number is rejected because fetch is for retrieving existing posts from esa.io.
+	// A post created by this tool still embeds create_new:true without post_number
+	// (the same case ValidateManagedPost accepts), so it is bound to the requested number.
+	// Any other nil post_number is rejected because fetch is for retrieving existing posts from esa.io.
+	if input.PostNumber == nil && input.CreateNew {
+		input.CreateNew = false
+		input.PostNumber = &postNumber
+	}
 	if input.PostNumber == nil {
-		return "", fmt.Errorf("post_number is required in embedded JSON (fetch targets existing posts only)")
+		return nil, WithKind(ErrorKindContent, fmt.Errorf("post_number is required in embedded JSON (fetch targets existing posts only)"))
 	}
 	if *input.PostNumber != postNumber {
-		return "", fmt.Errorf("post_number mismatch: embedded JSON has %d, but requested %d", *input.PostNumber, postNumber)
+		return nil, WithKind(ErrorKindContent, fmt.Errorf("post_number mismatch: embedded JSON has %d, but requested %d", *input.PostNumber, postNumber))
 	}
 
-	// 6. Pretty-print JSON for output
-	prettyJSON, err := json.MarshalIndent(input, "", "  ")
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+	// 6. Record the revision we saw so that a later update can detect concurrent edits
+	input.RevisionNumber = nil
+	if post.RevisionNumber > 0 {
+		revision := post.RevisionNumber
+		input.RevisionNumber = &revision
 	}
 
-	return string(prettyJSON), nil
+	return input, nil
+}
+
+// getReadablePost gets a post and rejects it unless the policy allows reading its category
+func getReadablePost(ctx context.Context, postNumber int, policy *Policy, client esa.EsaClientInterface) (*esa.Post, error) {
+	post, err := client.GetPost(ctx, postNumber)
+	if err != nil {
+		return nil, WithKind(ErrorKindAPI, fmt.Errorf("failed to get post: %w", err))
+	}
+	if err := ValidateReadAccess(post.Category, policy); err != nil {
+		return nil, err
+	}
+	return post, nil
 }
@@ -1,6 +1,8 @@
 package guard
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -9,19 +11,24 @@ import (
 )
 
 type mockFetchClient struct {
-	bodyMD string
-	err    error
+	bodyMD   string
+	revision int
+	err      error
 }
 
-func (m *mockFetchClient) CreatePost(post *esa.PostInput) (*esa.Post, error) {
+func (m *mockFetchClient) CreatePost(ctx context.Context, post *esa.PostInput) (*esa.Post, error) {
 	return nil, fmt.Errorf("CreatePost should not be called in fetch")
 }
 
-func (m *mockFetchClient) UpdatePost(postNumber int, post *esa.PostInput) (*esa.Post, error) {
+func (m *mockFetchClient) UpdatePost(ctx context.Context, postNumber int, post *esa.PostInput) (*esa.Post, error) {
 	return nil, fmt.Errorf("UpdatePost should not be called in fetch")
 }
 
-func (m *mockFetchClient) GetPost(postNumber int) (*esa.Post, error) {
+func (m *mockFetchClient) ListPosts(ctx context.Context, q string, page, perPage int) (*esa.PostList, error) {
+	return nil, fmt.Errorf("ListPosts should not be called in fetch")
+}
+
+func (m *mockFetchClient) GetPost(ctx context.Context, postNumber int) (*esa.Post, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -30,6 +37,8 @@ func (m *mockFetchClient) GetPost(postNumber int) (*esa.Post, error) {
 		Name:     "Test Post",
 		Category: "LLM/Test/2026/01/31",
 		BodyMD:   m.bodyMD,
+
+		RevisionNumber: m.revision,
 	}, nil
 }
 
@@ -43,7 +52,7 @@ func TestExecuteFetch_Success(t *testing.T) {
 
 	client := &mockFetchClient{bodyMD: bodyMD}
 
-	output, err := executeFetchWithClient(123, client)
+	output, err := executeFetchWithClient(context.Background(), 123, NewPolicy([]string{"LLM/Test"}), client)
 	if err != nil {
 		t.Fatalf("executeFetchWithClient() error = %v", err)
 	}
@@ -65,7 +74,7 @@ No JSON here.`
 
 	client := &mockFetchClient{bodyMD: bodyMD}
 
-	_, err := executeFetchWithClient(123, client)
+	_, err := executeFetchWithClient(context.Background(), 123, NewPolicy([]string{"LLM/Test"}), client)
 	if err == nil {
 		t.Fatal("Expected error for missing embedded JSON")
 	}
@@ -78,7 +87,7 @@ No JSON here.`
 func TestExecuteFetch_EmptyBody(t *testing.T) {
 	client := &mockFetchClient{bodyMD: ""}
 
-	_, err := executeFetchWithClient(123, client)
+	_, err := executeFetchWithClient(context.Background(), 123, NewPolicy([]string{"LLM/Test"}), client)
 	if err == nil {
 		t.Fatal("Expected error for empty body")
 	}
@@ -94,7 +103,7 @@ func TestExecuteFetch_BodyTooLarge(t *testing.T) {
 
 	client := &mockFetchClient{bodyMD: largeBody}
 
-	_, err := executeFetchWithClient(123, client)
+	_, err := executeFetchWithClient(context.Background(), 123, NewPolicy([]string{"LLM/Test"}), client)
 	if err == nil {
 		t.Fatal("Expected error for body exceeding 10MB")
 	}
@@ -112,7 +121,7 @@ func TestExecuteFetch_BodyExactly10MB(t *testing.T) {
 	client := &mockFetchClient{bodyMD: largeBody}
 
 	// Exactly 10MB should succeed (no size error)
-	_, err := executeFetchWithClient(123, client)
+	_, err := executeFetchWithClient(context.Background(), 123, NewPolicy([]string{"LLM/Test"}), client)
 	// May fail on JSON extraction but not on size check
 	if err != nil && strings.Contains(err.Error(), "exceeds") {
 		t.Fatalf("Expected no size error for exactly 10MB, got: %v", err)
@@ -127,7 +136,7 @@ func TestExecuteFetch_BodyJustUnder10MB(t *testing.T) {
 	client := &mockFetchClient{bodyMD: largeBody}
 
 	// Just under 10MB should succeed (no size error)
-	_, err := executeFetchWithClient(123, client)
+	_, err := executeFetchWithClient(context.Background(), 123, NewPolicy([]string{"LLM/Test"}), client)
 	// May fail on JSON extraction but not on size check
 	if err != nil && strings.Contains(err.Error(), "exceeds") {
 		t.Fatalf("Expected no size error for body just under 10MB, got: %v", err)
@@ -143,7 +152,7 @@ Content`
 
 	client := &mockFetchClient{bodyMD: bodyMD}
 
-	_, err := executeFetchWithClient(123, client)
+	_, err := executeFetchWithClient(context.Background(), 123, NewPolicy([]string{"LLM/Test"}), client)
 	if err == nil {
 		t.Fatal("Expected error for invalid JSON")
 	}
@@ -164,7 +173,7 @@ func TestExecuteFetch_PostNumberMismatch(t *testing.T) {
 
 	client := &mockFetchClient{bodyMD: bodyMD}
 
-	_, err := executeFetchWithClient(123, client)
+	_, err := executeFetchWithClient(context.Background(), 123, NewPolicy([]string{"LLM/Test"}), client)
 	if err == nil {
 		t.Fatal("Expected error for post_number mismatch")
 	}
@@ -193,7 +202,7 @@ func TestExecuteFetch_PostNumberNil(t *testing.T) {
 
 	client := &mockFetchClient{bodyMD: bodyMD}
 
-	_, err := executeFetchWithClient(123, client)
+	_, err := executeFetchWithClient(context.Background(), 123, NewPolicy([]string{"LLM/Test"}), client)
 	if err == nil {
 		t.Fatal("Expected error for nil post_number (fetch targets existing posts only)")
 	}
@@ -202,3 +211,40 @@ func TestExecuteFetch_PostNumberNil(t *testing.T) {
 		t.Errorf("Expected 'post_number is required' error, got: %v", err)
 	}
 }
+
+func TestFetch_RecordsRevisionNumber(t *testing.T) {
+	// 埋め込みJSONに古いrevision_numberが含まれていても、esa.io上の値で上書きする
+	bodyMD := "<!-- esa-guard-json\n" +
+		`{"post_number":123,"revision_number":1,"name":"Test","category":"LLM/Test/2026/01/31","body":{"background":"test","tasks":[{"id":"task-1","title":"Task 1: Test","status":"not_started","summary":["test"],"description":"test"}]}}` +
+		"\n-->\n\n## サマリー\n"
+
+	input, err := Fetch(context.Background(), 123, NewPolicy([]string{"LLM/Test"}), &mockFetchClient{bodyMD: bodyMD, revision: 5})
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if input.RevisionNumber == nil || *input.RevisionNumber != 5 {
+		t.Errorf("RevisionNumber = %v, want 5", input.RevisionNumber)
+	}
+
+	// リビジョン情報がない場合は記録しない
+	input, err = Fetch(context.Background(), 123, NewPolicy([]string{"LLM/Test"}), &mockFetchClient{bodyMD: bodyMD})
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if input.RevisionNumber != nil {
+		t.Errorf("RevisionNumber = %v, want nil", *input.RevisionNumber)
+	}
+}
+
+func TestFetch_CategoryNotReadable(t *testing.T) {
+	bodyMD := "<!-- esa-guard-json\n" +
+		`{"post_number":123,"name":"Test","category":"LLM/Test/2026/01/31","body":{"background":"test","tasks":[{"id":"task-1","title":"Task 1: Test","status":"not_started","summary":["test"],"description":"test"}]}}` +
+		"\n-->\n\n## サマリー\n"
+
+	// 記事のカテゴリ（LLM/Test/2026/01/31）が読み取り可能なカテゴリの外にある
+	_, err := Fetch(context.Background(), 123, NewPolicy([]string{"LLM/Other"}), &mockFetchClient{bodyMD: bodyMD})
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Code() != ErrCodeReadNotAllowed {
+		t.Fatalf("Fetch() error = %v, want code %s", err, ErrCodeReadNotAllowed)
+	}
+}
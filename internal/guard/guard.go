@@ -2,6 +2,7 @@ package guard
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -41,8 +42,9 @@ func NormalizeCategory(category string) (string, error) {
 	return category, nil
 }
 
-// IsAllowedCategory はカテゴリが許可カテゴリに含まれているかチェックします。
+// IsAllowedCategory はカテゴリがcategoriesのいずれか（またはその配下）に含まれているかチェックします。
 // 境界チェックにより、"LLM/Tasks" が "LLM/Tasks-evil" にマッチしないことを保証します。
+// 操作ごとの権限の判定には Policy.Evaluate を使います。
 func IsAllowedCategory(category string, allowedCategories []string) (bool, error) {
 	// カテゴリを正規化
 	normalized, err := NormalizeCategory(category)
@@ -59,14 +61,8 @@ func IsAllowedCategory(category string, allowedCategories []string) (bool, error
 				Wrap(err)
 		}
 
-		// 完全一致
-		if normalized == normalizedAllowed {
-			return true, nil
-		}
-
-		// サブカテゴリチェック（境界チェック付き）
-		// "LLM/Tasks" が "LLM/Tasks/sub" にマッチするが、"LLM/Tasks-evil" にはマッチしない
-		if strings.HasPrefix(normalized, normalizedAllowed+"/") {
+		// 完全一致、またはサブカテゴリ（境界チェック付き）
+		if isUnderCategory(normalized, normalizedAllowed) {
 			return true, nil
 		}
 	}
@@ -74,19 +70,24 @@ func IsAllowedCategory(category string, allowedCategories []string) (bool, error
 	return false, nil
 }
 
-// ValidateUpdateRequest は更新リクエストの妥当性を検証します。
-// 既存記事のカテゴリが許可範囲内か、カテゴリ変更が試みられていないかをチェックします。
-func ValidateUpdateRequest(existingCategory, newCategory string, allowedCategories []string) error {
-	// 既存カテゴリが許可範囲内か確認
-	allowedExisting, err := IsAllowedCategory(existingCategory, allowedCategories)
-	if err != nil {
-		return NewValidationError(ErrCodeCategoryNotAllowed, fmt.Sprintf("existing category validation failed: %v", err)).
-			Wrap(err)
-	}
-	if !allowedExisting {
-		return NewValidationError(ErrCodeCategoryNotAllowed, fmt.Sprintf("existing post category %s is not allowed", existingCategory)).
+// ValidateReadAccess は記事のカテゴリの読み取りがポリシーで許可されているかを検証します。
+// カテゴリなしの記事や判定できないカテゴリは拒否します（fail closed）。
+func ValidateReadAccess(category string, policy *Policy) error {
+	decision, err := policy.Evaluate(category, OperationRead)
+	if err != nil || !decision.Allowed {
+		return NewValidationError(ErrCodeReadNotAllowed, fmt.Sprintf("reading posts in category %q is not allowed (%s)", category, decision.Reason())).
 			WithField("category")
 	}
+	return nil
+}
+
+// ValidateUpdateRequest は更新リクエストの妥当性を検証します。
+// 既存記事のカテゴリの更新がポリシーで許可されているか、カテゴリ変更が試みられていないかをチェックします。
+func ValidateUpdateRequest(existingCategory, newCategory string, policy *Policy) error {
+	// 既存カテゴリの更新が許可されているか確認
+	if err := policy.Check("existing post category", existingCategory, OperationUpdate); err != nil {
+		return err
+	}
 
 	// カテゴリホッピング防止（既存カテゴリ == 入力カテゴリ）
 	if existingCategory != newCategory {
@@ -96,3 +97,65 @@ func ValidateUpdateRequest(existingCategory, newCategory string, allowedCategori
 
 	return nil
 }
+
+// ValidateRepositoryTag は既存記事にリポジトリのタグが付いているかを検証します。
+// リポジトリにカテゴリを割り当てている場合、別のリポジトリから作成された記事は更新できません。
+func ValidateRepositoryTag(tags []string, repositoryTag string, postNumber int) error {
+	if slices.Contains(tags, repositoryTag) {
+		return nil
+	}
+	return NewValidationError(ErrCodeRepositoryTagMissing,
+		fmt.Sprintf("post %d is not tagged with %s; only posts created from this repository can be updated here", postNumber, repositoryTag)).
+		WithField("tags")
+}
+
+// ValidateTeam は入力JSONに記録されたチームが設定のチームと一致するかを検証します。
+// 記事番号はチームごとの番号のため、別のチームの記事番号で更新すると無関係の記事を上書きしてしまいます。
+func ValidateTeam(inputTeam, team string) error {
+	if inputTeam == "" || inputTeam == team {
+		return nil
+	}
+	return NewValidationError(ErrCodeTeamMismatch,
+		fmt.Sprintf("the JSON belongs to team %s, but the configured team is %s; select the profile for %s with -profile", inputTeam, team, inputTeam)).
+		WithField("team")
+}
+
+// IsTeamName はesa.ioのチーム名に使える文字（A-Z, a-z, 0-9, _, -）だけの空でない文字列かを返します
+// 設定ファイルのプロファイル名も同じ文字に限る
+func IsTeamName(team string) bool {
+	if team == "" {
+		return false
+	}
+	for _, c := range team {
+		if !((c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateManagedPost は既存記事がガードによって作成・更新された記事かを検証します。
+// 本文の先頭に埋め込みJSONがあり、そのpost_numberが更新対象と一致する必要があります。
+// 新規作成時の埋め込みJSONは記事番号が確定する前に生成されるため、
+// create_new: true でpost_numberがない場合も管理対象とみなします。
+func ValidateManagedPost(bodyMD string, postNumber int) error {
+	embedded, err := ExtractEmbeddedJSON(bodyMD)
+	if err != nil {
+		return NewValidationError(ErrCodePostNotManaged,
+			fmt.Sprintf("post %d is not managed by esa-llm-scoped-guard (%v); review it with diff and use -adopt to take it over", postNumber, err)).
+			Wrap(err)
+	}
+
+	if embedded.PostNumber == nil {
+		if embedded.CreateNew {
+			return nil
+		}
+		return NewValidationError(ErrCodePostNotManaged,
+			fmt.Sprintf("post %d is not managed by esa-llm-scoped-guard (embedded JSON has no post_number); review it with diff and use -adopt to take it over", postNumber))
+	}
+	if *embedded.PostNumber != postNumber {
+		return NewValidationError(ErrCodePostNotManaged,
+			fmt.Sprintf("post %d is not managed by esa-llm-scoped-guard (embedded JSON belongs to post %d); review it with diff and use -adopt to take it over", postNumber, *embedded.PostNumber))
+	}
+	return nil
+}
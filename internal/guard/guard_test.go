@@ -203,6 +203,28 @@ func TestNormalizeCategory(t *testing.T) {
 	}
 }
 
+func TestIsTeamName(t *testing.T) {
+	tests := []struct {
+		name string
+		team string
+		want bool
+	}{
+		{"英数字", "myteam01", true},
+		{"アンダースコアとハイフン", "my_team-dev", true},
+		{"空", "", false},
+		{"ドット", "my.team", false},
+		{"スラッシュ", "my/team", false},
+		{"全角文字", "チーム", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTeamName(tt.team); got != tt.want {
+				t.Errorf("IsTeamName(%q) = %v, want %v", tt.team, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidateUpdateRequest(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -246,7 +268,7 @@ func TestValidateUpdateRequest(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := ValidateUpdateRequest(tt.existingCategory, tt.newCategory, tt.allowedCategories)
+			err := ValidateUpdateRequest(tt.existingCategory, tt.newCategory, NewPolicy(tt.allowedCategories))
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ValidateUpdateRequest() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -48,6 +48,12 @@ func ReadPostInputFromFile(path string) (*PostInput, error) {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
+	return DecodePostInput(data)
+}
+
+// DecodePostInput はJSONデータを厳格にデコードしPostInputを返します
+// 未知のフィールド、複数値、末尾データはエラーとします
+func DecodePostInput(data []byte) (*PostInput, error) {
 	// サイズ超過チェック
 	if len(data) > MaxInputSize {
 		return nil, NewValidationError(ErrCodeFileSizeExceeded, "file size exceeds 10MB")
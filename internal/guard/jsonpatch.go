@@ -0,0 +1,420 @@
+package guard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PatchType はパッチの形式
+type PatchType string
+
+const (
+	PatchTypeJSONPatch  PatchType = "json-patch"  // RFC 6902 JSON Patch
+	PatchTypeMergePatch PatchType = "merge-patch" // RFC 7386 JSON Merge Patch
+)
+
+// DetectPatchType はパッチの先頭の値から形式を判定します（配列ならJSON Patch、オブジェクトならMerge Patch）
+func DetectPatchType(patch []byte) (PatchType, error) {
+	trimmed := bytes.TrimLeft(patch, " \t\r\n")
+	if len(trimmed) == 0 {
+		return "", NewValidationError(ErrCodeJSONInvalid, "patch is empty")
+	}
+	switch trimmed[0] {
+	case '[':
+		return PatchTypeJSONPatch, nil
+	case '{':
+		return PatchTypeMergePatch, nil
+	default:
+		return "", NewValidationError(ErrCodeJSONInvalid, "patch must be a JSON array (RFC 6902) or object (RFC 7386)")
+	}
+}
+
+// jsonPatchOperation はRFC 6902の1操作
+type jsonPatchOperation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from,omitempty"`
+	Value *json.RawMessage `json:"value,omitempty"`
+}
+
+// applyJSONPatch はdocにRFC 6902 JSON Patchを適用します
+func applyJSONPatch(doc interface{}, patch []byte) (interface{}, error) {
+	var ops []jsonPatchOperation
+	if err := decodeStrict(patch, &ops); err != nil {
+		return nil, NewValidationError(ErrCodeJSONInvalid, fmt.Sprintf("failed to parse JSON Patch: %v", err)).Wrap(err)
+	}
+
+	for i, op := range ops {
+		var err error
+		doc, err = applyJSONPatchOperation(doc, op)
+		if err != nil {
+			return nil, NewValidationError(ErrCodeInvalidValue, fmt.Sprintf("patch operation %d (%s) failed: %v", i, op.Op, err)).
+				WithField("patch").WithIndex(i)
+		}
+	}
+	return doc, nil
+}
+
+// applyJSONPatchOperation は1操作を適用します
+func applyJSONPatchOperation(doc interface{}, op jsonPatchOperation) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("path is required")
+	}
+	path, err := parseJSONPointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := func() (interface{}, error) {
+		if op.Value == nil {
+			return nil, fmt.Errorf("value is required")
+		}
+		return decodeJSONValue(*op.Value)
+	}
+	from := func() ([]string, error) {
+		if op.From == nil {
+			return nil, fmt.Errorf("from is required")
+		}
+		return parseJSONPointer(*op.From)
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "remove":
+		doc, _, err := pointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if doc, _, err = pointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "move":
+		fromPath, err := from()
+		if err != nil {
+			return nil, err
+		}
+		if isPointerPrefix(fromPath, path) && len(fromPath) < len(path) {
+			return nil, fmt.Errorf("cannot move a value into one of its children")
+		}
+		doc, v, err := pointerRemove(doc, fromPath)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "copy":
+		fromPath, err := from()
+		if err != nil {
+			return nil, err
+		}
+		v, err := pointerGet(doc, fromPath)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, deepCopyJSON(v))
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(actual, v) {
+			return nil, fmt.Errorf("test failed at %s", *op.Path)
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
+// applyMergePatch はdocにRFC 7386 JSON Merge Patchを適用します
+func applyMergePatch(doc interface{}, patch []byte) (interface{}, error) {
+	p, err := decodeJSONValue(patch)
+	if err != nil {
+		return nil, NewValidationError(ErrCodeJSONInvalid, fmt.Sprintf("failed to parse merge patch: %v", err)).Wrap(err)
+	}
+	return mergePatchValue(doc, p), nil
+}
+
+// mergePatchValue はRFC 7386のMergePatch関数
+func mergePatchValue(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatchValue(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// patchTouchedFields はパッチが変更しうるトップレベルのフィールド名を返します
+// ルート全体を対象にする操作は "" を返す
+func patchTouchedFields(patchType PatchType, patch []byte) ([]string, error) {
+	var fields []string
+	switch patchType {
+	case PatchTypeJSONPatch:
+		var ops []jsonPatchOperation
+		if err := decodeStrict(patch, &ops); err != nil {
+			return nil, NewValidationError(ErrCodeJSONInvalid, fmt.Sprintf("failed to parse JSON Patch: %v", err)).Wrap(err)
+		}
+		for _, op := range ops {
+			pointers := []*string{op.Path}
+			if op.Op == "move" {
+				pointers = append(pointers, op.From) // moveは移動元も削除する
+			}
+			for _, p := range pointers {
+				if p == nil {
+					continue
+				}
+				path, err := parseJSONPointer(*p)
+				if err != nil {
+					return nil, NewValidationError(ErrCodeInvalidValue, err.Error()).WithField("patch")
+				}
+				if len(path) == 0 {
+					fields = append(fields, "")
+				} else if op.Op != "test" {
+					fields = append(fields, path[0])
+				}
+			}
+		}
+	case PatchTypeMergePatch:
+		p, err := decodeJSONValue(patch)
+		if err != nil {
+			return nil, NewValidationError(ErrCodeJSONInvalid, fmt.Sprintf("failed to parse merge patch: %v", err)).Wrap(err)
+		}
+		obj, ok := p.(map[string]interface{})
+		if !ok {
+			return []string{""}, nil
+		}
+		for key := range obj {
+			fields = append(fields, key)
+		}
+	}
+	return fields, nil
+}
+
+// parseJSONPointer はRFC 6901のJSON Pointerを分解します
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// isPointerPrefix はprefixがpathの先頭部分かを返します
+func isPointerPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pointerGet はpathの値を返します
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	current := doc
+	for _, token := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			v, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path not found: %s", token)
+			}
+			current = v
+		case []interface{}:
+			idx, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[idx]
+		default:
+			return nil, fmt.Errorf("cannot traverse into a scalar at %s", token)
+		}
+	}
+	return current, nil
+}
+
+// pointerAdd はpathにvalueを追加した文書を返します（配列は挿入、オブジェクトは追加または置換）
+func pointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		idx, err := arrayIndex(last, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		updated := make([]interface{}, 0, len(node)+1)
+		updated = append(updated, node[:idx]...)
+		updated = append(updated, value)
+		updated = append(updated, node[idx:]...)
+		return replaceAt(doc, path[:len(path)-1], updated)
+	default:
+		return nil, fmt.Errorf("cannot add to a scalar")
+	}
+}
+
+// pointerRemove はpathの値を取り除いた文書と、取り除いた値を返します
+func pointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		v, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path not found: %s", last)
+		}
+		delete(node, last)
+		return doc, v, nil
+	case []interface{}:
+		idx, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := node[idx]
+		updated := make([]interface{}, 0, len(node)-1)
+		updated = append(updated, node[:idx]...)
+		updated = append(updated, node[idx+1:]...)
+		doc, err = replaceAt(doc, path[:len(path)-1], updated)
+		return doc, v, err
+	default:
+		return nil, nil, fmt.Errorf("cannot remove from a scalar")
+	}
+}
+
+// replaceAt はpathの値をvalueに置き換えた文書を返します（配列の長さが変わる場合に使う）
+func replaceAt(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		idx, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node[idx] = value
+	}
+	return doc, nil
+}
+
+// arrayIndex は配列インデックスのトークンを解釈します（allowEndがtrueなら "-" と末尾の次を許可）
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	maxIdx := length - 1
+	if allowEnd {
+		maxIdx = length
+	}
+	if idx > maxIdx {
+		return 0, fmt.Errorf("array index %d out of range", idx)
+	}
+	return idx, nil
+}
+
+// decodeJSONValue はJSONを汎用の値にデコードします（数値は精度を保つためjson.Number）
+func decodeJSONValue(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := decodeStrict(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// decodeStrict はJSONを1つだけ含むことを確認してデコードします
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("multiple JSON values")
+	}
+	return nil
+}
+
+// deepCopyJSON は汎用のJSON値をディープコピーします
+func deepCopyJSON(v interface{}) interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(node))
+		for k, child := range node {
+			c[k] = deepCopyJSON(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(node))
+		for i, child := range node {
+			c[i] = deepCopyJSON(child)
+		}
+		return c
+	default:
+		return v
+	}
+}
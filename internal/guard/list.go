@@ -0,0 +1,224 @@
+package guard
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+const (
+	// listPerPage は1回の検索で取得する件数（esa.io APIの上限）
+	listPerPage = 100
+	// listMaxPages はカテゴリごとに取得する最大ページ数
+	listMaxPages = 10
+	// DefaultListLimit は一覧に表示する記事数の既定値
+	DefaultListLimit = 50
+)
+
+// ListOptions は記事一覧の検索条件
+type ListOptions struct {
+	Query    string // esa.ioの検索クエリ（カテゴリを指定する修飾子は使えない）
+	Category string // 許可カテゴリ内でさらに絞り込むカテゴリ（空なら許可カテゴリすべて）
+	Limit    int    // 最大件数（0以下は DefaultListLimit）
+}
+
+// PostSummary は一覧に表示する記事の概要
+type PostSummary struct {
+	Number   int          `json:"post_number"`
+	Name     string       `json:"name"`
+	Category string       `json:"category"`
+	URL      string       `json:"url,omitempty"`
+	Progress TaskProgress `json:"progress"`
+}
+
+// TaskProgress は埋め込みJSONから集計したタスクの進捗
+type TaskProgress struct {
+	Total      int `json:"total"`
+	NotStarted int `json:"not_started"`
+	InProgress int `json:"in_progress"`
+	InReview   int `json:"in_review"`
+	Completed  int `json:"completed"`
+}
+
+// ExecuteList は許可カテゴリ内のガード管理記事を一覧表示します
+func ExecuteList(ctx context.Context, opts ListOptions, policy *Policy, client esa.EsaClientInterface) error {
+	summaries, err := List(ctx, opts, policy, client)
+	if err != nil {
+		return err
+	}
+
+	if len(summaries) == 0 {
+		fmt.Println("No managed posts found.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range summaries {
+		fmt.Fprintf(w, "#%d\t%d/%d completed\t%s\t%s\n", s.Number, s.Progress.Completed, s.Progress.Total, s.Category, s.Name)
+	}
+	return w.Flush()
+}
+
+// ListPosts は記事一覧をコマンドの実行結果として返します
+func ListPosts(ctx context.Context, opts ListOptions, policy *Policy, client esa.EsaClientInterface) (*Result, error) {
+	summaries, err := List(ctx, opts, policy, client)
+	if err != nil {
+		return nil, err
+	}
+	result := newResult("list")
+	result.Posts = &summaries
+	return result, nil
+}
+
+// List は許可カテゴリ内だけを検索し、ガードが管理している記事（埋め込みJSONを持つ記事）の概要を返します。
+// 検索クエリにカテゴリを指定する修飾子やORが含まれる場合は、許可カテゴリの外を検索できてしまうため送信前に拒否します。
+// 検索結果も許可カテゴリ内かを改めて確認します（fail closed）。
+func List(ctx context.Context, opts ListOptions, policy *Policy, client esa.EsaClientInterface) ([]PostSummary, error) {
+	if err := validateListQuery(opts.Query); err != nil {
+		return nil, err
+	}
+
+	scopes := policy.WritableCategories()
+	if opts.Category != "" {
+		allowed, err := IsAllowedCategory(opts.Category, scopes)
+		if err != nil {
+			return nil, fmt.Errorf("category validation failed: %w", err)
+		}
+		if !allowed {
+			return nil, NewValidationError(ErrCodeCategoryNotAllowed, fmt.Sprintf("category %s is not allowed", opts.Category)).
+				WithField("category")
+		}
+		scopes = []string{opts.Category}
+	}
+	if len(scopes) == 0 {
+		return nil, NewValidationError(ErrCodeCategoryNotAllowed, "no allowed categories to search (fail closed)").
+			WithField("category")
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+
+	seen := make(map[int]bool)
+	var summaries []PostSummary
+	for _, scope := range scopes {
+		scopeQuery, err := categoryQuery(scope)
+		if err != nil {
+			return nil, err
+		}
+		q := scopeQuery
+		if query := strings.TrimSpace(opts.Query); query != "" {
+			q += " " + query
+		}
+
+		for page := 1; page <= listMaxPages && len(summaries) < limit; page++ {
+			list, err := client.ListPosts(ctx, q, page, listPerPage)
+			if err != nil {
+				return nil, WithKind(ErrorKindAPI, fmt.Errorf("failed to list posts: %w", err))
+			}
+			for _, post := range list.Posts {
+				if seen[post.Number] || len(summaries) >= limit {
+					continue
+				}
+				if summary, ok := summarizePost(post, policy); ok {
+					seen[post.Number] = true
+					summaries = append(summaries, summary)
+				}
+			}
+			if list.NextPage == nil {
+				break
+			}
+		}
+	}
+
+	slices.SortFunc(summaries, func(a, b PostSummary) int { return b.Number - a.Number })
+	if summaries == nil {
+		summaries = []PostSummary{}
+	}
+	return summaries, nil
+}
+
+// summarizePost は許可カテゴリ内の読み取れるガード管理記事であれば概要を返します
+func summarizePost(post esa.Post, policy *Policy) (PostSummary, bool) {
+	allowed, err := IsAllowedCategory(post.Category, policy.WritableCategories())
+	if err != nil || !allowed {
+		// カテゴリなしの記事など、許可カテゴリ外の結果は表示しない
+		return PostSummary{}, false
+	}
+	if decision, err := policy.Evaluate(post.Category, OperationRead); err != nil || !decision.Allowed {
+		// denied_categories など、読み取りを許可しないカテゴリの記事も表示しない
+		return PostSummary{}, false
+	}
+	if ValidateManagedPost(post.BodyMD, post.Number) != nil {
+		return PostSummary{}, false
+	}
+	input, err := ExtractEmbeddedJSON(post.BodyMD)
+	if err != nil {
+		return PostSummary{}, false
+	}
+
+	summary := PostSummary{
+		Number:   post.Number,
+		Name:     post.Name,
+		Category: post.Category,
+		URL:      post.URL,
+	}
+	for _, task := range input.Body.Tasks {
+		summary.Progress.Total++
+		switch task.Status {
+		case TaskStatusNotStarted:
+			summary.Progress.NotStarted++
+		case TaskStatusInProgress:
+			summary.Progress.InProgress++
+		case TaskStatusInReview:
+			summary.Progress.InReview++
+		case TaskStatusCompleted:
+			summary.Progress.Completed++
+		}
+	}
+	return summary, true
+}
+
+// categoryQuery はカテゴリ配下を検索する修飾子を返します
+func categoryQuery(category string) (string, error) {
+	normalized, err := NormalizeCategory(category)
+	if err != nil {
+		return "", err
+	}
+	if strings.ContainsAny(normalized, "\"\\") {
+		return "", NewValidationError(ErrCodeCategoryInvalidPath, fmt.Sprintf("category cannot be used in a search query: %s", normalized)).
+			WithField("category")
+	}
+	return fmt.Sprintf("in:\"%s\"", normalized), nil
+}
+
+// listQueryForbiddenQualifiers は検索範囲を許可カテゴリの外に広げうる修飾子
+var listQueryForbiddenQualifiers = []string{"in:", "on:", "category:"}
+
+// validateListQuery は検索クエリが許可カテゴリの外を検索しないことを確認します
+func validateListQuery(query string) error {
+	lower := strings.ToLower(query)
+	for _, qualifier := range listQueryForbiddenQualifiers {
+		if strings.Contains(lower, qualifier) {
+			return NewValidationError(ErrCodeQueryNotAllowed,
+				fmt.Sprintf("query must not contain %s (use -category to narrow the search)", qualifier)).
+				WithField("query")
+		}
+	}
+	for _, token := range strings.Fields(query) {
+		if token == "OR" || strings.Contains(token, "|") {
+			return NewValidationError(ErrCodeQueryNotAllowed, "query must not contain OR").
+				WithField("query")
+		}
+	}
+	if containsControlCharacters(query) {
+		return NewValidationError(ErrCodeQueryNotAllowed, "query contains control characters").
+			WithField("query")
+	}
+	return nil
+}
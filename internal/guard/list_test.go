@@ -0,0 +1,137 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+// listedPost はガード管理記事の検索結果を返す
+func listedPost(number int, category string, completed int) esa.Post {
+	input := managedInput(number)
+	input.Category = category
+	for i := 0; i < completed; i++ {
+		input.Body.Tasks[i].Status = TaskStatusCompleted
+	}
+	bodyMD, err := GenerateMarkdownWithJSON(input)
+	if err != nil {
+		panic(err)
+	}
+	return esa.Post{Number: number, Name: input.Name, Category: category, BodyMD: bodyMD}
+}
+
+func TestList(t *testing.T) {
+	var queries []string
+	client := &mockEsaClientForExecute{
+		listPostsFunc: func(q string, page, perPage int) (*esa.PostList, error) {
+			queries = append(queries, q)
+			if page == 1 {
+				next := 2
+				return &esa.PostList{
+					Posts: []esa.Post{
+						listedPost(10, "LLM/Tasks/2026/01/28", 1),
+						{Number: 11, Name: "手書きの記事", Category: "LLM/Tasks/2026/01/28", BodyMD: "# memo"},
+					},
+					NextPage: &next,
+				}, nil
+			}
+			return &esa.PostList{
+				Posts: []esa.Post{
+					listedPost(12, "LLM/Tasks/2026/01/29", 2),
+					// 検索結果が許可カテゴリ外でも表示しない
+					listedPost(13, "LLM/Tasks-evil/2026/01/29", 0),
+				},
+			}, nil
+		},
+	}
+
+	summaries, err := List(context.Background(), ListOptions{Query: "API"}, NewPolicy([]string{"LLM/Tasks"}), client)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	if !slices.Equal(queries, []string{`in:"LLM/Tasks" API`, `in:"LLM/Tasks" API`}) {
+		t.Errorf("queries = %q", queries)
+	}
+	var numbers []int
+	for _, s := range summaries {
+		numbers = append(numbers, s.Number)
+	}
+	if !slices.Equal(numbers, []int{12, 10}) {
+		t.Fatalf("numbers = %v, want [12 10]", numbers)
+	}
+	if got := summaries[0].Progress; got.Total != 2 || got.Completed != 2 {
+		t.Errorf("progress of #12 = %+v", got)
+	}
+	if got := summaries[1].Progress; got.Completed != 1 || got.NotStarted != 1 {
+		t.Errorf("progress of #10 = %+v", got)
+	}
+}
+
+func TestList_Limit(t *testing.T) {
+	calls := 0
+	client := &mockEsaClientForExecute{
+		listPostsFunc: func(q string, page, perPage int) (*esa.PostList, error) {
+			calls++
+			next := page + 1
+			return &esa.PostList{
+				Posts:    []esa.Post{listedPost(page*10, "LLM/Tasks/2026/01/28", 0), listedPost(page*10+1, "LLM/Tasks/2026/01/28", 0)},
+				NextPage: &next,
+			}, nil
+		},
+	}
+
+	summaries, err := List(context.Background(), ListOptions{Limit: 3}, NewPolicy([]string{"LLM/Tasks"}), client)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(summaries) != 3 || calls != 2 {
+		t.Errorf("got %d posts with %d calls, want 3 posts with 2 calls", len(summaries), calls)
+	}
+}
+
+func TestList_RejectsEscapingQuery(t *testing.T) {
+	client := &mockEsaClientForExecute{
+		listPostsFunc: func(q string, page, perPage int) (*esa.PostList, error) {
+			t.Fatalf("ListPosts should not be called (q = %q)", q)
+			return nil, nil
+		},
+	}
+
+	tests := []struct {
+		name     string
+		opts     ListOptions
+		wantCode ValidationErrorCode
+	}{
+		{name: "in:修飾子", opts: ListOptions{Query: "in:Secret"}, wantCode: ErrCodeQueryNotAllowed},
+		{name: "否定のin:修飾子", opts: ListOptions{Query: "API -in:LLM/Tasks"}, wantCode: ErrCodeQueryNotAllowed},
+		{name: "on:修飾子（大文字）", opts: ListOptions{Query: "ON:Secret"}, wantCode: ErrCodeQueryNotAllowed},
+		{name: "category:修飾子", opts: ListOptions{Query: "category:Secret"}, wantCode: ErrCodeQueryNotAllowed},
+		{name: "OR", opts: ListOptions{Query: "API OR foo"}, wantCode: ErrCodeQueryNotAllowed},
+		{name: "パイプ", opts: ListOptions{Query: "API|foo"}, wantCode: ErrCodeQueryNotAllowed},
+		{name: "許可外のカテゴリで絞り込み", opts: ListOptions{Category: "Secret"}, wantCode: ErrCodeCategoryNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := List(context.Background(), tt.opts, NewPolicy([]string{"LLM/Tasks"}), client)
+			var ve *ValidationError
+			if !errors.As(err, &ve) || ve.Code() != tt.wantCode {
+				t.Errorf("List() error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestListPosts_Empty(t *testing.T) {
+	result, err := ListPosts(context.Background(), ListOptions{Category: "LLM/Tasks/sub"}, NewPolicy([]string{"LLM/Tasks"}), &mockEsaClientForExecute{})
+	if err != nil {
+		t.Fatalf("ListPosts() error = %v", err)
+	}
+	if result.Posts == nil || len(*result.Posts) != 0 {
+		t.Errorf("Posts = %v, want empty list", result.Posts)
+	}
+}
@@ -0,0 +1,208 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+// ErrorKind はJSON出力におけるエラーの種類を表す
+// ValidationErrorCode より粗い分類で、呼び出し側がリトライや通知の方針を決めるために使う
+type ErrorKind string
+
+const (
+	ErrorKindValidation ErrorKind = "validation"   // 入力JSONやカテゴリの検証エラー
+	ErrorKindConfig     ErrorKind = "config"       // 設定ファイル・環境変数のエラー
+	ErrorKindAPI        ErrorKind = "api"          // esa.io APIの呼び出しエラー（以下の4つ以外）
+	ErrorKindNotFound   ErrorKind = "not_found"    // esa.io APIが404を返した（記事がない）
+	ErrorKindAuth       ErrorKind = "unauthorized" // esa.io APIが401を返した（トークンが無効）
+	ErrorKindForbidden  ErrorKind = "forbidden"    // esa.io APIが403を返した（権限がない）
+	ErrorKindRateLimit  ErrorKind = "rate_limited" // esa.io APIが429を返した（リトライしても解消しなかった）
+	ErrorKindIO         ErrorKind = "io"           // ファイル入出力のエラー
+	ErrorKindContent    ErrorKind = "content"      // 既存記事の内容がガードの想定と異なる
+	ErrorKindConflict   ErrorKind = "conflict"     // 既存記事が最後に確認した後に更新されている
+	ErrorKindUsage      ErrorKind = "usage"        // コマンドライン引数のエラー
+	ErrorKindCanceled   ErrorKind = "canceled"     // シグナル（Ctrl-Cなど）で中断した
+	ErrorKindTimeout    ErrorKind = "timeout"      // 設定したコマンドのタイムアウトを過ぎた
+	ErrorKindInternal   ErrorKind = "internal"     // 上記以外
+)
+
+// KindError はエラーの種類を明示したエラー
+// メッセージは元のエラーのまま変えない
+type KindError struct {
+	kind ErrorKind
+	err  error
+}
+
+// WithKind はerrに種類を付与したエラーを返します（errがnilならnil）
+func WithKind(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &KindError{kind: kind, err: err}
+}
+
+func (e *KindError) Kind() ErrorKind { return e.kind }
+func (e *KindError) Error() string   { return e.err.Error() }
+func (e *KindError) Unwrap() error   { return e.err }
+
+// ErrorKindOf はerrの種類を判定します
+// 中断・タイムアウトとesa.io APIのステータス（404/401/403/429）を最優先し、次に明示的に付与された種類、
+// ValidationError、ファイルエラーの順で判定する
+func ErrorKindOf(err error) ErrorKind {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorKindCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorKindTimeout
+	case errors.Is(err, esa.ErrNotFound):
+		return ErrorKindNotFound
+	case errors.Is(err, esa.ErrUnauthorized):
+		return ErrorKindAuth
+	case errors.Is(err, esa.ErrForbidden):
+		return ErrorKindForbidden
+	case errors.Is(err, esa.ErrRateLimited):
+		return ErrorKindRateLimit
+	}
+
+	var ke *KindError
+	if errors.As(err, &ke) {
+		return ke.kind
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ErrorKindValidation
+	}
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return ErrorKindIO
+	}
+	return ErrorKindInternal
+}
+
+// Result は各コマンドの実行結果（JSON出力モードのエンベロープ）
+type Result struct {
+	Status          string         `json:"status"` // "ok" または "error"
+	Command         string         `json:"command"`
+	PostNumber      int            `json:"post_number,omitempty"`
+	URL             string         `json:"url,omitempty"`
+	Name            string         `json:"name,omitempty"`     // read の記事名
+	Category        string         `json:"category,omitempty"` // read の記事カテゴリ
+	Created         bool           `json:"created,omitempty"`
+	RevisionNumber  int            `json:"revision_number,omitempty"`
+	Adopted         bool           `json:"adopted,omitempty"`
+	EditedSections  []string       `json:"edited_sections,omitempty"` // esa上で人が編集していたセクション
+	EditsImported   bool           `json:"edits_imported,omitempty"`
+	ChangedTasks    []string       `json:"changed_tasks,omitempty"` // reconcile/task で変わったタスクのID
+	JSONFileUpdated bool           `json:"json_file_updated,omitempty"`
+	Markdown        string         `json:"markdown,omitempty"`
+	Diff            *string        `json:"diff,omitempty"` // 差分なしの場合は空文字列（post -adopt では置き換えた本文との差分）
+	JSON            *PostInput     `json:"json,omitempty"`
+	Posts           *[]PostSummary `json:"posts,omitempty"`   // list の結果（該当なしの場合は空配列）
+	AuditRecords    *[]AuditRecord `json:"records,omitempty"` // audit の結果（該当なしの場合は空配列）
+	Warnings        []string       `json:"warnings,omitempty"`
+	Error           *ErrorDetail   `json:"error,omitempty"`
+	Errors          []ErrorDetail  `json:"errors,omitempty"` // validate -all の全エラー
+}
+
+// ErrorDetail はJSON出力のエラー詳細
+// Code/Field/Index はValidationErrorの場合のみ設定される
+type ErrorDetail struct {
+	Kind    ErrorKind           `json:"kind"`
+	Code    ValidationErrorCode `json:"code,omitempty"`
+	Field   string              `json:"field,omitempty"`
+	Index   *int                `json:"index,omitempty"`
+	Message string              `json:"message"`
+	// HTTPStatus/APICode はesa.io APIのエラーの場合のみ設定される
+	HTTPStatus int    `json:"http_status,omitempty"`
+	APICode    string `json:"api_code,omitempty"`
+}
+
+// NewErrorDetail はerrからErrorDetailを作成します
+func NewErrorDetail(err error) ErrorDetail {
+	detail := ErrorDetail{
+		Kind:    ErrorKindOf(err),
+		Message: err.Error(),
+	}
+	var apiErr *esa.APIError
+	if errors.As(err, &apiErr) {
+		detail.HTTPStatus = apiErr.StatusCode
+		detail.APICode = apiErr.Code
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		detail.Code = ve.Code()
+		detail.Field = ve.Field()
+		if ve.Index() >= 0 {
+			index := ve.Index()
+			detail.Index = &index
+		}
+	}
+	return detail
+}
+
+// ErrorFromDetail はErrorDetailからエラーを復元します（daemonから受け取ったエラーなど）
+// メッセージと種類（終了コード）、ValidationErrorのコード・フィールド・インデックスを引き継ぐ
+func ErrorFromDetail(detail ErrorDetail) error {
+	err := errors.New(detail.Message)
+	if detail.Code != "" {
+		ve := NewValidationError(detail.Code, detail.Message).WithField(detail.Field)
+		if detail.Index != nil {
+			ve = ve.WithIndex(*detail.Index)
+		}
+		err = ve
+	}
+	if detail.Kind == "" {
+		return err
+	}
+	return WithKind(detail.Kind, err)
+}
+
+// ExitCode はエラーの種類に対応するプロセスの終了コードを返します
+// esa.io APIの404/401/403/429は呼び出し側が区別できるよう専用の終了コードにし、
+// タイムアウトは timeout(1) と同じ124、シグナルによる中断はシェルの慣習に合わせて130、それ以外は1
+func (k ErrorKind) ExitCode() int {
+	switch k {
+	case ErrorKindNotFound:
+		return 3
+	case ErrorKindAuth:
+		return 4
+	case ErrorKindForbidden:
+		return 5
+	case ErrorKindRateLimit:
+		return 6
+	case ErrorKindTimeout:
+		return 124
+	case ErrorKindCanceled:
+		return 130
+	default:
+		return 1
+	}
+}
+
+// NewErrorResult は失敗時の結果を作成します
+func NewErrorResult(command string, err error) *Result {
+	detail := NewErrorDetail(err)
+	return &Result{
+		Status:  "error",
+		Command: command,
+		Error:   &detail,
+	}
+}
+
+// NewValidationErrorsResult は複数のバリデーションエラーをまとめた結果を作成します
+// Errorには先頭のエラーを設定する
+func NewValidationErrorsResult(command string, errs []*ValidationError) *Result {
+	result := NewErrorResult(command, errs[0])
+	for _, ve := range errs {
+		result.Errors = append(result.Errors, NewErrorDetail(ve))
+	}
+	return result
+}
+
+// newResult は成功時の結果を作成します
+func newResult(command string) *Result {
+	return &Result{Status: "ok", Command: command}
+}
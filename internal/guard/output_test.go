@@ -0,0 +1,202 @@
+package guard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+func TestErrorKindOf(t *testing.T) {
+	_, statErr := os.Stat(filepath.Join(t.TempDir(), "missing.json"))
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"明示的な種類", WithKind(ErrorKindAPI, errors.New("boom")), ErrorKindAPI},
+		{"ラップされた明示的な種類", fmt.Errorf("outer: %w", WithKind(ErrorKindConfig, errors.New("boom"))), ErrorKindConfig},
+		{"ValidationError", NewValidationError(ErrCodeFieldEmpty, "name is required"), ErrorKindValidation},
+		{"ファイルエラー", statErr, ErrorKindIO},
+		{"その他", errors.New("boom"), ErrorKindInternal},
+		{"esa APIの404", WithKind(ErrorKindAPI, fmt.Errorf("failed to get post: %w", &esa.APIError{StatusCode: 404})), ErrorKindNotFound},
+		{"esa APIの401", WithKind(ErrorKindAPI, &esa.APIError{StatusCode: 401}), ErrorKindAuth},
+		{"esa APIの403", WithKind(ErrorKindAPI, &esa.APIError{StatusCode: 403}), ErrorKindForbidden},
+		{"リトライ後のesa APIの429", WithKind(ErrorKindAPI, fmt.Errorf("request failed after 3 attempts: %w", &esa.APIError{StatusCode: 429})), ErrorKindRateLimit},
+		{"esa APIのその他のエラー", WithKind(ErrorKindAPI, &esa.APIError{StatusCode: 500}), ErrorKindAPI},
+		{"中断", WithKind(ErrorKindAPI, fmt.Errorf("failed to get post: %w", context.Canceled)), ErrorKindCanceled},
+		{"タイムアウト", WithKind(ErrorKindAPI, fmt.Errorf("failed to create post: %w", context.DeadlineExceeded)), ErrorKindTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorKindOf(tt.err); got != tt.want {
+				t.Errorf("ErrorKindOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorKind_ExitCode(t *testing.T) {
+	tests := []struct {
+		kind ErrorKind
+		want int
+	}{
+		{ErrorKindValidation, 1},
+		{ErrorKindAPI, 1},
+		{ErrorKindNotFound, 3},
+		{ErrorKindAuth, 4},
+		{ErrorKindForbidden, 5},
+		{ErrorKindRateLimit, 6},
+		{ErrorKindTimeout, 124},
+		{ErrorKindCanceled, 130},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			if got := tt.kind.ExitCode(); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewErrorResult_APIError(t *testing.T) {
+	err := WithKind(ErrorKindAPI, fmt.Errorf("failed to get post: %w", &esa.APIError{StatusCode: 404, Code: "not_found", Message: "Not found"}))
+
+	data, marshalErr := json.Marshal(NewErrorResult("fetch", err))
+	if marshalErr != nil {
+		t.Fatalf("Marshal() error = %v", marshalErr)
+	}
+
+	want := `{"status":"error","command":"fetch","error":{"kind":"not_found","message":"failed to get post: API error (status 404, not_found): Not found","http_status":404,"api_code":"not_found"}}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+}
+
+func TestWithKind_Nil(t *testing.T) {
+	if err := WithKind(ErrorKindAPI, nil); err != nil {
+		t.Errorf("WithKind(nil) = %v, want nil", err)
+	}
+}
+
+func TestNewErrorResult_ValidationError(t *testing.T) {
+	err := fmt.Errorf("validation failed: %w",
+		NewValidationError(ErrCodeTaskTitleInvalidPrefix, "invalid title").WithField("task.title").WithIndex(1))
+
+	data, marshalErr := json.Marshal(NewErrorResult("validate", err))
+	if marshalErr != nil {
+		t.Fatalf("Marshal() error = %v", marshalErr)
+	}
+
+	want := `{"status":"error","command":"validate","error":{"kind":"validation","code":"task_title_invalid_prefix","field":"task.title","index":1,"message":"validation failed: invalid title"}}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+}
+
+func TestNewErrorResult_IndexZeroIsKept(t *testing.T) {
+	result := NewErrorResult("validate", NewValidationError(ErrCodeFieldEmpty, "id is required").WithField("task.id").WithIndex(0))
+	if result.Error.Index == nil || *result.Error.Index != 0 {
+		t.Errorf("Index = %v, want 0", result.Error.Index)
+	}
+}
+
+func TestErrorFromDetail(t *testing.T) {
+	// ErrorDetailを経由しても同じエラー詳細・終了コードになる
+	errs := []error{
+		fmt.Errorf("validation failed: %w",
+			NewValidationError(ErrCodeTaskTitleInvalidPrefix, "invalid title").WithField("task.title").WithIndex(1)),
+		fmt.Errorf("failed to get post: %w", &esa.APIError{StatusCode: 404, Code: "not_found", Message: "Not found"}),
+		WithKind(ErrorKindConflict, fmt.Errorf("post 1 was updated")),
+	}
+	for _, err := range errs {
+		detail := NewErrorDetail(err)
+		restored := ErrorFromDetail(detail)
+		if restored.Error() != err.Error() || ErrorKindOf(restored) != ErrorKindOf(err) {
+			t.Errorf("ErrorFromDetail(%+v) = %v (kind %s)", detail, restored, ErrorKindOf(restored))
+		}
+		got := NewErrorDetail(restored)
+		if got.Code != detail.Code || got.Field != detail.Field || (got.Index == nil) != (detail.Index == nil) {
+			t.Errorf("detail = %+v, want %+v", got, detail)
+		}
+	}
+}
+
+func TestNewValidationErrorsResult(t *testing.T) {
+	errs := []*ValidationError{
+		NewValidationError(ErrCodeFieldEmpty, "name is required").WithField("name"),
+		NewValidationError(ErrCodeCategoryEmpty, "category is required").WithField("category"),
+	}
+	result := NewValidationErrorsResult("validate", errs)
+
+	if result.Status != "error" {
+		t.Errorf("Status = %v, want error", result.Status)
+	}
+	if result.Error.Code != ErrCodeFieldEmpty {
+		t.Errorf("Error.Code = %v, want %v", result.Error.Code, ErrCodeFieldEmpty)
+	}
+	if len(result.Errors) != 2 {
+		t.Fatalf("len(Errors) = %d, want 2", len(result.Errors))
+	}
+	if result.Errors[1].Field != "category" {
+		t.Errorf("Errors[1].Field = %v, want category", result.Errors[1].Field)
+	}
+}
+
+func TestPostFile_CreateResult(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonPath := filepath.Join(tmpDir, "test.json")
+	jsonContent := `{
+  "create_new": true,
+  "name": "Test Post",
+  "category": "Claude Code/開発日誌/2026/01/28",
+  "body": {
+    "background": "Test background",
+    "tasks": [{"id": "task-1", "title": "Task 1: Test", "status": "not_started", "summary": ["Test"], "description": "Test"}]
+  }
+}`
+	if err := os.WriteFile(jsonPath, []byte(jsonContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &mockEsaClientForExecute{
+		createPostFunc: func(input *esa.PostInput) (*esa.Post, error) {
+			return &esa.Post{Number: 77, URL: "https://example.esa.io/posts/77"}, nil
+		},
+	}
+
+	result, err := PostFile(context.Background(), jsonPath, NewPolicy([]string{"Claude Code/開発日誌"}), client, PostOptions{})
+	if err != nil {
+		t.Fatalf("PostFile() error = %v", err)
+	}
+	if result.Status != "ok" || result.Command != "post" {
+		t.Errorf("Status/Command = %v/%v, want ok/post", result.Status, result.Command)
+	}
+	if result.PostNumber != 77 || !result.Created || !result.JSONFileUpdated {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestPostFile_CategoryNotAllowedKind(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonPath := filepath.Join(tmpDir, "test.json")
+	jsonContent := `{"create_new":true,"name":"Test","category":"Other/2026/01/28","body":{"background":"b","tasks":[{"id":"task-1","title":"Task 1: Test","status":"not_started","summary":["s"],"description":"d"}]}}`
+	if err := os.WriteFile(jsonPath, []byte(jsonContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := PostFile(context.Background(), jsonPath, NewPolicy([]string{"Claude Code/開発日誌"}), &mockEsaClientForExecute{}, PostOptions{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	detail := NewErrorDetail(err)
+	if detail.Kind != ErrorKindValidation || detail.Code != ErrCodeCategoryNotAllowed {
+		t.Errorf("detail = %+v, want validation/category_not_allowed", detail)
+	}
+}
@@ -0,0 +1,195 @@
+package guard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+// patchForbiddenFields はパッチで変更できないフィールド
+// 記事の移動や別記事の上書き、ガードが管理する値の書き換えを防ぐ
+var patchForbiddenFields = []string{"category", "post_number", "create_new", "revision_number"}
+
+// ExecutePatch は記事の埋め込みJSONにパッチを適用して記事を更新します
+func ExecutePatch(ctx context.Context, postNumber int, patchPath string, policy *Policy, client esa.EsaClientInterface, opts PostOptions) error {
+	result, err := PatchFile(ctx, postNumber, patchPath, policy, client, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated post: %s (Number: %d)\n", result.URL, result.PostNumber)
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return nil
+}
+
+// PatchFile はファイル（"-" の場合は標準入力）のパッチを記事に適用し、実行結果を返します
+func PatchFile(ctx context.Context, postNumber int, patchPath string, policy *Policy, client esa.EsaClientInterface, opts PostOptions) (*Result, error) {
+	patch, err := ReadPatch(patchPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch: %w", err)
+	}
+
+	postResult, patched, err := Patch(ctx, postNumber, patch, policy, client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newResult("patch")
+	result.PostNumber = postResult.Post.Number
+	result.URL = postResult.Post.URL
+	result.RevisionNumber = postResult.Post.RevisionNumber
+	result.Warnings = append(result.Warnings, postResult.Warnings...)
+	result.JSON = patched
+	if postResult.HumanEdits != nil {
+		result.EditedSections = postResult.HumanEdits.Sections
+		if postResult.Imported != nil {
+			result.EditsImported = true
+		} else {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("overwrote edits made in esa: %s", strings.Join(postResult.HumanEdits.Sections, ", ")))
+		}
+	}
+	return result, nil
+}
+
+// ReadPatch はパッチを読み込みます（"-" の場合は標準入力から読む）
+func ReadPatch(path string) ([]byte, error) {
+	var r io.Reader
+	if path == "-" {
+		r = os.Stdin
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+
+		fileInfo, err := file.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat file: %w", err)
+		}
+		if !fileInfo.Mode().IsRegular() {
+			return nil, NewValidationError(ErrCodeNotRegularFile, fmt.Sprintf("file is not a regular file: %s", path))
+		}
+		r = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxInputSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxInputSize {
+		return nil, NewValidationError(ErrCodeFileSizeExceeded, "patch size exceeds 10MB")
+	}
+	return data, nil
+}
+
+// Patch は記事を取得して埋め込みJSONにパッチ（RFC 6902 JSON Patch または RFC 7386 Merge Patch）を適用し、
+// postコマンドと同じバリデーションとカテゴリチェックを通して記事を更新します。
+// category と post_number などガードが管理するフィールドに触れるパッチは拒否します。
+// 戻り値の2つ目はパッチ適用後の入力です。
+func Patch(ctx context.Context, postNumber int, patch []byte, policy *Policy, client esa.EsaClientInterface, opts PostOptions) (*PostResult, *PostInput, error) {
+	// 1. パッチの形式を判定し、触れるフィールドを検査（記事を取得する前に拒否する）
+	patchType, err := DetectPatchType(patch)
+	if err != nil {
+		return nil, nil, err
+	}
+	fields, err := patchTouchedFields(patchType, patch)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, field := range fields {
+		if field == "" {
+			return nil, nil, NewValidationError(ErrCodePatchForbiddenField, "patch must not replace the whole document").
+				WithField("patch")
+		}
+		for _, forbidden := range patchForbiddenFields {
+			if field == forbidden {
+				return nil, nil, NewValidationError(ErrCodePatchForbiddenField, fmt.Sprintf("patch must not modify %s", field)).
+					WithField(field)
+			}
+		}
+	}
+
+	// 2. 記事を取得し、埋め込みJSONにパッチを適用して更新
+	return editPost(ctx, postNumber, policy, client, opts, func(current *PostInput) (*PostInput, error) {
+		data, err := json.Marshal(current)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal embedded JSON: %w", err)
+		}
+		doc, err := decodeJSONValue(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode embedded JSON: %w", err)
+		}
+		switch patchType {
+		case PatchTypeJSONPatch:
+			doc, err = applyJSONPatch(doc, patch)
+		case PatchTypeMergePatch:
+			doc, err = applyMergePatch(doc, patch)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		patchedData, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal patched JSON: %w", err)
+		}
+		return DecodePostInput(patchedData)
+	})
+}
+
+// editPost は記事の埋め込みJSONを取得してeditで変更し、postコマンドと同じ経路で記事を更新します。
+// 取得時のリビジョンに対して更新するため、その間に記事が変更されていれば revision_conflict になります。
+// 戻り値の2つ目は変更後の入力です。
+func editPost(ctx context.Context, postNumber int, policy *Policy, client esa.EsaClientInterface, opts PostOptions, edit func(current *PostInput) (*PostInput, error)) (*PostResult, *PostInput, error) {
+	// 1. 記事を取得して埋め込みJSONを取り出す（取得時のリビジョンを記録）
+	// 書き込み対象なので、読み取りを拒否した場合は書き込みの拒否として返す（更新の権限は Post で確認する）
+	current, err := Fetch(ctx, postNumber, policy, client)
+	if errors.Is(err, ErrReadNotAllowed) {
+		return nil, nil, NewValidationError(ErrCodeCategoryNotAllowed, fmt.Sprintf("post %d is not in an allowed category: %v", postNumber, err)).
+			WithField("category")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	revision := current.RevisionNumber
+	current.RevisionNumber = nil
+	category := current.Category
+
+	// 2. 変更
+	edited, err := edit(current)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 3. 念のため、管理フィールドが変わっていないことを確認（fail closed）
+	if edited.Category != category || edited.CreateNew ||
+		edited.PostNumber == nil || *edited.PostNumber != postNumber || edited.RevisionNumber != nil {
+		return nil, nil, NewValidationError(ErrCodePatchForbiddenField, "category and post_number cannot be changed")
+	}
+
+	// 4. バリデーション
+	TrimPostInput(edited)
+	if err := ValidatePostInputSchema(edited); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidatePostInput(edited); err != nil {
+		return nil, nil, err
+	}
+
+	// 5. 取得時のリビジョンを付けて、postと同じ経路（カテゴリチェック含む）で更新
+	edited.RevisionNumber = revision
+	postResult, err := Post(ctx, edited, policy, client, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return postResult, edited, nil
+}
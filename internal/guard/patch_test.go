@@ -0,0 +1,267 @@
+package guard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "replace",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"replace","path":"/a","value":2}]`,
+			want:  `{"a":2}`,
+		},
+		{
+			name:  "配列の末尾に追加",
+			doc:   `{"a":[1,2]}`,
+			patch: `[{"op":"add","path":"/a/-","value":3}]`,
+			want:  `{"a":[1,2,3]}`,
+		},
+		{
+			name:  "配列の途中に挿入",
+			doc:   `{"a":[1,3]}`,
+			patch: `[{"op":"add","path":"/a/1","value":2}]`,
+			want:  `{"a":[1,2,3]}`,
+		},
+		{
+			name:  "remove",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"remove","path":"/a/0"}]`,
+			want:  `{"a":[2,3]}`,
+		},
+		{
+			name:  "move",
+			doc:   `{"a":1,"b":{}}`,
+			patch: `[{"op":"move","from":"/a","path":"/b/c"}]`,
+			want:  `{"b":{"c":1}}`,
+		},
+		{
+			name:  "copy",
+			doc:   `{"a":[1]}`,
+			patch: `[{"op":"copy","from":"/a","path":"/b"}]`,
+			want:  `{"a":[1],"b":[1]}`,
+		},
+		{
+			name:  "エスケープされたポインタ",
+			doc:   `{"a/b":1,"c~d":2}`,
+			patch: `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/c~0d"}]`,
+			want:  `{"a/b":3}`,
+		},
+		{
+			name:  "testが成功すれば続行",
+			doc:   `{"a":"x"}`,
+			patch: `[{"op":"test","path":"/a","value":"x"},{"op":"replace","path":"/a","value":"y"}]`,
+			want:  `{"a":"y"}`,
+		},
+		{
+			name:    "testが失敗",
+			doc:     `{"a":"x"}`,
+			patch:   `[{"op":"test","path":"/a","value":"z"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "存在しないパスのremove",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"remove","path":"/b"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "範囲外のインデックス",
+			doc:     `{"a":[1]}`,
+			patch:   `[{"op":"replace","path":"/a/1","value":2}]`,
+			wantErr: true,
+		},
+		{
+			name:    "未知のop",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"merge","path":"/a","value":2}]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := decodeJSONValue([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := applyJSONPatch(doc, []byte(tt.patch))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("applyJSONPatch() expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyJSONPatch() error = %v", err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			if string(gotJSON) != tt.want {
+				t.Errorf("applyJSONPatch() = %s, want %s", gotJSON, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	doc, err := decodeJSONValue([]byte(`{"a":"b","c":{"d":"e","f":"g"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := applyMergePatch(doc, []byte(`{"a":"z","c":{"f":null}}`))
+	if err != nil {
+		t.Fatalf("applyMergePatch() error = %v", err)
+	}
+	gotJSON, _ := json.Marshal(got)
+	if want := `{"a":"z","c":{"d":"e"}}`; string(gotJSON) != want {
+		t.Errorf("applyMergePatch() = %s, want %s", gotJSON, want)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	var updated string
+	client := reconcileClient(managedBody(123), &updated)
+
+	patch := `[{"op":"replace","path":"/body/tasks/1/status","value":"completed"},{"op":"replace","path":"/body/background","value":"New background"}]`
+	result, patched, err := Patch(context.Background(), 123, []byte(patch), NewPolicy([]string{"Claude Code/開発日誌"}), client, PostOptions{})
+	if err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+
+	if patched.Body.Tasks[1].Status != TaskStatusCompleted {
+		t.Errorf("task-2 status = %v, want completed", patched.Body.Tasks[1].Status)
+	}
+	if patched.RevisionNumber == nil || *patched.RevisionNumber != 3 {
+		t.Errorf("RevisionNumber = %v, want 3 (the fetched revision)", patched.RevisionNumber)
+	}
+	if result.Post.RevisionNumber != 4 {
+		t.Errorf("Post.RevisionNumber = %d, want 4", result.Post.RevisionNumber)
+	}
+	if !strings.Contains(updated, "New background") || !strings.Contains(updated, "- [x] Task 2: Second task") {
+		t.Errorf("updated body does not contain the patched content:\n%s", updated)
+	}
+}
+
+func TestPatch_MergePatch(t *testing.T) {
+	var updated string
+	client := reconcileClient(managedBody(123), &updated)
+
+	patch := `{"name":"Renamed","body":{"related_links":["https://example.com"]}}`
+	_, patched, err := Patch(context.Background(), 123, []byte(patch), NewPolicy([]string{"Claude Code/開発日誌"}), client, PostOptions{})
+	if err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if patched.Name != "Renamed" {
+		t.Errorf("Name = %q, want Renamed", patched.Name)
+	}
+	if len(patched.Body.Tasks) != 2 {
+		t.Errorf("merge patch should keep tasks, got %d", len(patched.Body.Tasks))
+	}
+	if !strings.Contains(updated, "https://example.com") {
+		t.Errorf("updated body does not contain the related link:\n%s", updated)
+	}
+}
+
+func TestPatch_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		wantCode ValidationErrorCode
+	}{
+		{
+			name:     "JSON Patchでcategoryを変更",
+			patch:    `[{"op":"replace","path":"/category","value":"Claude Code/開発日誌/2026/02/01"}]`,
+			wantCode: ErrCodePatchForbiddenField,
+		},
+		{
+			name:     "JSON Patchでpost_numberを削除",
+			patch:    `[{"op":"remove","path":"/post_number"}]`,
+			wantCode: ErrCodePatchForbiddenField,
+		},
+		{
+			name:     "moveでcategoryを移動元にする",
+			patch:    `[{"op":"move","from":"/category","path":"/name"}]`,
+			wantCode: ErrCodePatchForbiddenField,
+		},
+		{
+			name:     "ルートを置換",
+			patch:    `[{"op":"replace","path":"","value":{}}]`,
+			wantCode: ErrCodePatchForbiddenField,
+		},
+		{
+			name:     "Merge Patchでcategoryを変更",
+			patch:    `{"category":"Other/2026/01/28"}`,
+			wantCode: ErrCodePatchForbiddenField,
+		},
+		{
+			name:     "Merge Patchでpost_numberを変更",
+			patch:    `{"post_number":1}`,
+			wantCode: ErrCodePatchForbiddenField,
+		},
+		{
+			name:     "Merge Patchでcreate_newを設定",
+			patch:    `{"create_new":true}`,
+			wantCode: ErrCodePatchForbiddenField,
+		},
+		{
+			name:     "配列でもオブジェクトでもない",
+			patch:    `"body"`,
+			wantCode: ErrCodeJSONInvalid,
+		},
+		{
+			name:     "パッチ適用後のバリデーションエラー",
+			patch:    `[{"op":"replace","path":"/body/tasks/0/status","value":"done"}]`,
+			wantCode: ErrCodeInvalidValue,
+		},
+		{
+			name:     "未知のフィールドを追加",
+			patch:    `{"unknown":1}`,
+			wantCode: ErrCodeJSONInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated string
+			client := reconcileClient(managedBody(123), &updated)
+			_, _, err := Patch(context.Background(), 123, []byte(tt.patch), NewPolicy([]string{"Claude Code/開発日誌"}), client, PostOptions{})
+			if err == nil {
+				t.Fatal("Patch() expected error")
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected ValidationError, got %T: %v", err, err)
+			}
+			if ve.Code() != tt.wantCode {
+				t.Errorf("code = %v, want %v (%v)", ve.Code(), tt.wantCode, err)
+			}
+			if updated != "" {
+				t.Error("UpdatePost should not be called")
+			}
+		})
+	}
+}
+
+func TestPatch_CategoryNotAllowed(t *testing.T) {
+	var updated string
+	client := reconcileClient(managedBody(123), &updated)
+
+	_, _, err := Patch(context.Background(), 123, []byte(`{"name":"Renamed"}`), NewPolicy([]string{"LLM/Tasks"}), client, PostOptions{})
+	if !errors.Is(err, ErrCategoryNotAllowed) {
+		t.Errorf("Patch() error = %v, want category_not_allowed", err)
+	}
+	if updated != "" {
+		t.Error("UpdatePost should not be called")
+	}
+}
@@ -0,0 +1,245 @@
+package guard
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Operation はカテゴリに対する操作
+type Operation string
+
+const (
+	OperationRead   Operation = "read"
+	OperationCreate Operation = "create"
+	OperationUpdate Operation = "update"
+	OperationDelete Operation = "delete"
+)
+
+// Operations は操作の一覧（設定で指定できる順）
+var Operations = []Operation{OperationRead, OperationCreate, OperationUpdate, OperationDelete}
+
+// ParseOperation は設定に書かれた操作名を解釈します
+func ParseOperation(value string) (Operation, error) {
+	op := Operation(value)
+	if !slices.Contains(Operations, op) {
+		return "", NewValidationError(ErrCodeInvalidValue, fmt.Sprintf("invalid operation %q (must be read, create, update or delete)", value))
+	}
+	return op, nil
+}
+
+// 設定でルールを定めるキー（エラーメッセージでどのルールで判定したかを示す）
+const (
+	RuleKeyAllowedCategories   = "allowed_categories"
+	RuleKeyReadableCategories  = "readable_categories"
+	RuleKeyCategoryPermissions = "category_permissions"
+	RuleKeyDeniedCategories    = "denied_categories"
+)
+
+// Rule はカテゴリ（配下のカテゴリを含む）に対して許可する操作のルール
+type Rule struct {
+	// Key はルールを定めた設定のキー（allowed_categories など）
+	Key string
+	// Category はルールを適用するカテゴリ
+	Category string
+	// Operations は許可する操作（denied_categories のルールは空）
+	Operations []Operation
+	// File はルールを定めた設定ファイル（任意、エラーメッセージに使う）
+	File string
+}
+
+// String はルールを `allowed_categories "LLM/Tasks"` の形式で返します
+func (r *Rule) String() string {
+	return fmt.Sprintf("%s %q", r.Key, r.Category)
+}
+
+// permits はルールが操作を許可しているかを返します
+func (r *Rule) permits(op Operation) bool {
+	return slices.Contains(r.Operations, op)
+}
+
+// Policy はカテゴリごとに許可する操作を判定するポリシー
+// grants はいずれかが操作を許可すれば許可し（allowed_categories・readable_categories）、
+// limits は当てはまるもののうち1つでも操作を許可しなければ拒否する（category_permissions・denied_categories）。
+// 拒否の理由には、操作を許可しない limits のうち最も深いカテゴリのものを使う。
+// limits は許可を狭めることしかできないため、grants の外に広がることはない。nil のポリシーは何も許可しない
+type Policy struct {
+	grants []Rule
+	limits []Rule
+}
+
+// NewPolicy は allowedCategories 配下のすべての操作を許可するポリシーを作成します
+func NewPolicy(allowedCategories []string) *Policy {
+	p := &Policy{}
+	for _, category := range allowedCategories {
+		p.Grant(Rule{Key: RuleKeyAllowedCategories, Category: category, Operations: Operations})
+	}
+	return p
+}
+
+// Grant は操作を許可するルールを追加します
+func (p *Policy) Grant(rule Rule) *Policy {
+	p.grants = append(p.grants, rule)
+	return p
+}
+
+// Limit は許可を狭めるルールを追加します（Operations が空なら配下のすべての操作を拒否する）
+func (p *Policy) Limit(rule Rule) *Policy {
+	p.limits = append(p.limits, rule)
+	return p
+}
+
+// AllowRead は categories 配下の読み取りだけを許可します
+func (p *Policy) AllowRead(categories ...string) *Policy {
+	for _, category := range categories {
+		p.Grant(Rule{Key: RuleKeyReadableCategories, Category: category, Operations: []Operation{OperationRead}})
+	}
+	return p
+}
+
+// Permit は category 配下で許可する操作を ops に限ります
+func (p *Policy) Permit(category string, ops ...Operation) *Policy {
+	return p.Limit(Rule{Key: RuleKeyCategoryPermissions, Category: category, Operations: ops})
+}
+
+// Deny は categories 配下のすべての操作を拒否します
+func (p *Policy) Deny(categories ...string) *Policy {
+	for _, category := range categories {
+		p.Limit(Rule{Key: RuleKeyDeniedCategories, Category: category})
+	}
+	return p
+}
+
+// WritableCategories は作成・更新・削除のいずれかを許可する grants のカテゴリを返します
+// limits は考慮しないため、返したカテゴリ（やその配下）でも denied_categories などで書き込めないことがある。
+// list の検索範囲や、書き込めるカテゴリがまったくない場合に早く拒否する事前チェックに使い、
+// 個々の操作を許可するかは必ず Evaluate・Check で判定する
+func (p *Policy) WritableCategories() []string {
+	if p == nil {
+		return nil
+	}
+	var categories []string
+	for _, rule := range p.grants {
+		if rule.permits(OperationCreate) || rule.permits(OperationUpdate) || rule.permits(OperationDelete) {
+			categories = append(categories, rule.Category)
+		}
+	}
+	return categories
+}
+
+// Decision はポリシーの判定結果
+type Decision struct {
+	// Allowed は操作が許可されたかどうか
+	Allowed bool
+	// Operation は判定した操作
+	Operation Operation
+	// Rule は判定を決めたルール（当てはまるルールがなく拒否した場合は nil）
+	Rule *Rule
+}
+
+// Reason は判定の理由を返します（エラーメッセージやフックの判定理由に使う）
+func (d Decision) Reason() string {
+	var reason string
+	switch {
+	case d.Rule == nil:
+		return fmt.Sprintf("no rule permits %s", d.Operation)
+	case d.Allowed:
+		reason = fmt.Sprintf("%s is allowed by %s", d.Operation, d.Rule)
+	case len(d.Rule.Operations) == 0:
+		reason = fmt.Sprintf("denied by %s", d.Rule)
+	default:
+		reason = fmt.Sprintf("%s permits only %s", d.Rule, joinOperations(d.Rule.Operations))
+	}
+	if d.Rule.File != "" {
+		reason += " in " + d.Rule.File
+	}
+	return reason
+}
+
+// Evaluate はカテゴリに対する操作を判定し、判定を決めたルールを返します。
+// 境界チェックにより、"LLM/Tasks" のルールは "LLM/Tasks-evil" に当てはまりません。
+// limits のうち当てはまり操作を許可しないもの（最も深いもの）があれば拒否し、
+// なければ grants のうち操作を許可するもの（最も深いもの）で許可します。
+func (p *Policy) Evaluate(category string, op Operation) (Decision, error) {
+	normalized, err := NormalizeCategory(category)
+	if err != nil {
+		return Decision{Operation: op}, err
+	}
+	if p == nil {
+		return Decision{Operation: op}, nil
+	}
+
+	limit, err := deepestRule(normalized, p.limits, func(r *Rule) bool { return !r.permits(op) })
+	if err != nil {
+		return Decision{Operation: op}, err
+	}
+	if limit != nil {
+		return Decision{Operation: op, Rule: limit}, nil
+	}
+
+	grant, err := deepestRule(normalized, p.grants, func(r *Rule) bool { return r.permits(op) })
+	if err != nil {
+		return Decision{Operation: op}, err
+	}
+	if grant != nil {
+		return Decision{Allowed: true, Operation: op, Rule: grant}, nil
+	}
+
+	// 操作を許可しないルールだけが当てはまる場合は、そのルールを理由にする（readable_categories など）
+	grant, err = deepestRule(normalized, p.grants, func(*Rule) bool { return true })
+	if err != nil {
+		return Decision{Operation: op}, err
+	}
+	return Decision{Operation: op, Rule: grant}, nil
+}
+
+// Check はカテゴリに対する操作が許可されているかを検証します。
+// 許可されていなければ、判定を決めたルールを含む category_not_allowed エラーを返します（subjectはメッセージの主語）
+func (p *Policy) Check(subject, category string, op Operation) error {
+	decision, err := p.Evaluate(category, op)
+	if err != nil {
+		return NewValidationError(ErrCodeCategoryNotAllowed, fmt.Sprintf("%s validation failed: %v", subject, err)).
+			WithField("category").Wrap(err)
+	}
+	if !decision.Allowed {
+		return NewValidationError(ErrCodeCategoryNotAllowed, fmt.Sprintf("%s %s is not allowed (%s)", subject, category, decision.Reason())).
+			WithField("category")
+	}
+	return nil
+}
+
+// deepestRule はcategoryに当てはまり match を満たすルールのうち、最も深いカテゴリのルールを返します
+func deepestRule(category string, rules []Rule, match func(*Rule) bool) (*Rule, error) {
+	var deepest *Rule
+	for i := range rules {
+		rule := &rules[i]
+		// ルールのカテゴリも正規化（設定ファイルから読み込まれた値も検証）
+		ruleCategory, err := NormalizeCategory(rule.Category)
+		if err != nil {
+			return nil, NewValidationError(ErrCodeCategoryInvalidPath, fmt.Sprintf("invalid category in %s: %v", rule, err)).
+				Wrap(err)
+		}
+		if !isUnderCategory(category, ruleCategory) || !match(rule) {
+			continue
+		}
+		if deepest == nil || len(ruleCategory) > len(deepest.Category) {
+			deepest = rule
+		}
+	}
+	return deepest, nil
+}
+
+// isUnderCategory はcategoryがparentそのものか、その配下かを返します
+// "LLM/Tasks" は "LLM/Tasks/sub" に当てはまるが、"LLM/Tasks-evil" には当てはまらない
+func isUnderCategory(category, parent string) bool {
+	return category == parent || strings.HasPrefix(category, parent+"/")
+}
+
+// joinOperations は操作の一覧を "create, update" の形式で返します
+func joinOperations(ops []Operation) string {
+	names := make([]string, len(ops))
+	for i, op := range ops {
+		names[i] = string(op)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,122 @@
+package guard
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPolicy_Evaluate(t *testing.T) {
+	policy := NewPolicy([]string{"LLM", "LLM/Tasks"}).
+		AllowRead("Docs").
+		Permit("LLM/Docs", OperationRead, OperationUpdate).
+		Deny("LLM/Secrets")
+
+	tests := []struct {
+		name     string
+		policy   *Policy
+		category string
+		op       Operation
+		want     bool
+		wantRule string // 空なら当てはまるルールなし
+	}{
+		{"最も深い許可ルール", policy, "LLM/Tasks/2026", OperationCreate, true, `allowed_categories "LLM/Tasks"`},
+		{"境界を越えるカテゴリ", policy, "LLM-evil", OperationCreate, false, ""},
+		{"拒否カテゴリそのもの", policy, "LLM/Secrets", OperationUpdate, false, `denied_categories "LLM/Secrets"`},
+		{"拒否カテゴリの配下", policy, "LLM/Secrets/keys", OperationRead, false, `denied_categories "LLM/Secrets"`},
+		{"拒否カテゴリの境界の外", policy, "LLM/Secrets-public", OperationCreate, true, `allowed_categories "LLM"`},
+		{"操作を限ったカテゴリで許可した操作", policy, "LLM/Docs/api", OperationUpdate, true, `allowed_categories "LLM"`},
+		{"操作を限ったカテゴリで許可していない操作", policy, "LLM/Docs/api", OperationDelete, false, `category_permissions "LLM/Docs"`},
+		{"読み取り専用カテゴリの読み取り", policy, "Docs/Design", OperationRead, true, `readable_categories "Docs"`},
+		{"読み取り専用カテゴリへの書き込み", policy, "Docs/Design", OperationCreate, false, `readable_categories "Docs"`},
+		{"深い制限が許可しても浅い制限が許可しなければ拒否", NewPolicy([]string{"LLM"}).Permit("LLM", OperationRead).Permit("LLM/Tasks", OperationCreate), "LLM/Tasks/2026", OperationCreate, false, `category_permissions "LLM"`},
+		{"当てはまる制限のうち許可しない最も深いもので拒否", NewPolicy([]string{"LLM"}).Deny("LLM").Permit("LLM/Tasks", OperationRead), "LLM/Tasks", OperationCreate, false, `category_permissions "LLM/Tasks"`},
+		{"許可のない操作の制限は広げない", NewPolicy(nil).Permit("LLM", OperationCreate), "LLM", OperationCreate, false, ""},
+		{"nilのポリシー", nil, "LLM/Tasks", OperationRead, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := tt.policy.Evaluate(tt.category, tt.op)
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if decision.Allowed != tt.want {
+				t.Errorf("Allowed = %v, want %v (%s)", decision.Allowed, tt.want, decision.Reason())
+			}
+			got := ""
+			if decision.Rule != nil {
+				got = decision.Rule.String()
+			}
+			if got != tt.wantRule {
+				t.Errorf("Rule = %s, want %s", got, tt.wantRule)
+			}
+		})
+	}
+
+	t.Run("不正なカテゴリ", func(t *testing.T) {
+		if _, err := policy.Evaluate("LLM/../Secrets", OperationRead); err == nil {
+			t.Error("Evaluate() error = nil, want error")
+		}
+	})
+}
+
+func TestPolicy_Check(t *testing.T) {
+	policy := NewPolicy([]string{"LLM"}).Deny("LLM/Secrets")
+	policy.Limit(Rule{Key: RuleKeyCategoryPermissions, Category: "LLM/Inbox", Operations: []Operation{OperationCreate}, File: "/home/me/config.yaml"})
+
+	tests := []struct {
+		name       string
+		category   string
+		op         Operation
+		wantReason string // 空なら許可
+	}{
+		{"許可", "LLM/Tasks", OperationCreate, ""},
+		{"拒否ルール", "LLM/Secrets/keys", OperationCreate, `denied by denied_categories "LLM/Secrets"`},
+		{"操作の制限", "LLM/Inbox", OperationUpdate, `category_permissions "LLM/Inbox" permits only create in /home/me/config.yaml`},
+		{"当てはまるルールなし", "Private", OperationRead, "no rule permits read"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check("category", tt.category, tt.op)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || ve.Code() != ErrCodeCategoryNotAllowed {
+				t.Fatalf("Check() error = %v, want category_not_allowed", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantReason) {
+				t.Errorf("Check() error = %v, want reason %q", err, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestPolicy_WritableCategories(t *testing.T) {
+	// limits は考慮しないため、拒否カテゴリも書き込みを許可する grants のカテゴリとして返す
+	policy := NewPolicy([]string{"LLM", "LLM/Secrets"}).AllowRead("Docs").Deny("LLM/Secrets")
+	got := policy.WritableCategories()
+	if want := []string{"LLM", "LLM/Secrets"}; !slices.Equal(got, want) {
+		t.Errorf("WritableCategories() = %v, want %v", got, want)
+	}
+	if got := (*Policy)(nil).WritableCategories(); got != nil {
+		t.Errorf("WritableCategories() of nil policy = %v, want nil", got)
+	}
+}
+
+func TestParseOperation(t *testing.T) {
+	for _, value := range []string{"read", "create", "update", "delete"} {
+		if op, err := ParseOperation(value); err != nil || string(op) != value {
+			t.Errorf("ParseOperation(%q) = %q, %v", value, op, err)
+		}
+	}
+	for _, value := range []string{"", "move", "Read"} {
+		if _, err := ParseOperation(value); err == nil {
+			t.Errorf("ParseOperation(%q) error = nil, want error", value)
+		}
+	}
+}
@@ -6,27 +6,43 @@ import (
 
 // ExecutePreview は生成されるMarkdownを標準出力に出力する。
 func ExecutePreview(jsonPath string) error {
-	input, err := ReadPostInputFromFile(jsonPath)
+	result, err := PreviewFile(jsonPath)
 	if err != nil {
-		return fmt.Errorf("failed to read JSON file: %w", err)
+		return err
 	}
+	fmt.Print(result.Markdown)
 
-	TrimPostInput(input)
+	return nil
+}
 
-	if err := ValidatePostInputSchema(input); err != nil {
-		return fmt.Errorf("schema validation failed: %w", err)
+// PreviewFile はJSONファイルから生成されるMarkdownを実行結果として返す。
+func PreviewFile(jsonPath string) (*Result, error) {
+	input, err := ReadPostInputFromFile(jsonPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON file: %w", err)
 	}
 
-	if err := ValidatePostInput(input); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+	markdown, err := RenderPreview(input)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newResult("preview")
+	result.Markdown = markdown
+	return result, nil
+}
+
+// RenderPreview はPostInputを検証し、投稿時と同じ形式のMarkdownを返す。
+func RenderPreview(input *PostInput) (string, error) {
+	if err := ValidateInput(input); err != nil {
+		return "", err
 	}
 
 	// Generate markdown with embedded JSON (same format as post)
 	markdown, err := GenerateMarkdownWithJSON(input)
 	if err != nil {
-		return fmt.Errorf("failed to generate markdown: %w", err)
+		return "", fmt.Errorf("failed to generate markdown: %w", err)
 	}
-	fmt.Print(markdown)
 
-	return nil
+	return markdown, nil
 }
@@ -0,0 +1,56 @@
+package guard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+// ExecuteRead は記事のマークダウンを表示します
+func ExecuteRead(ctx context.Context, postNumber int, policy *Policy, client esa.EsaClientInterface) error {
+	result, err := ReadPost(ctx, postNumber, policy, client)
+	if err != nil {
+		return err
+	}
+	fmt.Print(result.Markdown)
+	if !strings.HasSuffix(result.Markdown, "\n") {
+		fmt.Println()
+	}
+	return nil
+}
+
+// ReadPost は記事のマークダウンをコマンドの実行結果として返します
+func ReadPost(ctx context.Context, postNumber int, policy *Policy, client esa.EsaClientInterface) (*Result, error) {
+	post, markdown, err := Read(ctx, postNumber, policy, client)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newResult("read")
+	result.PostNumber = post.Number
+	result.URL = post.URL
+	result.Name = post.Name
+	result.Category = post.Category
+	result.Markdown = markdown
+	return result, nil
+}
+
+// Read は読み取り可能なカテゴリ内の記事を取得し、本文のマークダウンを返します
+// ガードが作成した記事の場合、先頭の埋め込みJSONは取り除きます
+func Read(ctx context.Context, postNumber int, policy *Policy, client esa.EsaClientInterface) (*esa.Post, string, error) {
+	post, err := getReadablePost(ctx, postNumber, policy, client)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(post.BodyMD) > MaxInputSize {
+		return nil, "", WithKind(ErrorKindContent, fmt.Errorf("post body exceeds %d bytes limit", MaxInputSize))
+	}
+
+	markdown := post.BodyMD
+	if strings.HasPrefix(markdown, Sentinel) {
+		markdown = markdownAfterEmbeddedJSON(markdown)
+	}
+	return post, markdown, nil
+}
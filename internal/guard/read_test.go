@@ -0,0 +1,79 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		bodyMD   string
+		wantBody string
+	}{
+		{
+			name:     "ガード管理記事は埋め込みJSONを取り除く",
+			bodyMD:   managedBody(123),
+			wantBody: "## サマリー",
+		},
+		{
+			name:     "手書きの記事はそのまま返す",
+			bodyMD:   "# memo\n\nhello",
+			wantBody: "# memo\n\nhello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockEsaClientForExecute{
+				getPostFunc: func(number int) (*esa.Post, error) {
+					return &esa.Post{Number: number, Name: "Test", Category: "LLM/Docs/2026/01/28", BodyMD: tt.bodyMD}, nil
+				},
+			}
+
+			_, markdown, err := Read(context.Background(), 123, NewPolicy([]string{"LLM/Docs"}), client)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if strings.Contains(markdown, Sentinel) {
+				t.Errorf("markdown still contains embedded JSON: %q", markdown)
+			}
+			if !strings.HasPrefix(markdown, tt.wantBody) {
+				t.Errorf("markdown = %q, want prefix %q", markdown, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRead_CategoryNotReadable(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+	}{
+		{name: "別のカテゴリ", category: "Secret/2026/01/28"},
+		{name: "前方一致だけのカテゴリ", category: "LLM/Docs-evil/2026/01/28"},
+		{name: "カテゴリなし", category: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockEsaClientForExecute{
+				getPostFunc: func(number int) (*esa.Post, error) {
+					return &esa.Post{Number: number, Category: tt.category, BodyMD: "secret"}, nil
+				},
+			}
+
+			result, err := ReadPost(context.Background(), 123, NewPolicy([]string{"LLM/Docs"}), client)
+			if !errors.Is(err, ErrReadNotAllowed) {
+				t.Fatalf("ReadPost() error = %v, want ErrReadNotAllowed", err)
+			}
+			if result != nil {
+				t.Errorf("result = %+v, want nil", result)
+			}
+		})
+	}
+}
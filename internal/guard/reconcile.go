@@ -0,0 +1,187 @@
+package guard
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+// ReconcileResult はサマリーのチェックボックスをステータスに反映した結果
+type ReconcileResult struct {
+	Input        *PostInput // ステータスを反映した入力（revision_number付き）
+	ChangedTasks []string   // ステータスが変わったタスクのID
+	Diff         string     // 現在の本文と反映後の本文の差分
+	Post         *esa.Post  // apply した場合の更新後の記事
+	Warnings     []string   // apply した場合の更新の警告
+}
+
+// ExecuteReconcile はサマリーのチェックボックスをタスクのステータスに反映し、差分を表示します
+// applyがtrueの場合は反映した内容で記事を更新します
+func ExecuteReconcile(ctx context.Context, postNumber int, policy *Policy, client esa.EsaClientInterface, apply bool, opts PostOptions) error {
+	result, err := ReconcilePost(ctx, postNumber, policy, client, apply, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(result.ChangedTasks) == 0 {
+		fmt.Println("No checkbox changes to reconcile.")
+		return nil
+	}
+	fmt.Print(*result.Diff)
+	if result.URL != "" {
+		fmt.Printf("Updated post: %s (Number: %d)\n", result.URL, result.PostNumber)
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return nil
+}
+
+// ReconcilePost はチェックボックスの反映結果をコマンドの実行結果として返します
+func ReconcilePost(ctx context.Context, postNumber int, policy *Policy, client esa.EsaClientInterface, apply bool, opts PostOptions) (*Result, error) {
+	reconciled, err := Reconcile(ctx, postNumber, policy, client, apply, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newResult("reconcile")
+	result.PostNumber = postNumber
+	result.Diff = &reconciled.Diff
+	result.JSON = reconciled.Input
+	result.ChangedTasks = reconciled.ChangedTasks
+	result.Warnings = reconciled.Warnings
+	if reconciled.Post != nil {
+		result.URL = reconciled.Post.URL
+		result.RevisionNumber = reconciled.Post.RevisionNumber
+	}
+	return result, nil
+}
+
+// Reconcile は記事のサマリーのチェックボックスをタイトルでタスクに対応付け、
+// 新たにチェックされたタスクを completed に、チェックが外されたタスクを in_progress に戻します。
+// applyがtrueで変更がある場合は記事を更新します。変更の有無はステータスが変わったタスクで判定し、
+// 変更がなければ差分は空になります（埋め込みJSONの post_number の記録などだけの差分は示さない）。
+// 更新時にチェックボックス以外の編集が残っていると失われるため、その場合は更新を拒否します。
+// opts の EditMode は無視します（チェックボックスの編集は反映済みのため常に上書きする）。
+func Reconcile(ctx context.Context, postNumber int, policy *Policy, client esa.EsaClientInterface, apply bool, opts PostOptions) (*ReconcileResult, error) {
+	existingPost, err := client.GetPost(ctx, postNumber)
+	if err != nil {
+		return nil, WithKind(ErrorKindAPI, fmt.Errorf("failed to get existing post: %w", err))
+	}
+
+	// 読み取りだけの場合もカテゴリ制限を適用する
+	if err := ValidateUpdateRequest(existingPost.Category, existingPost.Category, policy); err != nil {
+		return nil, err
+	}
+	if err := ValidateManagedPost(existingPost.BodyMD, postNumber); err != nil {
+		return nil, WithKind(ErrorKindContent, err)
+	}
+
+	embedded, err := ExtractEmbeddedJSON(existingPost.BodyMD)
+	if err != nil {
+		return nil, WithKind(ErrorKindContent, fmt.Errorf("invalid JSON in post %d: %w", postNumber, err))
+	}
+
+	input := *embedded
+	input.CreateNew = false
+	input.PostNumber = &postNumber
+	input.RevisionNumber = nil
+	if existingPost.RevisionNumber > 0 {
+		revision := existingPost.RevisionNumber
+		input.RevisionNumber = &revision
+	}
+	input.Body = *cloneBody(&embedded.Body)
+
+	actual := normalizeBodyText(markdownAfterEmbeddedJSON(existingPost.BodyMD))
+	checkboxes := parseSummaryCheckboxes(actual)
+	checkedByTitle := make(map[string]bool)
+	checkedInOrder := make([]bool, 0, len(checkboxes))
+	for _, cb := range checkboxes {
+		checkedByTitle[cb.title] = cb.checked
+		checkedInOrder = append(checkedInOrder, cb.checked)
+	}
+
+	var changed []string
+	for i := range input.Body.Tasks {
+		task := &input.Body.Tasks[i]
+		checked, ok := checkedByTitle[task.Title]
+		if !ok {
+			continue // 見出しが編集されたタスクは対応付けできないためそのまま
+		}
+		switch {
+		case checked && task.Status != TaskStatusCompleted:
+			task.Status = TaskStatusCompleted
+		case !checked && task.Status == TaskStatusCompleted:
+			task.Status = TaskStatusInProgress
+		default:
+			continue
+		}
+		changed = append(changed, task.ID)
+	}
+
+	result := &ReconcileResult{Input: &input, ChangedTasks: changed}
+	if len(changed) == 0 {
+		return result, nil
+	}
+
+	newBodyMD, err := GenerateMarkdownWithJSON(&input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate markdown with JSON: %w", err)
+	}
+	result.Diff = generateUnifiedDiff(existingPost.BodyMD, newBodyMD)
+	if !apply {
+		return result, nil
+	}
+
+	// チェックボックス以外の編集があれば、上書きで失われるため更新しない
+	// 最後に書き込んだ本文にチェックボックスの状態だけを反映したものと、実際の本文を比較する
+	if err := ValidateInput(&input); err != nil {
+		return nil, fmt.Errorf("reconciled post is invalid: %w", err)
+	}
+	rendered := applySummaryCheckboxes(normalizeBodyText(GenerateMarkdown(&embedded.Body)), checkedInOrder)
+	if rendered != actual {
+		return nil, WithKind(ErrorKindConflict, NewValidationError(ErrCodeHumanEditsDetected, fmt.Sprintf(
+			"post %d has edits other than summary checkboxes (sections: %s); use post -edits import instead",
+			postNumber, strings.Join(editedSections(rendered, actual), ", "))))
+	}
+
+	// 人の編集はすべてチェックボックスで、反映済みなので上書きしてよい
+	opts.EditMode = EditModeForce
+	postResult, err := Post(ctx, &input, policy, client, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Post = postResult.Post
+	result.Warnings = postResult.Warnings
+	return result, nil
+}
+
+// summaryCheckbox はサマリーセクションのチェックボックス1行
+type summaryCheckbox struct {
+	title   string
+	checked bool
+}
+
+// parseSummaryCheckboxes はサマリーセクションのチェックボックスを出現順に返します
+func parseSummaryCheckboxes(md string) []summaryCheckbox {
+	var checkboxes []summaryCheckbox
+	inSummary := false
+	for _, line := range strings.Split(md, "\n") {
+		if strings.HasPrefix(line, "## ") {
+			inSummary = line == "## サマリー"
+			continue
+		}
+		if !inSummary {
+			continue
+		}
+		if title, ok := strings.CutPrefix(line, "- [x] "); ok {
+			checkboxes = append(checkboxes, summaryCheckbox{title: title, checked: true})
+		} else if title, ok := strings.CutPrefix(line, "- [ ] "); ok {
+			checkboxes = append(checkboxes, summaryCheckbox{title: title, checked: false})
+		}
+	}
+	return checkboxes
+}
@@ -0,0 +1,154 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+// reconcileClient はReconcileテスト用のモック（UpdatePostで本文を記録する）
+func reconcileClient(bodyMD string, updated *string) *mockEsaClientForExecute {
+	return &mockEsaClientForExecute{
+		getPostFunc: func(number int) (*esa.Post, error) {
+			return &esa.Post{Number: number, Category: "Claude Code/開発日誌/2026/01/28", BodyMD: bodyMD, RevisionNumber: 3}, nil
+		},
+		updatePostFunc: func(number int, input *esa.PostInput) (*esa.Post, error) {
+			*updated = input.BodyMD
+			return &esa.Post{Number: number, URL: "https://example.esa.io/posts/123", RevisionNumber: 4}, nil
+		},
+	}
+}
+
+func TestReconcile_TickAndUntick(t *testing.T) {
+	base := managedInput(123)
+	base.Body.Tasks[0].Status = TaskStatusCompleted
+	bodyMD, err := GenerateMarkdownWithJSON(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// task-1 のチェックを外し、task-2 にチェックを付ける
+	bodyMD = strings.Replace(bodyMD, "- [x] Task 1: Test task", "- [ ] Task 1: Test task", 1)
+	bodyMD = strings.Replace(bodyMD, "- [ ] Task 2: Second task", "- [x] Task 2: Second task", 1)
+
+	var updated string
+	result, err := Reconcile(context.Background(), 123, NewPolicy([]string{"Claude Code/開発日誌"}), reconcileClient(bodyMD, &updated), false, PostOptions{})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	if got := result.Input.Body.Tasks[0].Status; got != TaskStatusInProgress {
+		t.Errorf("task-1 status = %v, want in_progress", got)
+	}
+	if got := result.Input.Body.Tasks[1].Status; got != TaskStatusCompleted {
+		t.Errorf("task-2 status = %v, want completed", got)
+	}
+	if !slices.Equal(result.ChangedTasks, []string{"task-1", "task-2"}) {
+		t.Errorf("ChangedTasks = %v", result.ChangedTasks)
+	}
+	if result.Input.RevisionNumber == nil || *result.Input.RevisionNumber != 3 {
+		t.Errorf("RevisionNumber = %v, want 3", result.Input.RevisionNumber)
+	}
+	if !strings.Contains(result.Diff, "+- Status: `completed`") {
+		t.Errorf("diff should show the status change, got:\n%s", result.Diff)
+	}
+	if updated != "" {
+		t.Error("UpdatePost should not be called without apply")
+	}
+}
+
+func TestReconcile_MatchesByTitle(t *testing.T) {
+	// チェックボックスの並び順が変わっていてもタイトルで対応付ける
+	bodyMD := managedBody(123)
+	bodyMD = strings.Replace(bodyMD,
+		"- [ ] Task 1: Test task\n- [ ] Task 2: Second task",
+		"- [x] Task 2: Second task\n- [ ] Task 1: Test task", 1)
+
+	var updated string
+	result, err := Reconcile(context.Background(), 123, NewPolicy([]string{"Claude Code/開発日誌"}), reconcileClient(bodyMD, &updated), false, PostOptions{})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if !slices.Equal(result.ChangedTasks, []string{"task-2"}) {
+		t.Errorf("ChangedTasks = %v, want [task-2]", result.ChangedTasks)
+	}
+}
+
+func TestReconcile_Apply(t *testing.T) {
+	bodyMD := strings.Replace(managedBody(123), "- [ ] Task 2: Second task", "- [x] Task 2: Second task", 1)
+
+	var updated string
+	result, err := Reconcile(context.Background(), 123, NewPolicy([]string{"Claude Code/開発日誌"}), reconcileClient(bodyMD, &updated), true, PostOptions{})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if result.Post == nil || result.Post.RevisionNumber != 4 {
+		t.Fatalf("expected updated post, got %+v", result.Post)
+	}
+	if !strings.Contains(updated, "- [x] Task 2: Second task") || !strings.Contains(updated, "- Status: `completed`") {
+		t.Errorf("updated body should contain the reconciled status:\n%s", updated)
+	}
+	if strings.Contains(updated, "revision_number") {
+		t.Error("revision_number should not be embedded")
+	}
+}
+
+func TestReconcile_ApplyRefusesOtherEdits(t *testing.T) {
+	bodyMD := strings.Replace(managedBody(123), "- [ ] Task 2: Second task", "- [x] Task 2: Second task", 1)
+	bodyMD = strings.Replace(bodyMD, "\nd2\n", "\nd2 fixed by hand\n", 1)
+
+	var updated string
+	_, err := Reconcile(context.Background(), 123, NewPolicy([]string{"Claude Code/開発日誌"}), reconcileClient(bodyMD, &updated), true, PostOptions{})
+	if !errors.Is(err, ErrHumanEditsDetected) {
+		t.Fatalf("expected ErrHumanEditsDetected, got %v", err)
+	}
+	if updated != "" {
+		t.Error("UpdatePost should not be called")
+	}
+}
+
+func TestReconcile_NoChanges(t *testing.T) {
+	var updated string
+	result, err := Reconcile(context.Background(), 123, NewPolicy([]string{"Claude Code/開発日誌"}), reconcileClient(managedBody(123), &updated), true, PostOptions{})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if result.Diff != "" || len(result.ChangedTasks) != 0 || result.Post != nil {
+		t.Errorf("expected no changes, got %+v", result)
+	}
+}
+
+func TestReconcile_NoChangesOnCreatedPost(t *testing.T) {
+	// 作成したばかりの記事の埋め込みJSONは create_new のまま（post_number を持たない）
+	created := managedInput(123)
+	created.CreateNew = true
+	created.PostNumber = nil
+	bodyMD, err := GenerateMarkdownWithJSON(created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var updated string
+	result, err := Reconcile(context.Background(), 123, NewPolicy([]string{"Claude Code/開発日誌"}), reconcileClient(bodyMD, &updated), true, PostOptions{})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	// チェックボックスの変更がなければ、埋め込みJSONの違いだけで差分を示したり更新したりしない
+	if result.Diff != "" || len(result.ChangedTasks) != 0 || result.Post != nil {
+		t.Errorf("expected no changes, got diff %q, changed %v", result.Diff, result.ChangedTasks)
+	}
+	if updated != "" {
+		t.Error("UpdatePost should not be called")
+	}
+}
+
+func TestReconcile_CategoryNotAllowed(t *testing.T) {
+	var updated string
+	_, err := Reconcile(context.Background(), 123, NewPolicy([]string{"Other"}), reconcileClient(managedBody(123), &updated), false, PostOptions{})
+	if !errors.Is(err, ErrCategoryNotAllowed) {
+		t.Errorf("expected ErrCategoryNotAllowed, got %v", err)
+	}
+}
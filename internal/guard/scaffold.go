@@ -0,0 +1,142 @@
+package guard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+// scaffoldPlaceholder は雛形で埋める仮の文言
+const scaffoldPlaceholder = "TODO"
+
+// ScaffoldOptions は雛形JSONの生成条件
+type ScaffoldOptions struct {
+	BaseCategory string         // 日付を除いたカテゴリ（許可カテゴリ配下）
+	Name         string         // 記事名（FromPost を指定した場合は省略可能で、元記事の名前を使う）
+	FromPost     int            // 0より大きければ、その記事の埋め込みJSONを元に構成をコピーする（ポリシーで読み取れる記事のみ）
+	Now          time.Time      // 日付の基準時刻
+	Location     *time.Location // 日付を決めるタイムゾーン（nilはローカル時刻）
+}
+
+// ExecuteInit は雛形JSONを生成してファイルに書き出します
+func ExecuteInit(ctx context.Context, opts ScaffoldOptions, jsonPath string, policy *Policy, client esa.EsaClientInterface) error {
+	result, err := InitFile(ctx, opts, jsonPath, policy, client)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created JSON file: %s (category: %s)\n", jsonPath, result.JSON.Category)
+	return nil
+}
+
+// InitFile は雛形JSONを生成して新しいファイルに書き出し、実行結果を返します
+// 既存のファイルは上書きしません
+func InitFile(ctx context.Context, opts ScaffoldOptions, jsonPath string, policy *Policy, client esa.EsaClientInterface) (*Result, error) {
+	input, err := Scaffold(ctx, opts, policy, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := createJSONFile(jsonPath, input); err != nil {
+		return nil, WithKind(ErrorKindIO, err)
+	}
+
+	result := newResult("init")
+	result.JSON = input
+	result.JSONFileUpdated = true
+	return result, nil
+}
+
+// Scaffold は新規作成用の雛形JSONを生成します。
+// カテゴリは BaseCategory に指定タイムゾーンでの今日の /yyyy/mm/dd を付けたもので、許可カテゴリ配下である必要があります。
+// FromPost を指定した場合は、その記事の背景・開発指針・タスク構成をコピーし、
+// タスクのステータスを not_started に戻して github_urls を外します。
+// 生成した内容は validate と同じ検証を通ることを確認してから返します。
+func Scaffold(ctx context.Context, opts ScaffoldOptions, policy *Policy, client esa.EsaClientInterface) (*PostInput, error) {
+	// 1. カテゴリの決定と権限チェック
+	base, err := NormalizeCategory(opts.BaseCategory)
+	if err != nil {
+		return nil, err
+	}
+	if hasValidDateSuffix(base) {
+		return nil, NewValidationError(ErrCodeInvalidValue, fmt.Sprintf("base category must not include the date suffix: %s", base)).
+			WithField("category")
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	category := base + "/" + opts.Now.In(loc).Format("2006/01/02")
+
+	if err := policy.Check("category", category, OperationCreate); err != nil {
+		return nil, err
+	}
+
+	// 2. 本文の用意（元記事からのコピーまたは空の雛形）
+	input := &PostInput{
+		CreateNew: true,
+		Name:      opts.Name,
+		Category:  category,
+	}
+	if opts.FromPost > 0 {
+		source, err := Fetch(ctx, opts.FromPost, policy, client)
+		if err != nil {
+			return nil, err
+		}
+		if input.Name == "" {
+			input.Name = source.Name
+		}
+		input.Body = *cloneBody(&source.Body)
+		for i := range input.Body.Tasks {
+			input.Body.Tasks[i].Status = TaskStatusNotStarted
+			input.Body.Tasks[i].GitHubURLs = nil
+		}
+	} else {
+		input.Body = Body{
+			Background: scaffoldPlaceholder,
+			Tasks: []Task{
+				{
+					ID:          "task-1",
+					Title:       "Task 1: " + scaffoldPlaceholder,
+					Status:      TaskStatusNotStarted,
+					Summary:     []string{scaffoldPlaceholder},
+					Description: scaffoldPlaceholder,
+				},
+			},
+		}
+	}
+	if input.Name == "" {
+		return nil, NewValidationError(ErrCodeMissingRequired, "name is required").WithField("name")
+	}
+
+	// 3. 生成した雛形がそのまま validate を通ることを確認
+	if err := ValidateInput(input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// createJSONFile はPostInputを新しいJSONファイルに書き込みます（既存のファイルがあればエラー）
+func createJSONFile(jsonPath string, input *PostInput) error {
+	data, err := json.MarshalIndent(input, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	file, err := os.OpenFile(jsonPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("JSON file already exists: %s", jsonPath)
+		}
+		return fmt.Errorf("failed to create JSON file: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(jsonPath)
+		return fmt.Errorf("failed to write JSON file: %w", err)
+	}
+	return file.Close()
+}
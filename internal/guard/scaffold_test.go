@@ -0,0 +1,135 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+func TestScaffold(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	// UTCでは1月27日だが、東京では1月28日
+	now := time.Date(2026, 1, 27, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		opts         ScaffoldOptions
+		wantCategory string
+		wantCode     ValidationErrorCode
+	}{
+		{
+			name:         "タイムゾーンで日付を決める",
+			opts:         ScaffoldOptions{BaseCategory: "LLM/Tasks", Name: "Plan", Now: now, Location: tokyo},
+			wantCategory: "LLM/Tasks/2026/01/28",
+		},
+		{
+			name:         "UTC",
+			opts:         ScaffoldOptions{BaseCategory: "LLM/Tasks/sub", Name: "Plan", Now: now, Location: time.UTC},
+			wantCategory: "LLM/Tasks/sub/2026/01/27",
+		},
+		{
+			name:     "許可されていないカテゴリ",
+			opts:     ScaffoldOptions{BaseCategory: "Other", Name: "Plan", Now: now},
+			wantCode: ErrCodeCategoryNotAllowed,
+		},
+		{
+			name:     "日付付きのカテゴリ",
+			opts:     ScaffoldOptions{BaseCategory: "LLM/Tasks/2026/01/28", Name: "Plan", Now: now},
+			wantCode: ErrCodeInvalidValue,
+		},
+		{
+			name:     "名前がない",
+			opts:     ScaffoldOptions{BaseCategory: "LLM/Tasks", Now: now},
+			wantCode: ErrCodeMissingRequired,
+		},
+		{
+			name:     "名前が不正",
+			opts:     ScaffoldOptions{BaseCategory: "LLM/Tasks", Name: "a/b", Now: now},
+			wantCode: ErrCodeFieldInvalidChars,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := Scaffold(context.Background(), tt.opts, NewPolicy([]string{"LLM/Tasks"}), nil)
+			if tt.wantCode != "" {
+				var ve *ValidationError
+				if !errors.As(err, &ve) || ve.Code() != tt.wantCode {
+					t.Errorf("Scaffold() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scaffold() error = %v", err)
+			}
+			if input.Category != tt.wantCategory {
+				t.Errorf("Category = %s, want %s", input.Category, tt.wantCategory)
+			}
+			if !input.CreateNew || input.PostNumber != nil {
+				t.Errorf("scaffold must be a create_new input: %+v", input)
+			}
+		})
+	}
+}
+
+func TestScaffold_FromPost(t *testing.T) {
+	source := managedInput(42)
+	source.Body.Tasks[0].Status = TaskStatusCompleted
+	source.Body.Tasks[0].GitHubURLs = []string{"https://github.com/owner/repo/pull/1"}
+	bodyMD, err := GenerateMarkdownWithJSON(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &mockEsaClientForExecute{
+		getPostFunc: func(number int) (*esa.Post, error) {
+			return &esa.Post{Number: number, Category: source.Category, BodyMD: bodyMD}, nil
+		},
+	}
+
+	now := time.Date(2026, 2, 3, 12, 0, 0, 0, time.UTC)
+	input, err := Scaffold(context.Background(), ScaffoldOptions{BaseCategory: "Claude Code/開発日誌", FromPost: 42, Now: now, Location: time.UTC},
+		NewPolicy([]string{"Claude Code/開発日誌"}), client)
+	if err != nil {
+		t.Fatalf("Scaffold() error = %v", err)
+	}
+
+	if input.Name != "Test Post" {
+		t.Errorf("Name = %q, want the source post's name", input.Name)
+	}
+	if input.Category != "Claude Code/開発日誌/2026/02/03" {
+		t.Errorf("Category = %s", input.Category)
+	}
+	if len(input.Body.Tasks) != 2 {
+		t.Fatalf("tasks = %d, want 2", len(input.Body.Tasks))
+	}
+	for _, task := range input.Body.Tasks {
+		if task.Status != TaskStatusNotStarted || task.GitHubURLs != nil {
+			t.Errorf("task %s should be reset, got status=%s github_urls=%v", task.ID, task.Status, task.GitHubURLs)
+		}
+	}
+}
+
+func TestInitFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "new.json")
+	opts := ScaffoldOptions{BaseCategory: "LLM/Tasks", Name: "Plan", Now: time.Now()}
+
+	if _, err := InitFile(context.Background(), opts, path, NewPolicy([]string{"LLM/Tasks"}), nil); err != nil {
+		t.Fatalf("InitFile() error = %v", err)
+	}
+	// 書き出したファイルはそのまま validate を通る
+	if _, err := ValidateFile(path); err != nil {
+		t.Errorf("ValidateFile() error = %v", err)
+	}
+
+	// 既存のファイルは上書きしない
+	if _, err := InitFile(context.Background(), opts, path, NewPolicy([]string{"LLM/Tasks"}), nil); err == nil {
+		t.Error("InitFile() should refuse to overwrite an existing file")
+	}
+}
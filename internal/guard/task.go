@@ -0,0 +1,225 @@
+package guard
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+)
+
+// TaskEdit はタスク一覧への変更。変更したタスクのIDを返す
+type TaskEdit func(body *Body) ([]string, error)
+
+// TaskTarget はタスク編集の対象（ローカルのJSONファイルまたは記事の埋め込みJSON）
+// JSONPath と PostNumber のどちらか一方を指定する
+type TaskTarget struct {
+	JSONPath   string
+	PostNumber int
+}
+
+// ParseTaskStatus はタスクのステータスを解釈します
+func ParseTaskStatus(s string) (TaskStatus, error) {
+	status := TaskStatus(s)
+	switch status {
+	case TaskStatusNotStarted, TaskStatusInProgress, TaskStatusInReview, TaskStatusCompleted:
+		return status, nil
+	default:
+		return "", NewValidationError(ErrCodeInvalidValue,
+			fmt.Sprintf("invalid task status %q (must be not_started, in_progress, in_review, or completed)", s)).
+			WithField("task.status")
+	}
+}
+
+// AddTask はタスクを末尾に追加する変更を返します
+// タイトルは "Task N: " を除いた名前で指定し（付いている場合は取り除く）、IDが空なら自動で採番します
+func AddTask(task Task) TaskEdit {
+	return func(body *Body) ([]string, error) {
+		task.Title = taskName(task.Title)
+		if task.ID == "" {
+			task.ID = nextTaskID(body.Tasks)
+		}
+		if findTask(body.Tasks, task.ID) >= 0 {
+			return nil, NewValidationError(ErrCodeDuplicateID, fmt.Sprintf("task %s already exists", task.ID)).
+				WithField("task.id")
+		}
+		if task.Status == "" {
+			task.Status = TaskStatusNotStarted
+		}
+		body.Tasks = append(body.Tasks, task)
+		renumberTasks(body.Tasks)
+		return []string{task.ID}, nil
+	}
+}
+
+// SetTaskStatus はタスクのステータスを変更する変更を返します
+// githubURLが空でなければ、まだ登録されていない場合にgithub_urlsへ追加します
+func SetTaskStatus(id string, status TaskStatus, githubURL string) TaskEdit {
+	return func(body *Body) ([]string, error) {
+		i := findTask(body.Tasks, id)
+		if i < 0 {
+			return nil, taskNotFoundError(id)
+		}
+		task := &body.Tasks[i]
+		task.Status = status
+		if githubURL != "" && !slices.Contains(task.GitHubURLs, githubURL) {
+			task.GitHubURLs = append(task.GitHubURLs, githubURL)
+		}
+		return []string{id}, nil
+	}
+}
+
+// RemoveTask はタスクを削除する変更を返します
+// 削除したタスクへのdepends_onは取り除き、残りのタスクのタイトルを振り直します
+func RemoveTask(id string) TaskEdit {
+	return func(body *Body) ([]string, error) {
+		i := findTask(body.Tasks, id)
+		if i < 0 {
+			return nil, taskNotFoundError(id)
+		}
+		body.Tasks = slices.Delete(body.Tasks, i, i+1)
+
+		changed := []string{id}
+		for j := range body.Tasks {
+			task := &body.Tasks[j]
+			if !slices.Contains(task.DependsOn, id) {
+				continue
+			}
+			task.DependsOn = slices.DeleteFunc(task.DependsOn, func(dep string) bool { return dep == id })
+			if len(task.DependsOn) == 0 {
+				task.DependsOn = nil
+			}
+			changed = append(changed, task.ID)
+		}
+		renumberTasks(body.Tasks)
+		return changed, nil
+	}
+}
+
+// ExecuteTask はタスクを編集し、結果を表示します
+func ExecuteTask(ctx context.Context, target TaskTarget, edit TaskEdit, policy *Policy, client esa.EsaClientInterface, opts PostOptions) error {
+	result, err := EditTasks(ctx, target, edit, policy, client, opts)
+	if err != nil {
+		return err
+	}
+
+	if result.JSONFileUpdated {
+		fmt.Printf("JSON file updated: %s (tasks: %s)\n", target.JSONPath, strings.Join(result.ChangedTasks, ", "))
+	} else {
+		fmt.Printf("Updated post: %s (Number: %d, tasks: %s)\n", result.URL, result.PostNumber, strings.Join(result.ChangedTasks, ", "))
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return nil
+}
+
+// EditTasks はJSONファイルまたは記事の埋め込みJSONのタスクを編集し、実行結果を返します
+// 編集後の内容がバリデーションを通らない場合は何も書き込みません
+// 記事を編集する場合はpostコマンドと同じカテゴリチェックと編集検知を通して更新します
+func EditTasks(ctx context.Context, target TaskTarget, edit TaskEdit, policy *Policy, client esa.EsaClientInterface, opts PostOptions) (*Result, error) {
+	if (target.JSONPath == "") == (target.PostNumber <= 0) {
+		return nil, NewValidationError(ErrCodeMutuallyExclusive, "exactly one of JSON file or post number must be specified")
+	}
+
+	result := newResult("task")
+	var changed []string
+	applyEdit := func(input *PostInput) (*PostInput, error) {
+		var err error
+		changed, err = edit(&input.Body)
+		return input, err
+	}
+
+	if target.JSONPath != "" {
+		edited, err := editJSONFile(target.JSONPath, applyEdit)
+		if err != nil {
+			return nil, err
+		}
+		result.JSON = edited
+		result.JSONFileUpdated = true
+		result.ChangedTasks = changed
+		return result, nil
+	}
+
+	postResult, edited, err := editPost(ctx, target.PostNumber, policy, client, opts, applyEdit)
+	if err != nil {
+		return nil, err
+	}
+	result.PostNumber = postResult.Post.Number
+	result.URL = postResult.Post.URL
+	result.RevisionNumber = postResult.Post.RevisionNumber
+	result.Warnings = append(result.Warnings, postResult.Warnings...)
+	result.JSON = edited
+	result.ChangedTasks = changed
+	if postResult.HumanEdits != nil {
+		result.EditedSections = postResult.HumanEdits.Sections
+		if postResult.Imported != nil {
+			result.EditsImported = true
+		} else {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("overwrote edits made in esa: %s", strings.Join(postResult.HumanEdits.Sections, ", ")))
+		}
+	}
+	return result, nil
+}
+
+// editJSONFile はJSONファイルをeditで変更し、バリデーションを通った場合のみ原子的に書き換えます
+func editJSONFile(jsonPath string, edit func(*PostInput) (*PostInput, error)) (*PostInput, error) {
+	fileInfo, err := os.Stat(jsonPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat JSON file: %w", err)
+	}
+	input, err := ReadPostInputFromFile(jsonPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON file: %w", err)
+	}
+
+	edited, err := edit(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateInput(edited); err != nil {
+		return nil, err
+	}
+
+	if err := writeJSONFile(jsonPath, edited, fileInfo.Mode().Perm()); err != nil {
+		return nil, WithKind(ErrorKindIO, err)
+	}
+	return edited, nil
+}
+
+// renumberTasks はタスクのタイトルを並び順に "Task 1: " から振り直します
+func renumberTasks(tasks []Task) {
+	for i := range tasks {
+		tasks[i].Title = fmt.Sprintf("Task %d: %s", i+1, taskName(tasks[i].Title))
+	}
+}
+
+// taskName はタイトルから "Task N: " を取り除いた名前を返します
+func taskName(title string) string {
+	if matches := taskTitlePrefixRegex.FindStringSubmatch(title); matches != nil {
+		return matches[2]
+	}
+	return title
+}
+
+// nextTaskID は既存のIDと重複しない "task-N" 形式のIDを返します
+func nextTaskID(tasks []Task) string {
+	for n := len(tasks) + 1; ; n++ {
+		id := fmt.Sprintf("task-%d", n)
+		if findTask(tasks, id) < 0 {
+			return id
+		}
+	}
+}
+
+// findTask はIDが一致するタスクのインデックスを返します（見つからなければ -1）
+func findTask(tasks []Task, id string) int {
+	return slices.IndexFunc(tasks, func(task Task) bool { return task.ID == id })
+}
+
+// taskNotFoundError はタスクが見つからない場合のエラーを返します
+func taskNotFoundError(id string) error {
+	return NewValidationError(ErrCodeNonExistentRef, fmt.Sprintf("task %s not found", id)).WithField("task.id")
+}
@@ -0,0 +1,182 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// threeTaskBody は依存関係のある3タスクの本文を返す
+func threeTaskBody() *Body {
+	return &Body{
+		Background: "Test background",
+		Tasks: []Task{
+			{ID: "task-1", Title: "Task 1: First", Status: TaskStatusCompleted, Summary: []string{"s1"}, Description: "d1"},
+			{ID: "task-2", Title: "Task 2: Second", Status: TaskStatusInProgress, Summary: []string{"s2"}, Description: "d2", DependsOn: []string{"task-1"}},
+			{ID: "task-3", Title: "Task 3: Third", Status: TaskStatusNotStarted, Summary: []string{"s3"}, Description: "d3", DependsOn: []string{"task-1", "task-2"}},
+		},
+	}
+}
+
+func TestAddTask(t *testing.T) {
+	body := threeTaskBody()
+	changed, err := AddTask(Task{Title: "Task 9: Fourth", Summary: []string{"s4"}, Description: "d4"})(body)
+	if err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+
+	added := body.Tasks[3]
+	if added.ID != "task-4" || added.Title != "Task 4: Fourth" || added.Status != TaskStatusNotStarted {
+		t.Errorf("added task = %+v", added)
+	}
+	if !slices.Equal(changed, []string{"task-4"}) {
+		t.Errorf("changed = %v", changed)
+	}
+
+	// 同じIDは追加できない
+	if _, err := AddTask(Task{ID: "task-1", Title: "Dup"})(body); !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("AddTask() with duplicate ID error = %v, want duplicate_id", err)
+	}
+}
+
+func TestSetTaskStatus(t *testing.T) {
+	body := threeTaskBody()
+	url := "https://github.com/owner/repo/pull/1"
+	if _, err := SetTaskStatus("task-2", TaskStatusInReview, url)(body); err != nil {
+		t.Fatalf("SetTaskStatus() error = %v", err)
+	}
+	// 同じURLは重複して追加しない
+	if _, err := SetTaskStatus("task-2", TaskStatusInReview, url)(body); err != nil {
+		t.Fatalf("SetTaskStatus() error = %v", err)
+	}
+
+	task := body.Tasks[1]
+	if task.Status != TaskStatusInReview {
+		t.Errorf("status = %v, want in_review", task.Status)
+	}
+	if !slices.Equal(task.GitHubURLs, []string{url}) {
+		t.Errorf("github_urls = %v", task.GitHubURLs)
+	}
+
+	if _, err := SetTaskStatus("task-9", TaskStatusCompleted, "")(body); !errors.Is(err, ErrNonExistentRef) {
+		t.Errorf("SetTaskStatus() for missing task error = %v, want non_existent_ref", err)
+	}
+}
+
+func TestRemoveTask(t *testing.T) {
+	body := threeTaskBody()
+	changed, err := RemoveTask("task-1")(body)
+	if err != nil {
+		t.Fatalf("RemoveTask() error = %v", err)
+	}
+
+	var titles []string
+	for _, task := range body.Tasks {
+		titles = append(titles, task.Title)
+	}
+	if !slices.Equal(titles, []string{"Task 1: Second", "Task 2: Third"}) {
+		t.Errorf("titles = %v", titles)
+	}
+	if body.Tasks[0].DependsOn != nil {
+		t.Errorf("task-2 depends_on = %v, want nil", body.Tasks[0].DependsOn)
+	}
+	if !slices.Equal(body.Tasks[1].DependsOn, []string{"task-2"}) {
+		t.Errorf("task-3 depends_on = %v, want [task-2]", body.Tasks[1].DependsOn)
+	}
+	if !slices.Equal(changed, []string{"task-1", "task-2", "task-3"}) {
+		t.Errorf("changed = %v", changed)
+	}
+	if err := ValidateTaskNumberSequence(body.Tasks); err != nil {
+		t.Errorf("ValidateTaskNumberSequence() error = %v", err)
+	}
+}
+
+func TestParseTaskStatus(t *testing.T) {
+	if status, err := ParseTaskStatus("in_review"); err != nil || status != TaskStatusInReview {
+		t.Errorf("ParseTaskStatus(in_review) = %v, %v", status, err)
+	}
+	if _, err := ParseTaskStatus("done"); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("ParseTaskStatus(done) error = %v, want invalid_value", err)
+	}
+}
+
+func TestEditTasks_JSONFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "task.json")
+	input := managedInput(123)
+	if err := writeJSONFile(path, input, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := EditTasks(context.Background(), TaskTarget{JSONPath: path}, RemoveTask("task-1"), nil, nil, PostOptions{})
+	if err != nil {
+		t.Fatalf("EditTasks() error = %v", err)
+	}
+	if !result.JSONFileUpdated {
+		t.Error("JSONFileUpdated should be true")
+	}
+
+	written, err := ReadPostInputFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written.Body.Tasks) != 1 || written.Body.Tasks[0].Title != "Task 1: Second task" {
+		t.Errorf("written tasks = %+v", written.Body.Tasks)
+	}
+}
+
+func TestEditTasks_InvalidResultNotWritten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "task.json")
+	if err := writeJSONFile(path, managedInput(123), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 要約のないタスクはバリデーションで弾かれる
+	_, err = EditTasks(context.Background(), TaskTarget{JSONPath: path}, AddTask(Task{Title: "No summary", Description: "d"}), nil, nil, PostOptions{})
+	if err == nil {
+		t.Fatal("EditTasks() expected validation error")
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(before) != string(after) {
+		t.Error("JSON file should not be modified when validation fails")
+	}
+}
+
+func TestEditTasks_Post(t *testing.T) {
+	var updated string
+	client := reconcileClient(managedBody(123), &updated)
+
+	result, err := EditTasks(context.Background(), TaskTarget{PostNumber: 123}, SetTaskStatus("task-1", TaskStatusCompleted, ""),
+		NewPolicy([]string{"Claude Code/開発日誌"}), client, PostOptions{})
+	if err != nil {
+		t.Fatalf("EditTasks() error = %v", err)
+	}
+	if result.RevisionNumber != 4 {
+		t.Errorf("RevisionNumber = %d, want 4", result.RevisionNumber)
+	}
+	if !strings.Contains(updated, "- [x] Task 1: Test task") {
+		t.Errorf("updated body should have task 1 checked:\n%s", updated)
+	}
+
+	// 許可されていないカテゴリの記事は編集できない
+	updated = ""
+	_, err = EditTasks(context.Background(), TaskTarget{PostNumber: 123}, SetTaskStatus("task-1", TaskStatusCompleted, ""),
+		NewPolicy([]string{"LLM/Tasks"}), client, PostOptions{})
+	if !errors.Is(err, ErrCategoryNotAllowed) {
+		t.Errorf("EditTasks() error = %v, want category_not_allowed", err)
+	}
+	if updated != "" {
+		t.Error("UpdatePost should not be called")
+	}
+}
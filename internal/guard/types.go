@@ -31,9 +31,15 @@ type Body struct {
 
 // PostInput は入力JSONの構造体
 type PostInput struct {
-	CreateNew  bool   `json:"create_new,omitempty"`  // 新規作成フラグ
-	PostNumber *int   `json:"post_number,omitempty"` // 更新時に指定
-	Name       string `json:"name"`                  // 必須
-	Category   string `json:"category"`              // 必須
-	Body       Body   `json:"body"`                  // 必須
+	CreateNew  bool `json:"create_new,omitempty"`  // 新規作成フラグ
+	PostNumber *int `json:"post_number,omitempty"` // 更新時に指定
+	// 最後に確認したリビジョン（更新時のみ、任意）
+	// 指定するとesa.io上のリビジョンが異なる場合に更新を拒否する
+	RevisionNumber *int `json:"revision_number,omitempty"`
+	// 記事が属するesa.ioのチーム（任意、postが設定のチームを記録する）
+	// 指定すると設定のチームと異なる場合に投稿を拒否する（別のチームの同じ記事番号を更新しない）
+	Team     string `json:"team,omitempty"`
+	Name     string `json:"name"`     // 必須
+	Category string `json:"category"` // 必須
+	Body     Body   `json:"body"`     // 必須
 }
@@ -1,7 +1,9 @@
 package guard
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 )
 
 // ExecuteValidate はJSONの妥当性を検証する。
@@ -12,6 +14,19 @@ func ExecuteValidate(jsonPath string) error {
 		return fmt.Errorf("failed to read JSON file: %w", err)
 	}
 
+	return ValidateInput(input)
+}
+
+// ValidateFile はJSONファイルを検証し、実行結果を返す。
+func ValidateFile(jsonPath string) (*Result, error) {
+	if err := ExecuteValidate(jsonPath); err != nil {
+		return nil, err
+	}
+	return newResult("validate"), nil
+}
+
+// ValidateInput はPostInputをトリミングした上でスキーマと詳細なバリデーションを行う。
+func ValidateInput(input *PostInput) error {
 	TrimPostInput(input)
 
 	if err := ValidatePostInputSchema(input); err != nil {
@@ -24,3 +39,54 @@ func ExecuteValidate(jsonPath string) error {
 
 	return nil
 }
+
+// ExecuteValidateAll はJSONの妥当性を検証し、見つかったバリデーションエラーをすべて返す。
+// ファイルの読み込みに失敗した場合（JSONとして不正な場合を除く）は error を返す。
+func ExecuteValidateAll(jsonPath string) ([]*ValidationError, error) {
+	input, err := ReadPostInputFromFile(jsonPath)
+	if err != nil {
+		var ve *ValidationError
+		if errors.As(err, &ve) {
+			return []*ValidationError{ve}, nil
+		}
+		return nil, fmt.Errorf("failed to read JSON file: %w", err)
+	}
+
+	return ValidateInputAll(input), nil
+}
+
+// ValidateInputAll はPostInputをトリミングした上で、スキーマ違反と詳細なバリデーションエラーをすべて返す。
+// 詳細なバリデーションが同じフィールド（とインデックス）で報告するスキーマ違反は重複するため除き、
+// 結果はフィールド名・インデックスの順に並べる（実行ごとに順序が変わらないようにする）。
+// エラーがなければ nil を返す。
+func ValidateInputAll(input *PostInput) []*ValidationError {
+	TrimPostInput(input)
+
+	detailed := ValidatePostInputAll(input)
+	errs := collectHTMLCommentErrors(input)
+	for _, ve := range schemaViolations(input) {
+		if !reportedIn(ve, detailed) {
+			errs = append(errs, ve)
+		}
+	}
+	errs = append(errs, detailed...)
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return compareErrorPosition(errs[i], errs[j]) < 0
+	})
+	return errs
+}
+
+// reportedIn はveと同じフィールドのエラーがerrsにあるかを返します
+// インデックスのないエラーはそのフィールドのすべての要素のエラーとみなす
+func reportedIn(ve *ValidationError, errs []*ValidationError) bool {
+	if ve.Field() == "" {
+		return false
+	}
+	for _, other := range errs {
+		if other.Field() == ve.Field() && (other.Index() < 0 || other.Index() == ve.Index()) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,8 +1,10 @@
 package guard
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -79,3 +81,119 @@ func TestExecuteValidate_MalformedJSON(t *testing.T) {
 		t.Error("expected error for malformed JSON")
 	}
 }
+
+func TestExecuteValidateAll_ReportsEveryError(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "invalid.json")
+
+	invalidJSON := `{
+		"create_new": true,
+		"name": "Test Post",
+		"category": "LLM/Tasks",
+		"body": {
+			"background": "Test background",
+			"tasks": [
+				{
+					"id": "task-1",
+					"title": "First",
+					"status": "not_started",
+					"summary": ["Test summary"],
+					"description": "Test description"
+				},
+				{
+					"id": "task-1",
+					"title": "Task 2: Second",
+					"status": "not_started",
+					"summary": ["Test summary"],
+					"description": "Test description"
+				}
+			]
+		}
+	}`
+
+	if err := os.WriteFile(tmpFile, []byte(invalidJSON), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	errs, err := ExecuteValidateAll(tmpFile)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	wantCodes := []ValidationErrorCode{
+		ErrCodeCategoryInvalidDateSuffix,
+		ErrCodeDuplicateID,
+		ErrCodeTaskTitleInvalidPrefix,
+	}
+	if len(errs) != len(wantCodes) {
+		t.Fatalf("len(errs) = %d, want %d: %v", len(errs), len(wantCodes), errs)
+	}
+	for i, want := range wantCodes {
+		if errs[i].Code() != want {
+			t.Errorf("errs[%d].Code() = %v, want %v", i, errs[i].Code(), want)
+		}
+	}
+}
+
+func TestValidateInputAll_StableWithoutDuplicates(t *testing.T) {
+	newInput := func() *PostInput {
+		return &PostInput{
+			CreateNew: true,
+			Name:      "",
+			Category:  "LLM/Tasks/2026/01/31",
+			Body: Body{
+				Background: "Background",
+				Tasks: []Task{
+					{ID: "task-1", Title: "Task 1: First", Status: "not_started", Summary: []string{}, Description: ""},
+					{ID: "task-2", Title: "Task 2: Second", Status: "done", Summary: []string{"ok"}, Description: "desc", GitHubURLs: []string{"not a url"}},
+				},
+			},
+		}
+	}
+
+	// 詳細なバリデーションが報告する欠陥はスキーマ違反として重ねて報告せず、フィールド・インデックス順に並べる
+	want := []string{
+		"field_empty name",
+		"field_empty task.description[0]",
+		"field_invalid_format task.github_urls[1]",
+		"invalid_value task.status[1]",
+		"field_invalid_format task.summary[0]",
+	}
+	// jsonschemaの末端エラーの順序は実行ごとに変わりうるため、何度実行しても同じ結果になることを確認する
+	for run := 0; run < 10; run++ {
+		var got []string
+		for _, ve := range ValidateInputAll(newInput()) {
+			entry := fmt.Sprintf("%s %s", ve.Code(), ve.Field())
+			if ve.Index() >= 0 {
+				entry += fmt.Sprintf("[%d]", ve.Index())
+			}
+			got = append(got, entry)
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("run %d: errors =\n%s\nwant\n%s", run, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
+
+func TestExecuteValidateAll_MalformedJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "malformed.json")
+
+	if err := os.WriteFile(tmpFile, []byte(`{invalid json`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	errs, err := ExecuteValidateAll(tmpFile)
+	if err != nil {
+		t.Fatalf("expected JSON error to be reported as ValidationError, got %v", err)
+	}
+	if len(errs) != 1 || errs[0].Code() != ErrCodeJSONInvalid {
+		t.Errorf("errs = %v, want single json_invalid error", errs)
+	}
+}
+
+func TestExecuteValidateAll_FileNotFound(t *testing.T) {
+	if _, err := ExecuteValidateAll("/nonexistent/path.json"); err == nil {
+		t.Error("expected error for nonexistent file")
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -33,6 +35,139 @@ const (
 	stateVisited                     // 処理完了
 )
 
+// ValidatePostInputSchemaAll はJSONスキーマ違反とHTMLコメントシーケンスをすべて検出して返します
+// スキーマ違反はjsonschemaの末端エラーごとに1件のValidationErrorとなります
+func ValidatePostInputSchemaAll(input *PostInput) []*ValidationError {
+	return append(collectHTMLCommentErrors(input), schemaViolations(input)...)
+}
+
+// schemaViolations はJSONスキーマ違反を、フィールド・インデックス・メッセージの順に並べて返します
+// jsonschemaの末端エラーの順序は実行ごとに変わるため、並べ替えて結果を安定させる
+func schemaViolations(input *PostInput) []*ValidationError {
+	// スキーマを一度だけコンパイル
+	schemaOnce.Do(compileSchema)
+	if schemaCompileError != nil {
+		return []*ValidationError{asValidationError(schemaCompileError, ErrCodeInvalidValue)}
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return []*ValidationError{NewValidationError(ErrCodeJSONInvalid, fmt.Sprintf("failed to marshal input: %v", err)).Wrap(err)}
+	}
+
+	// JSONサイズチェック（2MB上限）
+	if len(data) > MaxJSONSize {
+		return []*ValidationError{NewValidationError(ErrCodeFileSizeExceeded, fmt.Sprintf("JSON size exceeds %d bytes (got %d bytes)", MaxJSONSize, len(data)))}
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []*ValidationError{NewValidationError(ErrCodeJSONInvalid, fmt.Sprintf("failed to unmarshal input: %v", err)).Wrap(err)}
+	}
+
+	err = compiledSchema.Validate(v)
+	if err == nil {
+		return nil
+	}
+
+	var schemaErr *jsonschema.ValidationError
+	if !errors.As(err, &schemaErr) {
+		return []*ValidationError{asValidationError(err, ErrCodeInvalidValue)}
+	}
+	var errs []*ValidationError
+	for _, leaf := range schemaLeafErrors(schemaErr) {
+		field, index := schemaField(leaf.InstanceLocation)
+		location := leaf.InstanceLocation
+		if location == "" {
+			location = "(root)"
+		}
+		ve := NewValidationError(schemaErrorCode(leaf.KeywordLocation), fmt.Sprintf("schema validation failed: %s: %s", location, leaf.Message)).
+			WithField(field).Wrap(leaf)
+		if index >= 0 {
+			ve = ve.WithIndex(index)
+		}
+		errs = append(errs, ve)
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		if c := compareErrorPosition(errs[i], errs[j]); c != 0 {
+			return c < 0
+		}
+		return errs[i].Message() < errs[j].Message()
+	})
+	return errs
+}
+
+// schemaErrorCode はjsonschemaのエラーのキーワード（KeywordLocationの末尾）をエラーコードに変換します
+func schemaErrorCode(keywordLocation string) ValidationErrorCode {
+	switch path.Base(keywordLocation) {
+	case "minLength", "minItems":
+		return ErrCodeFieldEmpty
+	case "maxLength", "maxItems":
+		return ErrCodeFieldTooLong
+	case "pattern", "format":
+		return ErrCodeFieldInvalidFormat
+	case "required":
+		return ErrCodeMissingRequired
+	default:
+		return ErrCodeInvalidValue
+	}
+}
+
+// compareErrorPosition はエラーをフィールド名、インデックスの順に比較します
+func compareErrorPosition(a, b *ValidationError) int {
+	if c := strings.Compare(a.Field(), b.Field()); c != 0 {
+		return c
+	}
+	return a.Index() - b.Index()
+}
+
+// schemaLeafErrors はjsonschemaのエラーツリーから末端のエラーを列挙します
+func schemaLeafErrors(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		leaves = append(leaves, schemaLeafErrors(cause)...)
+	}
+	return leaves
+}
+
+// schemaField はJSON Pointer形式のインスタンス位置を ValidationError のフィールド名と配列インデックスに変換します
+// 例: "/body/tasks/2/summary" → ("task.summary", 2)、"/body/background" → ("background", -1)
+func schemaField(location string) (string, int) {
+	segments := strings.Split(strings.TrimPrefix(location, "/"), "/")
+	if len(segments) > 0 && segments[0] == "body" {
+		segments = segments[1:]
+	}
+	if len(segments) == 0 || segments[0] == "" {
+		return "", -1
+	}
+
+	if segments[0] == "tasks" && len(segments) >= 2 {
+		index, err := strconv.Atoi(segments[1])
+		if err != nil {
+			return "tasks", -1
+		}
+		if len(segments) == 2 {
+			return "tasks", index
+		}
+		return "task." + segments[2], index
+	}
+
+	if len(segments) >= 2 {
+		if index, err := strconv.Atoi(segments[1]); err == nil {
+			return segments[0], index
+		}
+	}
+	return segments[0], -1
+}
+
+// PostInputSchema は埋め込まれた入力JSONのスキーマを返します
+func PostInputSchema() json.RawMessage {
+	return json.RawMessage(schemaJSON)
+}
+
 // compileSchema はJSONスキーマを一度だけコンパイルします
 func compileSchema() {
 	compiler := jsonschema.NewCompiler()
@@ -87,66 +222,89 @@ func ValidatePostInputSchema(input *PostInput) error {
 // checkHTMLCommentSequences はPostInput内のすべてのテキストフィールドに
 // HTMLコメント開始/終了シーケンス（<!--, -->）が含まれていないかチェックします
 func checkHTMLCommentSequences(input *PostInput) error {
+	if errs := collectHTMLCommentErrors(input); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// containsHTMLComment は文字列にHTMLコメント開始/終了シーケンスが含まれているかチェックします
+func containsHTMLComment(s string) bool {
+	return strings.Contains(s, "<!--") || strings.Contains(s, "-->")
+}
+
+// collectHTMLCommentErrors はHTMLコメントシーケンスを含むすべてのフィールドのエラーを返します
+func collectHTMLCommentErrors(input *PostInput) []*ValidationError {
+	var errs []*ValidationError
+	add := func(field string, index int, label string) {
+		ve := NewValidationError(ErrCodeFieldInvalidChars, fmt.Sprintf("%s contains forbidden HTML comment sequence (<!-- or -->)", label)).
+			WithField(field)
+		if index >= 0 {
+			ve = ve.WithIndex(index)
+		}
+		errs = append(errs, ve)
+	}
+
 	// name
-	if strings.Contains(input.Name, "<!--") || strings.Contains(input.Name, "-->") {
-		return fmt.Errorf("name contains forbidden HTML comment sequence (<!-- or -->)")
+	if containsHTMLComment(input.Name) {
+		add("name", -1, "name")
 	}
 
 	// category
-	if strings.Contains(input.Category, "<!--") || strings.Contains(input.Category, "-->") {
-		return fmt.Errorf("category contains forbidden HTML comment sequence (<!-- or -->)")
+	if containsHTMLComment(input.Category) {
+		add("category", -1, "category")
 	}
 
 	// body.background
-	if strings.Contains(input.Body.Background, "<!--") || strings.Contains(input.Body.Background, "-->") {
-		return fmt.Errorf("background contains forbidden HTML comment sequence (<!-- or -->)")
+	if containsHTMLComment(input.Body.Background) {
+		add("background", -1, "background")
 	}
 
 	// body.related_links
 	for i, link := range input.Body.RelatedLinks {
-		if strings.Contains(link, "<!--") || strings.Contains(link, "-->") {
-			return fmt.Errorf("related_links[%d] contains forbidden HTML comment sequence (<!-- or -->)", i)
+		if containsHTMLComment(link) {
+			add("related_links", i, fmt.Sprintf("related_links[%d]", i))
 		}
 	}
 
 	// body.instructions
 	for i, inst := range input.Body.Instructions {
-		if strings.Contains(inst, "<!--") || strings.Contains(inst, "-->") {
-			return fmt.Errorf("instructions[%d] contains forbidden HTML comment sequence (<!-- or -->)", i)
+		if containsHTMLComment(inst) {
+			add("instructions", i, fmt.Sprintf("instructions[%d]", i))
 		}
 	}
 
 	// body.tasks
 	for i, task := range input.Body.Tasks {
-		if strings.Contains(task.ID, "<!--") || strings.Contains(task.ID, "-->") {
-			return fmt.Errorf("task[%d].id contains forbidden HTML comment sequence (<!-- or -->)", i)
+		if containsHTMLComment(task.ID) {
+			add("task.id", i, fmt.Sprintf("task[%d].id", i))
 		}
-		if strings.Contains(task.Title, "<!--") || strings.Contains(task.Title, "-->") {
-			return fmt.Errorf("task[%d].title contains forbidden HTML comment sequence (<!-- or -->)", i)
+		if containsHTMLComment(task.Title) {
+			add("task.title", i, fmt.Sprintf("task[%d].title", i))
 		}
-		if strings.Contains(task.Description, "<!--") || strings.Contains(task.Description, "-->") {
-			return fmt.Errorf("task[%d].description contains forbidden HTML comment sequence (<!-- or -->)", i)
+		if containsHTMLComment(task.Description) {
+			add("task.description", i, fmt.Sprintf("task[%d].description", i))
 		}
 		for j, summary := range task.Summary {
-			if strings.Contains(summary, "<!--") || strings.Contains(summary, "-->") {
-				return fmt.Errorf("task[%d].summary[%d] contains forbidden HTML comment sequence (<!-- or -->)", i, j)
+			if containsHTMLComment(summary) {
+				add("task.summary", i, fmt.Sprintf("task[%d].summary[%d]", i, j))
 			}
 		}
 		// task.github_urls
 		for j, url := range task.GitHubURLs {
-			if strings.Contains(url, "<!--") || strings.Contains(url, "-->") {
-				return fmt.Errorf("task[%d].github_urls[%d] contains forbidden HTML comment sequence (<!-- or -->)", i, j)
+			if containsHTMLComment(url) {
+				add("task.github_urls", i, fmt.Sprintf("task[%d].github_urls[%d]", i, j))
 			}
 		}
 		// task.depends_on
 		for j, dep := range task.DependsOn {
-			if strings.Contains(dep, "<!--") || strings.Contains(dep, "-->") {
-				return fmt.Errorf("task[%d].depends_on[%d] contains forbidden HTML comment sequence (<!-- or -->)", i, j)
+			if containsHTMLComment(dep) {
+				add("task.depends_on", i, fmt.Sprintf("task[%d].depends_on[%d]", i, j))
 			}
 		}
 	}
 
-	return nil
+	return errs
 }
 
 // detectCyclicDependency はDFSを使用して循環依存を検出します
@@ -222,72 +380,95 @@ func TrimPostInput(input *PostInput) {
 }
 
 // ValidatePostInput は PostInput の各フィールドを検証します
+// 最初に見つかったエラーを返します（全エラーが必要な場合は ValidatePostInputAll を使用）
 func ValidatePostInput(input *PostInput) error {
+	if errs := ValidatePostInputAll(input); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ValidatePostInputAll は最初のエラーで止まらずに PostInput 全体を検証し、
+// 見つかったすべてのエラーを検出順に返します。エラーがなければ nil を返します。
+// 先頭の要素は ValidatePostInput が返すエラーと一致します。
+func ValidatePostInputAll(input *PostInput) []*ValidationError {
+	var errs []*ValidationError
+	add := func(err *ValidationError) {
+		errs = append(errs, err)
+	}
+
 	// create_newとpost_numberの検証
 	if input.CreateNew && input.PostNumber != nil {
-		return NewValidationError(ErrCodeMutuallyExclusive, "cannot specify both create_new and post_number")
+		add(NewValidationError(ErrCodeMutuallyExclusive, "cannot specify both create_new and post_number"))
 	}
 	if !input.CreateNew && input.PostNumber == nil {
-		return NewValidationError(ErrCodeMissingRequired, "must specify either create_new or post_number")
+		add(NewValidationError(ErrCodeMissingRequired, "must specify either create_new or post_number"))
 	}
 	if input.PostNumber != nil && *input.PostNumber <= 0 {
-		return NewValidationError(ErrCodeInvalidValue, "post_number must be greater than 0").WithField("post_number")
+		add(NewValidationError(ErrCodeInvalidValue, "post_number must be greater than 0").WithField("post_number"))
+	}
+	if input.RevisionNumber != nil {
+		if input.PostNumber == nil {
+			add(NewValidationError(ErrCodeInvalidValue, "revision_number can only be specified with post_number").WithField("revision_number"))
+		} else if *input.RevisionNumber <= 0 {
+			add(NewValidationError(ErrCodeInvalidValue, "revision_number must be greater than 0").WithField("revision_number"))
+		}
+	}
+
+	// teamの検証（チーム名と同じく [A-Za-z0-9_-] のみ）
+	if input.Team != "" && !IsTeamName(input.Team) {
+		add(NewValidationError(ErrCodeFieldInvalidChars, "team contains invalid characters (only A-Z, a-z, 0-9, _, - allowed)").WithField("team"))
 	}
 
 	// nameの検証
 	if input.Name == "" {
-		return NewValidationError(ErrCodeFieldEmpty, "name cannot be empty").WithField("name")
+		add(NewValidationError(ErrCodeFieldEmpty, "name cannot be empty").WithField("name"))
 	}
 	if len(input.Name) > 255 {
-		return NewValidationError(ErrCodeFieldTooLong, "name exceeds 255 bytes").WithField("name")
+		add(NewValidationError(ErrCodeFieldTooLong, "name exceeds 255 bytes").WithField("name"))
 	}
 	if containsControlCharacters(input.Name) {
-		return NewValidationError(ErrCodeFieldInvalidChars, "name contains control characters").WithField("name")
+		add(NewValidationError(ErrCodeFieldInvalidChars, "name contains control characters").WithField("name"))
 	}
 	if strings.Contains(input.Name, "/") {
-		return NewValidationError(ErrCodeFieldInvalidChars, "name cannot contain /").WithField("name")
+		add(NewValidationError(ErrCodeFieldInvalidChars, "name cannot contain /").WithField("name"))
 	}
 	if strings.ContainsAny(input.Name, "（）：") {
-		return NewValidationError(ErrCodeFieldInvalidChars, "name cannot contain fullwidth parentheses or colon").WithField("name")
+		add(NewValidationError(ErrCodeFieldInvalidChars, "name cannot contain fullwidth parentheses or colon").WithField("name"))
 	}
 
 	// categoryの検証（パス正規化を含む）
 	if input.Category == "" {
-		return NewValidationError(ErrCodeCategoryEmpty, "category cannot be empty").WithField("category")
-	}
-	// パストラバーサル、空セグメント、先頭/末尾スラッシュをチェック
-	if _, err := NormalizeCategory(input.Category); err != nil {
-		return err
-	}
-	if !hasValidDateSuffix(input.Category) {
-		return NewValidationError(ErrCodeCategoryInvalidDateSuffix, "category must end with /yyyy/mm/dd format").WithField("category")
+		add(NewValidationError(ErrCodeCategoryEmpty, "category cannot be empty").WithField("category"))
+	} else {
+		// パストラバーサル、空セグメント、先頭/末尾スラッシュをチェック
+		if _, err := NormalizeCategory(input.Category); err != nil {
+			add(asValidationError(err, ErrCodeCategoryInvalidPath))
+		}
+		if !hasValidDateSuffix(input.Category) {
+			add(NewValidationError(ErrCodeCategoryInvalidDateSuffix, "category must end with /yyyy/mm/dd format").WithField("category"))
+		}
 	}
 
 	// bodyの検証
 	if input.Body.Background == "" {
-		return NewValidationError(ErrCodeFieldEmpty, "background cannot be empty").WithField("background")
+		add(NewValidationError(ErrCodeFieldEmpty, "background cannot be empty").WithField("background"))
 	}
 	// backgroundには## 背景より上位の見出し（#, ##）を含めることができない
 	if containsHeadingMarkers(input.Body.Background, 2) {
-		return NewValidationError(ErrCodeFieldInvalidFormat, "background cannot contain heading markers (# or ##)").WithField("background")
+		add(NewValidationError(ErrCodeFieldInvalidFormat, "background cannot contain heading markers (# or ##)").WithField("background"))
 	}
 
-	// instructionsの検証
-	if err := ValidateInstructions(input.Body.Instructions); err != nil {
-		var ve *ValidationError
-		if errors.As(err, &ve) {
-			// ValidationErrorの場合はコードを保持
-			return NewValidationError(ve.Code(), fmt.Sprintf("instructions: %v", err)).
-				WithField("instructions").Wrap(err)
-		}
-		// それ以外のエラーはFieldInvalidFormatとして扱う
-		return NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("instructions: %v", err)).
-			WithField("instructions").Wrap(err)
+	// instructionsの検証（コードを保持してフィールドを付与）
+	for _, ve := range validateInstructionsAll(input.Body.Instructions) {
+		add(NewValidationError(ve.Code(), fmt.Sprintf("instructions: %v", ve)).
+			WithField("instructions").Wrap(ve))
 	}
 
 	// tasksの検証
 	if len(input.Body.Tasks) == 0 {
-		return NewValidationError(ErrCodeFieldEmpty, "tasks cannot be empty").WithField("tasks")
+		add(NewValidationError(ErrCodeFieldEmpty, "tasks cannot be empty").WithField("tasks"))
+		return errs
 	}
 
 	// タスクIDのユニーク性チェック用マップ
@@ -295,91 +476,97 @@ func ValidatePostInput(input *PostInput) error {
 
 	for i, task := range input.Body.Tasks {
 		if task.ID == "" {
-			return NewValidationError(ErrCodeFieldEmpty, fmt.Sprintf("task[%d].id cannot be empty", i)).
-				WithField("task.id").WithIndex(i)
+			add(NewValidationError(ErrCodeFieldEmpty, fmt.Sprintf("task[%d].id cannot be empty", i)).
+				WithField("task.id").WithIndex(i))
 		}
 		if task.Title == "" {
-			return NewValidationError(ErrCodeFieldEmpty, fmt.Sprintf("task[%d].title cannot be empty", i)).
-				WithField("task.title").WithIndex(i)
+			add(NewValidationError(ErrCodeFieldEmpty, fmt.Sprintf("task[%d].title cannot be empty", i)).
+				WithField("task.title").WithIndex(i))
 		}
 		if task.Description == "" {
-			return NewValidationError(ErrCodeFieldEmpty, fmt.Sprintf("task[%d].description cannot be empty", i)).
-				WithField("task.description").WithIndex(i)
+			add(NewValidationError(ErrCodeFieldEmpty, fmt.Sprintf("task[%d].description cannot be empty", i)).
+				WithField("task.description").WithIndex(i))
 		}
 		// descriptionには### タスクタイトルより上位の見出し（#, ##, ###）を含めることができない
 		if containsHeadingMarkers(task.Description, 3) {
-			return NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("task[%d].description cannot contain heading markers (# or ## or ###)", i)).
-				WithField("task.description").WithIndex(i)
+			add(NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("task[%d].description cannot contain heading markers (# or ## or ###)", i)).
+				WithField("task.description").WithIndex(i))
 		}
 		if string(task.Status) == "" {
-			return NewValidationError(ErrCodeFieldEmpty, fmt.Sprintf("task[%d].status cannot be empty", i)).
-				WithField("task.status").WithIndex(i)
+			add(NewValidationError(ErrCodeFieldEmpty, fmt.Sprintf("task[%d].status cannot be empty", i)).
+				WithField("task.status").WithIndex(i))
 		}
 
-		// Summaryの検証
-		if err := ValidateSummary(task.Summary); err != nil {
-			var ve *ValidationError
-			if errors.As(err, &ve) {
-				// ValidationErrorの場合はコードを保持してインデックスを追加
-				return NewValidationError(ve.Code(), fmt.Sprintf("task[%d].summary: %v", i, err)).
-					WithField("task.summary").WithIndex(i).Wrap(err)
-			}
-			// それ以外のエラーはFieldInvalidFormatとして扱う
-			return NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("task[%d].summary: %v", i, err)).
-				WithField("task.summary").WithIndex(i).Wrap(err)
+		// Summaryの検証（コードを保持してインデックスを追加）
+		for _, ve := range validateSummaryAll(task.Summary) {
+			add(NewValidationError(ve.Code(), fmt.Sprintf("task[%d].summary: %v", i, ve)).
+				WithField("task.summary").WithIndex(i).Wrap(ve))
 		}
 
 		// GitHub URLsの検証
 		for j, ghURL := range task.GitHubURLs {
 			if !isGitHubURL(ghURL) {
-				return NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("task[%d].github_urls[%d]: must be a valid GitHub URL (https://github.com/...)", i, j)).
-					WithField("task.github_urls").WithIndex(i)
+				add(NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("task[%d].github_urls[%d]: must be a valid GitHub URL (https://github.com/...)", i, j)).
+					WithField("task.github_urls").WithIndex(i))
 			}
 		}
 
 		// ステータスとGitHub URLsの整合性チェック
 		if len(task.GitHubURLs) > 0 && task.Status == TaskStatusNotStarted {
-			return NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("task[%d]: status is 'not_started' but has GitHub URLs (should be 'in_progress' or later)", i)).
-				WithField("task.status").WithIndex(i)
+			add(NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("task[%d]: status is 'not_started' but has GitHub URLs (should be 'in_progress' or later)", i)).
+				WithField("task.status").WithIndex(i))
 		}
 
-		// IDのユニーク性チェック
+		// IDのユニーク性チェック（空IDは上で報告済み）
+		if task.ID == "" {
+			continue
+		}
 		if taskIDs[task.ID] {
-			return NewValidationError(ErrCodeDuplicateID, fmt.Sprintf("duplicate task ID: %s", task.ID)).
-				WithField("task.id")
+			add(NewValidationError(ErrCodeDuplicateID, fmt.Sprintf("duplicate task ID: %s", task.ID)).
+				WithField("task.id"))
 		}
 		taskIDs[task.ID] = true
 	}
 
 	// タスク番号の形式と連続性を検証
-	if err := ValidateTaskNumberSequence(input.Body.Tasks); err != nil {
-		return err
-	}
+	errs = append(errs, validateTaskNumberSequenceAll(input.Body.Tasks)...)
 
 	// 依存関係の検証
 	for i, task := range input.Body.Tasks {
 		for j, depID := range task.DependsOn {
 			if depID == "" {
-				return NewValidationError(ErrCodeFieldEmpty, fmt.Sprintf("task[%d].depends_on[%d]: empty task ID", i, j)).
-					WithField("task.depends_on").WithIndex(i)
+				add(NewValidationError(ErrCodeFieldEmpty, fmt.Sprintf("task[%d].depends_on[%d]: empty task ID", i, j)).
+					WithField("task.depends_on").WithIndex(i))
+				continue
 			}
 			if depID == task.ID {
-				return NewValidationError(ErrCodeSelfReference, fmt.Sprintf("task[%d].depends_on: self-reference is not allowed", i)).
-					WithField("task.depends_on").WithIndex(i)
+				add(NewValidationError(ErrCodeSelfReference, fmt.Sprintf("task[%d].depends_on: self-reference is not allowed", i)).
+					WithField("task.depends_on").WithIndex(i))
+				continue
 			}
 			if !taskIDs[depID] {
-				return NewValidationError(ErrCodeNonExistentRef, fmt.Sprintf("task[%d].depends_on references non-existent task ID: %s", i, depID)).
-					WithField("task.depends_on").WithIndex(i)
+				add(NewValidationError(ErrCodeNonExistentRef, fmt.Sprintf("task[%d].depends_on references non-existent task ID: %s", i, depID)).
+					WithField("task.depends_on").WithIndex(i))
 			}
 		}
 	}
 
 	// 循環依存チェック
 	if hasCycle, cyclePath := detectCyclicDependency(input.Body.Tasks); hasCycle {
-		return NewValidationError(ErrCodeCircularDependency, fmt.Sprintf("circular dependency detected: %s", strings.Join(cyclePath, " -> ")))
+		add(NewValidationError(ErrCodeCircularDependency, fmt.Sprintf("circular dependency detected: %s", strings.Join(cyclePath, " -> "))))
 	}
 
-	return nil
+	return errs
+}
+
+// asValidationError はerrを*ValidationErrorとして返します
+// ValidationErrorでない場合は指定したコードでラップします
+func asValidationError(err error, fallback ValidationErrorCode) *ValidationError {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve
+	}
+	return NewValidationError(fallback, err.Error()).Wrap(err)
 }
 
 // containsControlCharacters は文字列に制御文字（改行、タブなど）が含まれているかチェックします
@@ -453,15 +640,24 @@ func containsHeadingMarkers(text string, maxLevel int) bool {
 // - 最低1行、最大3行
 // - 各行140字以内
 func ValidateSummary(summary []string) error {
+	if errs := validateSummaryAll(summary); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// validateSummaryAll はSummaryフィールドのエラーをすべて返します
+func validateSummaryAll(summary []string) []*ValidationError {
+	var errs []*ValidationError
 	if len(summary) < 1 || len(summary) > 3 {
-		return NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("summary must have 1-3 items, got %d", len(summary)))
+		errs = append(errs, NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("summary must have 1-3 items, got %d", len(summary))))
 	}
 	for i, line := range summary {
 		if len([]rune(line)) > 140 {
-			return NewValidationError(ErrCodeFieldTooLong, fmt.Sprintf("summary line %d exceeds 140 characters", i+1))
+			errs = append(errs, NewValidationError(ErrCodeFieldTooLong, fmt.Sprintf("summary line %d exceeds 140 characters", i+1)))
 		}
 	}
-	return nil
+	return errs
 }
 
 // ValidateInstructions はInstructionsフィールドを検証します
@@ -470,28 +666,36 @@ func ValidateSummary(summary []string) error {
 // - 見出しマーカー（#, ##）禁止
 // - リストマーカー（-, *, +, 数字+.）で始まる項目を禁止
 func ValidateInstructions(instructions []string) error {
+	if errs := validateInstructionsAll(instructions); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// validateInstructionsAll はInstructionsフィールドのエラーをすべて返します
+func validateInstructionsAll(instructions []string) []*ValidationError {
+	var errs []*ValidationError
 	if len(instructions) > 10 {
-		return NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("instructions must have at most 10 items, got %d", len(instructions)))
+		errs = append(errs, NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("instructions must have at most 10 items, got %d", len(instructions))))
 	}
 	for i, item := range instructions {
 		if len([]rune(item)) > 500 {
-			return NewValidationError(ErrCodeFieldTooLong, fmt.Sprintf("instructions item %d exceeds 500 characters", i+1))
+			errs = append(errs, NewValidationError(ErrCodeFieldTooLong, fmt.Sprintf("instructions item %d exceeds 500 characters", i+1)))
 		}
 		// 見出しマーカーチェック
 		if containsHeadingMarkers(item, 2) {
-			return NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("instructions item %d cannot contain heading markers (# or ##)", i+1))
+			errs = append(errs, NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("instructions item %d cannot contain heading markers (# or ##)", i+1)))
 		}
 		// リストマーカーチェック（行頭の -, *, +, 数字+. を禁止）
 		trimmed := strings.TrimSpace(item)
 		if strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") || strings.HasPrefix(trimmed, "+ ") {
-			return NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("instructions item %d cannot start with list markers (-, *, +)", i+1))
-		}
-		// 数字 + . のパターンチェック（例: "1. ", "2. "）
-		if numberedListMarkerRegex.MatchString(trimmed) {
-			return NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("instructions item %d cannot start with numbered list markers (e.g., '1. ')", i+1))
+			errs = append(errs, NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("instructions item %d cannot start with list markers (-, *, +)", i+1)))
+		} else if numberedListMarkerRegex.MatchString(trimmed) {
+			// 数字 + . のパターンチェック（例: "1. ", "2. "）
+			errs = append(errs, NewValidationError(ErrCodeFieldInvalidFormat, fmt.Sprintf("instructions item %d cannot start with numbered list markers (e.g., '1. ')", i+1)))
 		}
 	}
-	return nil
+	return errs
 }
 
 // ValidateTaskTitleFormat は単一タスクのタイトル形式を検証します
@@ -663,10 +867,21 @@ func diagnoseTaskTitleError(title string, index int) error {
 // ValidateTaskNumberSequence はタスク番号が1から厳密に連続しているかを検証します
 // タスクは配列の順番通りに1, 2, 3, 4... と並んでいる必要があります
 func ValidateTaskNumberSequence(tasks []Task) error {
+	if errs := validateTaskNumberSequenceAll(tasks); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// validateTaskNumberSequenceAll はタスク番号の形式・重複・連続性のエラーをすべて返します
+// 形式エラーまたは重複がある場合、番号が確定しないため連続性チェックは行いません
+func validateTaskNumberSequenceAll(tasks []Task) []*ValidationError {
 	if len(tasks) == 0 {
 		return nil
 	}
 
+	var errs []*ValidationError
+
 	// 各タスクの番号を取得し、重複チェックと順序チェックを行う
 	taskNumbers := make([]int, len(tasks)) // 各タスクの番号を保存
 	seen := make(map[int]int)              // taskNumber -> taskIndex (重複検出用)
@@ -674,31 +889,37 @@ func ValidateTaskNumberSequence(tasks []Task) error {
 	for i, task := range tasks {
 		taskNum, _, err := ValidateTaskTitleFormat(task.Title, i)
 		if err != nil {
-			return err // 形式エラーは先に返す
+			errs = append(errs, asValidationError(err, ErrCodeTaskTitleInvalidPrefix)) // 形式エラーは先に返す
+			continue
 		}
 
 		// 重複チェック
 		if existingIndex, exists := seen[taskNum]; exists {
-			return NewValidationError(ErrCodeTaskNumberDuplicate,
+			errs = append(errs, NewValidationError(ErrCodeTaskNumberDuplicate,
 				fmt.Sprintf("duplicate task number %d found at task[%d] and task[%d]",
 					taskNum, existingIndex, i)).
-				WithField("task.title").WithIndex(i)
+				WithField("task.title").WithIndex(i))
+			continue
 		}
 		seen[taskNum] = i
 		taskNumbers[i] = taskNum // 番号を保存
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
+
 	// 順序チェック（保存した番号を再利用）
 	for i, taskNum := range taskNumbers {
 		// 期待される番号は i+1（0-indexed なので）
 		expectedNum := i + 1
 		if taskNum != expectedNum {
-			return NewValidationError(ErrCodeTaskNumberNotSequential,
+			errs = append(errs, NewValidationError(ErrCodeTaskNumberNotSequential,
 				fmt.Sprintf("task[%d].title: expected Task %d but got Task %d (tasks must be numbered sequentially: 1, 2, 3, ...)",
 					i, expectedNum, taskNum)).
-				WithField("task.title").WithIndex(i)
+				WithField("task.title").WithIndex(i))
 		}
 	}
 
-	return nil
+	return errs
 }
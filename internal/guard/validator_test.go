@@ -2541,3 +2541,215 @@ func TestValidatePostInputSchema_JSONSize(t *testing.T) {
 		t.Errorf("Expected error message about JSON size, got: %v", err)
 	}
 }
+
+func TestValidatePostInputAll(t *testing.T) {
+	postNumber := 0
+	input := &PostInput{
+		PostNumber: &postNumber,
+		Name:       "Bad/Name",
+		Category:   "LLM/Tasks",
+		Body: Body{
+			Background: "Background",
+			Tasks: []Task{
+				{ID: "task-1", Title: "Task 1: First", Status: TaskStatusNotStarted, Summary: []string{"ok"}, Description: "desc"},
+				{ID: "task-2", Title: "Second", Status: TaskStatusNotStarted, Summary: []string{}, Description: "desc"},
+				{ID: "task-3", Title: "Task 3: Third", Status: TaskStatusNotStarted, Summary: []string{"ok"}, Description: "", DependsOn: []string{"task-9"}},
+			},
+		},
+	}
+
+	errs := ValidatePostInputAll(input)
+
+	wantCodes := []ValidationErrorCode{
+		ErrCodeInvalidValue,              // post_number
+		ErrCodeFieldInvalidChars,         // name
+		ErrCodeCategoryInvalidDateSuffix, // category
+		ErrCodeFieldInvalidFormat,        // task[1].summary
+		ErrCodeFieldEmpty,                // task[2].description
+		ErrCodeTaskTitleInvalidPrefix,    // task[1].title
+		ErrCodeNonExistentRef,            // task[2].depends_on
+	}
+	if len(errs) != len(wantCodes) {
+		for _, e := range errs {
+			t.Logf("got: %s", e)
+		}
+		t.Fatalf("len(errs) = %d, want %d", len(errs), len(wantCodes))
+	}
+	for i, want := range wantCodes {
+		if errs[i].Code() != want {
+			t.Errorf("errs[%d].Code() = %v, want %v (%s)", i, errs[i].Code(), want, errs[i])
+		}
+	}
+
+	// フィールドとインデックスのメタデータが保持されていること
+	if errs[3].Field() != "task.summary" || errs[3].Index() != 1 {
+		t.Errorf("summary error field/index = %s/%d, want task.summary/1", errs[3].Field(), errs[3].Index())
+	}
+	if errs[4].Field() != "task.description" || errs[4].Index() != 2 {
+		t.Errorf("description error field/index = %s/%d, want task.description/2", errs[4].Field(), errs[4].Index())
+	}
+
+	// 先頭のエラーは ValidatePostInput の結果と一致すること
+	first := ValidatePostInput(input)
+	if first == nil || first.Error() != errs[0].Error() {
+		t.Errorf("ValidatePostInput() = %v, want %v", first, errs[0])
+	}
+}
+
+func TestValidatePostInputAll_Valid(t *testing.T) {
+	input := &PostInput{
+		CreateNew: true,
+		Name:      "Test",
+		Category:  "LLM/Tasks/2026/01/31",
+		Body: Body{
+			Background: "Background",
+			Tasks: []Task{
+				{ID: "task-1", Title: "Task 1: First", Status: TaskStatusNotStarted, Summary: []string{"ok"}, Description: "desc"},
+			},
+		},
+	}
+
+	if errs := ValidatePostInputAll(input); errs != nil {
+		t.Errorf("ValidatePostInputAll() = %v, want nil", errs)
+	}
+}
+
+func TestValidatePostInputAll_TaskNumberSequence(t *testing.T) {
+	input := &PostInput{
+		CreateNew: true,
+		Name:      "Test",
+		Category:  "LLM/Tasks/2026/01/31",
+		Body: Body{
+			Background: "Background",
+			Tasks: []Task{
+				{ID: "task-1", Title: "Task 1: First", Status: TaskStatusNotStarted, Summary: []string{"ok"}, Description: "desc"},
+				{ID: "task-2", Title: "Task 3: Second", Status: TaskStatusNotStarted, Summary: []string{"ok"}, Description: "desc"},
+				{ID: "task-3", Title: "Task 4: Third", Status: TaskStatusNotStarted, Summary: []string{"ok"}, Description: "desc"},
+			},
+		},
+	}
+
+	errs := ValidatePostInputAll(input)
+	if len(errs) != 2 {
+		t.Fatalf("len(errs) = %d, want 2: %v", len(errs), errs)
+	}
+	for i, e := range errs {
+		if !errors.Is(e, ErrTaskNumberNotSequential) {
+			t.Errorf("errs[%d] = %v, want task_number_not_sequential", i, e)
+		}
+		if e.Index() != i+1 {
+			t.Errorf("errs[%d].Index() = %d, want %d", i, e.Index(), i+1)
+		}
+	}
+}
+
+func TestValidatePostInputSchemaAll(t *testing.T) {
+	input := &PostInput{
+		CreateNew: true,
+		Name:      "Test <!-- x",
+		Category:  "LLM/Tasks/2026/01/31",
+		Body: Body{
+			Background: "Background",
+			Tasks: []Task{
+				{ID: "task-1", Title: "Task 1: First", Status: "done", Summary: []string{"ok"}, Description: "desc"},
+			},
+		},
+	}
+
+	errs := ValidatePostInputSchemaAll(input)
+	if len(errs) != 2 {
+		t.Fatalf("len(errs) = %d, want 2: %v", len(errs), errs)
+	}
+	if errs[0].Field() != "name" || errs[0].Code() != ErrCodeFieldInvalidChars {
+		t.Errorf("errs[0] = %s/%s, want name/field_invalid_chars", errs[0].Field(), errs[0].Code())
+	}
+	if errs[1].Field() != "task.status" || errs[1].Index() != 0 {
+		t.Errorf("errs[1] field/index = %s/%d, want task.status/0", errs[1].Field(), errs[1].Index())
+	}
+}
+
+func TestSchemaField(t *testing.T) {
+	tests := []struct {
+		location  string
+		wantField string
+		wantIndex int
+	}{
+		{"", "", -1},
+		{"/name", "name", -1},
+		{"/body/background", "background", -1},
+		{"/body/tasks", "tasks", -1},
+		{"/body/tasks/2", "tasks", 2},
+		{"/body/tasks/2/summary", "task.summary", 2},
+		{"/body/tasks/0/summary/1", "task.summary", 0},
+		{"/body/related_links/3", "related_links", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			field, index := schemaField(tt.location)
+			if field != tt.wantField || index != tt.wantIndex {
+				t.Errorf("schemaField(%q) = (%q, %d), want (%q, %d)", tt.location, field, index, tt.wantField, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestSchemaErrorCode(t *testing.T) {
+	tests := []struct {
+		keywordLocation string
+		want            ValidationErrorCode
+	}{
+		{"/properties/name/minLength", ErrCodeFieldEmpty},
+		{"/properties/body/properties/tasks/items/properties/summary/maxItems", ErrCodeFieldTooLong},
+		{"/properties/team/pattern", ErrCodeFieldInvalidFormat},
+		{"/properties/body/required", ErrCodeMissingRequired},
+		{"/properties/body/properties/tasks/items/properties/status/enum", ErrCodeInvalidValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keywordLocation, func(t *testing.T) {
+			if got := schemaErrorCode(tt.keywordLocation); got != tt.want {
+				t.Errorf("schemaErrorCode(%q) = %s, want %s", tt.keywordLocation, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePostInput_RevisionNumber(t *testing.T) {
+	postNumber := 1
+	zero := 0
+	three := 3
+	tests := []struct {
+		name     string
+		mutate   func(*PostInput)
+		wantCode ValidationErrorCode
+	}{
+		{"post_numberと併用", func(in *PostInput) { in.PostNumber = &postNumber; in.RevisionNumber = &three }, ""},
+		{"create_newと併用", func(in *PostInput) { in.CreateNew = true; in.RevisionNumber = &three }, ErrCodeInvalidValue},
+		{"0以下", func(in *PostInput) { in.PostNumber = &postNumber; in.RevisionNumber = &zero }, ErrCodeInvalidValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &PostInput{
+				Name:     "Test",
+				Category: "LLM/Tasks/2026/01/28",
+				Body: Body{
+					Background: "bg",
+					Tasks:      []Task{{ID: "task-1", Title: "Task 1: Test", Status: TaskStatusNotStarted, Summary: []string{"s"}, Description: "d"}},
+				},
+			}
+			tt.mutate(input)
+			err := ValidatePostInput(input)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || ve.Code() != tt.wantCode || ve.Field() != "revision_number" {
+				t.Errorf("got %v, want %s on revision_number", err, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package hook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+// PreToolUse はClaude CodeのPreToolUseフックのイベント名
+const PreToolUse = "PreToolUse"
+
+// maxEventSize は受け付けるフックイベントの最大サイズ
+// 記事本文（10MB上限）をエスケープ込みで受け取れるよう余裕を持たせる
+const maxEventSize = 2 * guard.MaxInputSize
+
+// 判定結果（permissionDecision）
+const (
+	DecisionAllow = "allow"
+	DecisionDeny  = "deny"
+)
+
+// guardedCommandHint は拒否したときにエージェントに案内する書き込み方法
+const guardedCommandHint = "write esa posts with `esa-llm-scoped-guard post -json <file>` instead, which enforces allowed_categories"
+
+// Event はPreToolUseフックが標準入力から受け取るイベント
+type Event struct {
+	HookEventName string                     `json:"hook_event_name"`
+	ToolName      string                     `json:"tool_name"`
+	ToolInput     map[string]json.RawMessage `json:"tool_input"`
+}
+
+// Output はフックの標準出力に書き込む判定
+type Output struct {
+	HookSpecificOutput SpecificOutput `json:"hookSpecificOutput"`
+}
+
+// SpecificOutput はPreToolUseフックの判定の中身
+type SpecificOutput struct {
+	HookEventName            string `json:"hookEventName"`
+	PermissionDecision       string `json:"permissionDecision"`
+	PermissionDecisionReason string `json:"permissionDecisionReason"`
+}
+
+// PostGetter は更新・削除の対象の既存記事を取得する関数
+type PostGetter func(postNumber int) (*esa.Post, error)
+
+// operation はesa MCPサーバーのツールが行う書き込みの種類
+type operation string
+
+const (
+	operationCreate operation = "create"
+	operationUpdate operation = "update"
+	operationMove   operation = "move"
+	operationDelete operation = "delete"
+)
+
+// ReadEvent はフックイベントを読み込みます
+func ReadEvent(r io.Reader) (*Event, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxEventSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hook event: %w", err)
+	}
+	if len(data) > maxEventSize {
+		return nil, fmt.Errorf("hook event exceeds %d bytes", maxEventSize)
+	}
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("invalid hook event: %w", err)
+	}
+	if event.HookEventName != PreToolUse {
+		return nil, fmt.Errorf("unsupported hook event %q (register the hook for %s)", event.HookEventName, PreToolUse)
+	}
+	if event.ToolName == "" {
+		return nil, errors.New("hook event has no tool_name")
+	}
+	return &event, nil
+}
+
+// Allow は書き込みを許可する判定を返します
+func Allow(reason string) *Output {
+	return &Output{HookSpecificOutput: SpecificOutput{HookEventName: PreToolUse, PermissionDecision: DecisionAllow, PermissionDecisionReason: reason}}
+}
+
+// Deny は書き込みを拒否する判定を返します（理由にはガード付きのpostコマンドの案内を付ける）
+func Deny(err error) *Output {
+	reason := fmt.Sprintf("esa-llm-scoped-guard blocked this esa write: %v; %s", err, guardedCommandHint)
+	return &Output{HookSpecificOutput: SpecificOutput{HookEventName: PreToolUse, PermissionDecision: DecisionDeny, PermissionDecisionReason: reason}}
+}
+
+// Evaluate はesa MCPサーバーのツール呼び出しを判定します
+// 作成は指定カテゴリ、更新・移動・削除は既存記事のカテゴリ（getPostで取得）でその操作をpolicyが許可するかを確認し、
+// 更新・移動でのカテゴリ変更は ValidateUpdateRequest で拒否する。repositoryTag（リポジトリにカテゴリを割り当てている場合の
+// リポジトリのタグ）が空でなければ、作成にはそのタグを付けさせ、更新・移動・削除はそのタグが付いた記事に限る。
+// 読み取りのツール（get_/search_/list_）には判定せず nil を返し、通常の権限確認に任せる。
+// それ以外の認識できないツールは拒否する（fail closed）
+func Evaluate(event *Event, team, repositoryTag string, policy *guard.Policy, getPost PostGetter) *Output {
+	tool := toolAction(event.ToolName)
+	if isReadTool(tool) {
+		return nil
+	}
+	op, ok := toolOperations[tool]
+	if !ok {
+		return Deny(fmt.Errorf("tool %s is not a write the guard can check", event.ToolName))
+	}
+
+	args := arguments(event.ToolInput)
+	if err := checkTeam(args, team); err != nil {
+		return Deny(err)
+	}
+	reason, err := evaluate(op, args, repositoryTag, policy, getPost)
+	if err != nil {
+		return Deny(err)
+	}
+	return Allow(reason)
+}
+
+// toolOperations は認識する書き込みツール（esa_ プレフィックスを除いた名前）と書き込みの種類
+var toolOperations = map[string]operation{
+	"create_post": operationCreate,
+	"update_post": operationUpdate,
+	"move_post":   operationMove,
+	"delete_post": operationDelete,
+}
+
+// toolAction は mcp__<server>__esa_update_post のようなツール名から update_post を取り出します
+func toolAction(toolName string) string {
+	name := toolName
+	if strings.HasPrefix(name, "mcp__") {
+		if i := strings.LastIndex(name, "__"); i >= len("mcp__") {
+			name = name[i+len("__"):]
+		}
+	}
+	return strings.TrimPrefix(name, "esa_")
+}
+
+// isReadTool は読み取り専用のツールかを返します
+func isReadTool(tool string) bool {
+	for _, prefix := range []string{"get_", "search_", "list_"} {
+		if strings.HasPrefix(tool, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func evaluate(op operation, args arguments, repositoryTag string, policy *guard.Policy, getPost PostGetter) (string, error) {
+	// esa.ioは記事名の "/" をカテゴリの区切りとして扱うため、記事名でカテゴリを指定させない
+	name, _, err := args.stringArg("name")
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(name, "/") {
+		return "", guard.NewValidationError(guard.ErrCodeCategoryInvalidPath, "post name must not contain \"/\" (set the category separately)").
+			WithField("name")
+	}
+	category, hasCategory, err := args.stringArg("category")
+	if err != nil {
+		return "", err
+	}
+
+	// リポジトリのタグはツールの引数を書き換えられないため、付けていなければ拒否して付けさせる
+	// （更新でタグを置き換える場合も外させない）
+	tags, hasTags, err := args.stringsArg("tags")
+	if err != nil {
+		return "", err
+	}
+	if repositoryTag != "" && (op == operationCreate || hasTags) && !slices.Contains(tags, repositoryTag) {
+		return "", guard.NewValidationError(guard.ErrCodeRepositoryTagMissing,
+			fmt.Sprintf("tags must include %s (posts written from this repository are tagged with its name)", repositoryTag)).
+			WithField("tags")
+	}
+
+	if op == operationCreate {
+		return checkOperation(policy, "category", category, guard.OperationCreate)
+	}
+
+	number, err := args.postNumber()
+	if err != nil {
+		return "", err
+	}
+	existing, err := getPost(number)
+	if err != nil {
+		return "", fmt.Errorf("failed to get post %d: %w", number, err)
+	}
+	if repositoryTag != "" {
+		if err := guard.ValidateRepositoryTag(existing.Tags, repositoryTag, number); err != nil {
+			return "", err
+		}
+	}
+
+	if op == operationDelete {
+		return checkOperation(policy, fmt.Sprintf("post %d in category", number), existing.Category, guard.OperationDelete)
+	}
+
+	// カテゴリを指定しない更新は既存のカテゴリのまま。移動はカテゴリの指定が必須
+	if !hasCategory {
+		if op == operationMove {
+			return "", guard.NewValidationError(guard.ErrCodeMissingRequired, "move requires a category").WithField("category")
+		}
+		category = existing.Category
+	}
+	if err := guard.ValidateUpdateRequest(existing.Category, category, policy); err != nil {
+		return "", err
+	}
+	return checkOperation(policy, fmt.Sprintf("post %d in category", number), existing.Category, guard.OperationUpdate)
+}
+
+// checkOperation はカテゴリに対する操作をpolicyで判定し、許可した場合は判定を決めたルールを含む理由を返します
+func checkOperation(policy *guard.Policy, subject, category string, op guard.Operation) (string, error) {
+	if err := policy.Check(subject, category, op); err != nil {
+		return "", err
+	}
+	decision, err := policy.Evaluate(category, op)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s: %s", subject, category, decision.Reason()), nil
+}
+
+// checkTeam はツールの引数のチームが設定のチームと一致するかを確認します
+// 記事番号はチームごとの番号のため、チームを省略した呼び出し（esa MCPサーバーの既定のチームに書き込む）は
+// 設定のチームと照合できず、別のチームの記事を判定してしまうため拒否する（fail closed）
+func checkTeam(args arguments, team string) error {
+	teamName, ok, err := args.stringArg("teamName", "team_name", "team")
+	if err != nil {
+		return err
+	}
+	if !ok || teamName == "" {
+		return guard.NewValidationError(guard.ErrCodeMissingRequired, fmt.Sprintf("team is required to verify the write goes to the configured team %s (set teamName)", team)).
+			WithField("team_name")
+	}
+	if teamName != team {
+		return guard.NewValidationError(guard.ErrCodeInvalidValue, fmt.Sprintf("team %q is not the configured team %s", teamName, team)).
+			WithField("team_name")
+	}
+	return nil
+}
+
+// arguments はツールの引数（tool_input）
+type arguments map[string]json.RawMessage
+
+// lookup は名前のいずれかで指定された引数を返します
+// 同じ引数が複数の名前（postNumber と post_number など）で指定されていればエラー（どちらが使われるか分からないため）
+func (a arguments) lookup(names ...string) (json.RawMessage, string, error) {
+	var value json.RawMessage
+	var found string
+	for _, name := range names {
+		raw, ok := a[name]
+		if !ok {
+			continue
+		}
+		if found != "" {
+			return nil, "", guard.NewValidationError(guard.ErrCodeMutuallyExclusive, fmt.Sprintf("both %s and %s are given", found, name)).WithField(name)
+		}
+		value, found = raw, name
+	}
+	return value, found, nil
+}
+
+// stringArg は文字列の引数を返します（nullは未指定として扱う）
+func (a arguments) stringArg(names ...string) (string, bool, error) {
+	raw, name, err := a.lookup(names...)
+	if err != nil || name == "" || string(raw) == "null" {
+		return "", false, err
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", false, guard.NewValidationError(guard.ErrCodeInvalidValue, fmt.Sprintf("%s must be a string", name)).WithField(name)
+	}
+	return s, true, nil
+}
+
+// stringsArg は文字列の配列の引数を返します（nullは未指定として扱う）
+func (a arguments) stringsArg(names ...string) ([]string, bool, error) {
+	raw, name, err := a.lookup(names...)
+	if err != nil || name == "" || string(raw) == "null" {
+		return nil, false, err
+	}
+	var values []string
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, false, guard.NewValidationError(guard.ErrCodeInvalidValue, fmt.Sprintf("%s must be an array of strings", name)).WithField(name)
+	}
+	return values, true, nil
+}
+
+// postNumber は対象の記事番号を返します（数値・数字の文字列のどちらも受け付ける）
+func (a arguments) postNumber() (int, error) {
+	raw, name, err := a.lookup("postNumber", "post_number", "number")
+	if err != nil {
+		return 0, err
+	}
+	if name == "" {
+		return 0, guard.NewValidationError(guard.ErrCodeMissingRequired, "post number is required").WithField("post_number")
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		raw = json.RawMessage(s)
+	}
+	number, err := strconv.Atoi(string(raw))
+	if err != nil || number <= 0 {
+		return 0, guard.NewValidationError(guard.ErrCodeInvalidValue, fmt.Sprintf("%s must be a positive integer", name)).WithField(name)
+	}
+	return number, nil
+}
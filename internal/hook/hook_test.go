@@ -0,0 +1,156 @@
+package hook
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+func TestEvaluate(t *testing.T) {
+	posts := map[int]*esa.Post{
+		1: {Number: 1, Name: "Plan", Category: "LLM/Tasks/2026/01/28"},
+		2: {Number: 2, Name: "Secret", Category: "Private/Notes"},
+		3: {Number: 3, Name: "Token", Category: "LLM/Tasks/Secrets"},
+	}
+	getPost := func(postNumber int) (*esa.Post, error) {
+		if post, ok := posts[postNumber]; ok {
+			return post, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	policy := guard.NewPolicy([]string{"LLM/Tasks"}).Deny("LLM/Tasks/Secrets")
+
+	tests := []struct {
+		name     string
+		toolName string
+		input    string
+		want     string // 空なら判定しない
+	}{
+		{"許可カテゴリへの作成", "mcp__esa__esa_create_post", `{"teamName": "test-team", "name": "New", "category": "LLM/Tasks/2026/01/29", "bodyMd": "x"}`, DecisionAllow},
+		{"許可カテゴリ外への作成", "mcp__esa__esa_create_post", `{"teamName": "test-team", "name": "New", "category": "Private/Notes"}`, DecisionDeny},
+		{"境界を越えるカテゴリへの作成", "mcp__esa__esa_create_post", `{"teamName": "test-team", "name": "New", "category": "LLM/Tasks-evil"}`, DecisionDeny},
+		{"カテゴリなしの作成", "mcp__esa__esa_create_post", `{"teamName": "test-team", "name": "New"}`, DecisionDeny},
+		{"記事名でカテゴリを指定する作成", "mcp__esa__esa_create_post", `{"teamName": "test-team", "name": "Private/Notes/New", "category": "LLM/Tasks"}`, DecisionDeny},
+		{"別のチームへの作成", "mcp__esa__esa_create_post", `{"teamName": "other-team", "name": "New", "category": "LLM/Tasks"}`, DecisionDeny},
+		{"チームを省略した作成", "mcp__esa__esa_create_post", `{"name": "New", "category": "LLM/Tasks/2026/01/29"}`, DecisionDeny},
+		{"チームを省略した更新", "mcp__esa__esa_update_post", `{"postNumber": 1, "bodyMd": "x"}`, DecisionDeny},
+		{"空のチームでの削除", "mcp__esa__esa_delete_post", `{"teamName": "", "postNumber": 1}`, DecisionDeny},
+		{"snake_caseのチームでの更新", "mcp__esa__esa_update_post", `{"team_name": "test-team", "postNumber": 1, "bodyMd": "x"}`, DecisionAllow},
+		{"許可カテゴリ内の更新", "mcp__esa__esa_update_post", `{"teamName": "test-team", "postNumber": 1, "bodyMd": "x"}`, DecisionAllow},
+		{"snake_caseの引数での更新", "mcp__esa__update_post", `{"teamName": "test-team", "post_number": "1", "body_md": "x"}`, DecisionAllow},
+		{"同じカテゴリを指定した更新", "mcp__esa__esa_update_post", `{"teamName": "test-team", "postNumber": 1, "category": "LLM/Tasks/2026/01/28"}`, DecisionAllow},
+		{"更新でのカテゴリの移動", "mcp__esa__esa_update_post", `{"teamName": "test-team", "postNumber": 1, "category": "LLM/Tasks/2026/01/29"}`, DecisionDeny},
+		{"許可カテゴリ外の記事の更新", "mcp__esa__esa_update_post", `{"teamName": "test-team", "postNumber": 2, "bodyMd": "x"}`, DecisionDeny},
+		{"存在しない記事の更新", "mcp__esa__esa_update_post", `{"teamName": "test-team", "postNumber": 99, "bodyMd": "x"}`, DecisionDeny},
+		{"記事番号なしの更新", "mcp__esa__esa_update_post", `{"teamName": "test-team", "bodyMd": "x"}`, DecisionDeny},
+		{"記事番号を二通りに指定した更新", "mcp__esa__esa_update_post", `{"teamName": "test-team", "postNumber": 1, "post_number": 2}`, DecisionDeny},
+		{"移動", "mcp__esa__esa_move_post", `{"teamName": "test-team", "postNumber": 1, "category": "Private/Notes"}`, DecisionDeny},
+		{"許可カテゴリ内の記事の削除", "mcp__esa__esa_delete_post", `{"teamName": "test-team", "postNumber": 1}`, DecisionAllow},
+		{"許可カテゴリ外の記事の削除", "mcp__esa__esa_delete_post", `{"teamName": "test-team", "postNumber": 2}`, DecisionDeny},
+		{"拒否カテゴリへの作成", "mcp__esa__esa_create_post", `{"teamName": "test-team", "name": "New", "category": "LLM/Tasks/Secrets/2026"}`, DecisionDeny},
+		{"拒否カテゴリの記事の更新", "mcp__esa__esa_update_post", `{"teamName": "test-team", "postNumber": 3, "bodyMd": "x"}`, DecisionDeny},
+		{"読み取り", "mcp__esa__esa_get_post", `{"postNumber": 2}`, ""},
+		{"検索", "mcp__esa__esa_search_posts", `{"query": "in:Private"}`, ""},
+		{"認識できない書き込み", "mcp__esa__esa_create_comment", `{"teamName": "test-team", "postNumber": 2, "bodyMd": "x"}`, DecisionDeny},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.input), &input); err != nil {
+				t.Fatal(err)
+			}
+			event := &Event{HookEventName: PreToolUse, ToolName: tt.toolName, ToolInput: input}
+			output := Evaluate(event, "test-team", "", policy, getPost)
+
+			if tt.want == "" {
+				if output != nil {
+					t.Errorf("Evaluate() = %+v, want no decision", output.HookSpecificOutput)
+				}
+				return
+			}
+			if output == nil {
+				t.Fatalf("Evaluate() = nil, want %s", tt.want)
+			}
+			got := output.HookSpecificOutput
+			if got.PermissionDecision != tt.want {
+				t.Errorf("decision = %s (%s), want %s", got.PermissionDecision, got.PermissionDecisionReason, tt.want)
+			}
+			if got.HookEventName != PreToolUse {
+				t.Errorf("hookEventName = %s", got.HookEventName)
+			}
+			// 拒否した場合はガード付きのpostコマンドを案内する
+			if tt.want == DecisionDeny && !strings.Contains(got.PermissionDecisionReason, "esa-llm-scoped-guard post") {
+				t.Errorf("reason = %s, want a pointer to the post command", got.PermissionDecisionReason)
+			}
+		})
+	}
+}
+
+func TestEvaluate_RepositoryTag(t *testing.T) {
+	posts := map[int]*esa.Post{
+		1: {Number: 1, Name: "Plan", Category: "LLM/Tasks/repo-a", Tags: []string{"repo-a"}},
+		2: {Number: 2, Name: "Other", Category: "LLM/Tasks/repo-a", Tags: []string{"repo-b"}},
+	}
+	getPost := func(postNumber int) (*esa.Post, error) {
+		if post, ok := posts[postNumber]; ok {
+			return post, nil
+		}
+		return nil, errors.New("not found")
+	}
+	// リポジトリに割り当てたカテゴリに狭めたポリシー（割り当て前のカテゴリは読み取り専用）
+	policy := guard.NewPolicy([]string{"LLM/Tasks/repo-a"}).AllowRead("LLM/Tasks")
+
+	tests := []struct {
+		name     string
+		toolName string
+		input    string
+		want     string
+	}{
+		{"タグ付きの作成", "mcp__esa__esa_create_post", `{"teamName": "test-team", "name": "New", "category": "LLM/Tasks/repo-a", "tags": ["repo-a"]}`, DecisionAllow},
+		{"タグなしの作成", "mcp__esa__esa_create_post", `{"teamName": "test-team", "name": "New", "category": "LLM/Tasks/repo-a"}`, DecisionDeny},
+		{"割り当て外のカテゴリへの作成", "mcp__esa__esa_create_post", `{"teamName": "test-team", "name": "New", "category": "LLM/Tasks/repo-b", "tags": ["repo-a"]}`, DecisionDeny},
+		{"タグ付きの記事の更新", "mcp__esa__esa_update_post", `{"teamName": "test-team", "postNumber": 1, "bodyMd": "x"}`, DecisionAllow},
+		{"更新でタグを外す", "mcp__esa__esa_update_post", `{"teamName": "test-team", "postNumber": 1, "tags": ["other"]}`, DecisionDeny},
+		{"別のリポジトリのタグの記事の更新", "mcp__esa__esa_update_post", `{"teamName": "test-team", "postNumber": 2, "bodyMd": "x"}`, DecisionDeny},
+		{"別のリポジトリのタグの記事の削除", "mcp__esa__esa_delete_post", `{"teamName": "test-team", "postNumber": 2}`, DecisionDeny},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.input), &input); err != nil {
+				t.Fatal(err)
+			}
+			event := &Event{HookEventName: PreToolUse, ToolName: tt.toolName, ToolInput: input}
+			got := Evaluate(event, "test-team", "repo-a", policy, getPost).HookSpecificOutput
+			if got.PermissionDecision != tt.want {
+				t.Errorf("decision = %s (%s), want %s", got.PermissionDecision, got.PermissionDecisionReason, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"PreToolUse", `{"hook_event_name": "PreToolUse", "tool_name": "mcp__esa__esa_create_post", "tool_input": {}}`, false},
+		{"PostToolUse", `{"hook_event_name": "PostToolUse", "tool_name": "mcp__esa__esa_create_post"}`, true},
+		{"tool_nameなし", `{"hook_event_name": "PreToolUse"}`, true},
+		{"JSONでない", `PreToolUse`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadEvent(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
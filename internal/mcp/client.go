@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+// Client はServeと同じ改行区切りのJSON-RPCでツールを呼び出すクライアント
+// daemonに接続したCLI（クライアントモード）が使う。並行して呼び出してはならない
+type Client struct {
+	w       io.Writer
+	scanner *bufio.Scanner
+	nextID  int
+}
+
+// NewClient はrwで通信する新しいClientを作成します
+func NewClient(rw io.ReadWriter) *Client {
+	scanner := bufio.NewScanner(rw)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	return &Client{w: rw, scanner: scanner}
+}
+
+// Initialize はinitializeリクエストとinitialized通知を送ります
+func (c *Client) Initialize() error {
+	var result struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if err := c.call("initialize", map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"clientInfo":      map[string]interface{}{"name": serverName, "version": "0.0.0"},
+	}, &result); err != nil {
+		return err
+	}
+	return c.send(request{JSONRPC: "2.0", Method: "notifications/initialized"})
+}
+
+// CallTool はツールを呼び出し、structuredContentをresultにデコードします
+// ツールがエラーを返した場合は、サーバー側のエラーの種類・バリデーションエラーのコードを引き継いだエラーを返す
+func (c *Client) CallTool(name string, arguments interface{}, result interface{}) error {
+	var toolResult struct {
+		StructuredContent json.RawMessage `json:"structuredContent"`
+		IsError           bool            `json:"isError"`
+	}
+	if err := c.call("tools/call", map[string]interface{}{"name": name, "arguments": arguments}, &toolResult); err != nil {
+		return err
+	}
+
+	if toolResult.IsError {
+		var content struct {
+			Error *guard.ErrorDetail `json:"error"`
+		}
+		if err := json.Unmarshal(toolResult.StructuredContent, &content); err != nil || content.Error == nil {
+			return fmt.Errorf("tool %s failed without error details", name)
+		}
+		return guard.ErrorFromDetail(*content.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(toolResult.StructuredContent, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", name, err)
+	}
+	return nil
+}
+
+// call はリクエストを送り、対応するレスポンスのresultをresultにデコードします
+func (c *Client) call(method string, params interface{}, result interface{}) error {
+	c.nextID++
+	id := json.RawMessage(fmt.Sprintf("%d", c.nextID))
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s params: %w", method, err)
+	}
+	if err := c.send(request{JSONRPC: "2.0", ID: id, Method: method, Params: rawParams}); err != nil {
+		return err
+	}
+
+	// 通知は送らないサーバーなので、次のメッセージがこのリクエストへの応答
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read %s response: %w", method, err)
+		}
+		return fmt.Errorf("failed to read %s response: %w", method, io.ErrUnexpectedEOF)
+	}
+	var resp struct {
+		ID     json.RawMessage `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+		return fmt.Errorf("invalid %s response: %w", method, err)
+	}
+	if string(resp.ID) != string(id) {
+		return fmt.Errorf("unexpected response id %s for %s (want %s)", resp.ID, method, id)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s failed: %s (code %d)", method, resp.Error.Message, resp.Error.Code)
+	}
+	if len(resp.Result) == 0 {
+		return errors.New(method + " response has no result")
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("invalid %s result: %w", method, err)
+	}
+	return nil
+}
+
+// send は1メッセージを1行で書き込みます
+func (c *Client) send(req request) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", req.Method, err)
+	}
+	if _, err := c.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to send %s: %w", req.Method, err)
+	}
+	return nil
+}
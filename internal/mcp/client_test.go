@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+// newTestClient はServeContextで応答するサーバーに接続したClientを返す
+func newTestClient(t *testing.T, s *Server) *Client {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.ServeContext(ctx, serverConn, serverConn)
+		serverConn.Close()
+	}()
+	t.Cleanup(func() {
+		cancel()
+		clientConn.Close()
+		<-done
+	})
+
+	c := NewClient(clientConn)
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	return c
+}
+
+func TestClient_CallTool(t *testing.T) {
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), &mockEsaClient{
+		createPostFunc: func(input *esa.PostInput) (*esa.Post, error) {
+			return &esa.Post{Number: 7, URL: "https://test.esa.io/posts/7", RevisionNumber: 1}, nil
+		},
+	})
+	c := newTestClient(t, s)
+
+	var out struct {
+		PostNumber int  `json:"post_number"`
+		Created    bool `json:"created"`
+	}
+	if err := c.CallTool("post", json.RawMessage(validCreateArgs), &out); err != nil {
+		t.Fatalf("CallTool(post) error = %v", err)
+	}
+	if out.PostNumber != 7 || !out.Created {
+		t.Errorf("post result = %+v", out)
+	}
+
+	// 同じ接続で続けて呼び出せる
+	if err := c.CallTool("validate", json.RawMessage(validCreateArgs), nil); err != nil {
+		t.Errorf("CallTool(validate) error = %v", err)
+	}
+}
+
+func TestClient_CallToolError(t *testing.T) {
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), &mockEsaClient{
+		getPostFunc: func(number int) (*esa.Post, error) {
+			return nil, &esa.APIError{StatusCode: 404, Code: "not_found", Message: "Not found"}
+		},
+	})
+	c := newTestClient(t, s)
+
+	tests := []struct {
+		name      string
+		tool      string
+		args      string
+		wantKind  guard.ErrorKind
+		wantCode  guard.ValidationErrorCode
+		wantField string
+	}{
+		{"カテゴリ外への投稿", "post", `{"create_new":true,"name":"Test Post","category":"Other/2026/01/28","body":{"background":"b","tasks":[{"id":"task-1","title":"Task 1: Test","status":"not_started","summary":["s"],"description":"d"}]}}`, guard.ErrorKindValidation, guard.ErrCodeCategoryNotAllowed, "category"},
+		{"存在しない記事", "fetch", `{"post_number":99}`, guard.ErrorKindNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.CallTool(tt.tool, json.RawMessage(tt.args), nil)
+			if err == nil {
+				t.Fatal("CallTool() error = nil")
+			}
+			if got := guard.ErrorKindOf(err); got != tt.wantKind {
+				t.Errorf("ErrorKindOf() = %v, want %v", got, tt.wantKind)
+			}
+			var ve *guard.ValidationError
+			if tt.wantCode != "" && (!errors.As(err, &ve) || ve.Code() != tt.wantCode || ve.Field() != tt.wantField) {
+				t.Errorf("error = %v, want code %s field %s", err, tt.wantCode, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestClient_ServerClosed(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	// リクエストを読んだだけで応答せずに切断する
+	go func() {
+		bufio.NewReader(serverConn).ReadBytes('\n')
+		serverConn.Close()
+	}()
+	defer clientConn.Close()
+
+	if err := NewClient(clientConn).Initialize(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Initialize() error = %v, want unexpected EOF", err)
+	}
+}
@@ -0,0 +1,288 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+const (
+	// ProtocolVersion はサーバーが既定で応答するMCPプロトコルバージョン
+	ProtocolVersion = "2025-06-18"
+
+	// serverName はinitialize応答で返すサーバー名
+	serverName = "esa-llm-scoped-guard"
+
+	// maxMessageSize は1メッセージ（1行）の最大サイズ
+	// 入力JSON（10MB上限）をエスケープ込みで受け取れるよう余裕を持たせる
+	maxMessageSize = 2 * guard.MaxInputSize
+)
+
+// supportedProtocolVersions はサーバーが対応しているMCPプロトコルバージョン（新しい順）
+var supportedProtocolVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// JSON-RPC 2.0 エラーコード
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// request はJSON-RPCリクエスト/通知
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response はJSON-RPCレスポンス
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError はJSON-RPCのエラーオブジェクト
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Server はstdio上でMCPを話すサーバー
+// ツール呼び出しはすべてguardパッケージの検証・カテゴリ制限を経由する
+type Server struct {
+	policy        *guard.Policy
+	audit         *guard.AuditLog
+	repositoryTag string
+	team          string
+	client        esa.EsaClientInterface
+	tools         []tool
+}
+
+// NewServer は新しいServerを作成します（ツールで許可する操作はpolicyで判定する）
+func NewServer(policy *guard.Policy, client esa.EsaClientInterface) *Server {
+	s := &Server{
+		policy: policy,
+		client: client,
+	}
+	s.tools = s.buildTools()
+	return s
+}
+
+// SetAuditLog はpost/patchによる書き込みを記録する監査ログを設定します
+func (s *Server) SetAuditLog(audit *guard.AuditLog) {
+	s.audit = audit
+}
+
+// SetRepositoryTag はリポジトリにカテゴリを割り当てている場合のリポジトリのタグを設定します
+// post/patchで作成する記事にはこのタグを付け、更新はこのタグが付いた記事に限る
+func (s *Server) SetRepositoryTag(tag string) {
+	s.repositoryTag = tag
+}
+
+// SetTeam は設定のesa.ioチーム名を設定します
+// postは入力JSONのteamがこれと異なれば拒否し、埋め込みJSONにはこのチームを記録する
+func (s *Server) SetTeam(team string) {
+	s.team = team
+}
+
+// postOptions はpost/patchで使う投稿オプションを返します
+func (s *Server) postOptions() guard.PostOptions {
+	return guard.PostOptions{Audit: s.audit, RepositoryTag: s.repositoryTag, Team: s.team}
+}
+
+// Serve は改行区切りのJSON-RPCメッセージをrから読み、応答をwに書き込みます
+// rがEOFに達すると nil を返します
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	return s.ServeContext(context.Background(), r, w)
+}
+
+// ServeContext はServeと同じですが、ctxがキャンセルされると新しいメッセージを読まずに nil を返します
+// ctxはツールのesa.io APIの呼び出しにも渡すため、処理中の呼び出しは中断され、そのエラーを応答してから終了する
+func (s *Server) ServeContext(ctx context.Context, r io.Reader, w io.Writer) error {
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	encoder := json.NewEncoder(w)
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		var line []byte
+		select {
+		case <-ctx.Done():
+			return nil
+		case l, ok := <-lines:
+			if !ok {
+				return readError(<-readErr)
+			}
+			line = l
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		resp := s.handleMessage(ctx, line)
+		if resp == nil {
+			continue // 通知には応答しない
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return fmt.Errorf("failed to write response: %w", err)
+		}
+	}
+}
+
+// readError はメッセージの読み込みエラーを返します（EOFで終わった場合はnil）
+func readError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, bufio.ErrTooLong) {
+		return fmt.Errorf("message exceeds %d bytes", maxMessageSize)
+	}
+	return fmt.Errorf("failed to read request: %w", err)
+}
+
+// handleMessage は1メッセージを処理し、応答を返します（通知の場合はnil）
+func (s *Server) handleMessage(ctx context.Context, line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), codeParseError, fmt.Sprintf("parse error: %v", err))
+	}
+
+	// idがないものは通知
+	isNotification := len(req.ID) == 0
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if isNotification {
+			return nil
+		}
+		return errorResponse(req.ID, codeInvalidRequest, "invalid request")
+	}
+
+	result, rpcErr := s.dispatch(ctx, req.Method, req.Params)
+	if isNotification {
+		return nil
+	}
+	if rpcErr != nil {
+		return &response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// dispatch はメソッド名に応じて処理を振り分けます
+func (s *Server) dispatch(ctx context.Context, method string, params json.RawMessage) (interface{}, *rpcError) {
+	switch method {
+	case "initialize":
+		return s.handleInitialize(params)
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return s.handleToolsList()
+	case "tools/call":
+		return s.handleToolsCall(ctx, params)
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", method)}
+	}
+}
+
+// handleInitialize はinitializeリクエストに応答します
+func (s *Server) handleInitialize(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+		}
+	}
+
+	// 対応しているバージョンならそのまま、それ以外（未指定を含む）は対応している最新のバージョンで応答する
+	version := ProtocolVersion
+	if slices.Contains(supportedProtocolVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{},
+		},
+		"serverInfo": map[string]interface{}{
+			"name":    serverName,
+			"version": "0.0.0",
+		},
+	}, nil
+}
+
+// handleToolsList はtools/listリクエストに応答します
+func (s *Server) handleToolsList() (interface{}, *rpcError) {
+	defs := make([]toolDefinition, 0, len(s.tools))
+	for _, t := range s.tools {
+		defs = append(defs, t.definition)
+	}
+	return map[string]interface{}{"tools": defs}, nil
+}
+
+// handleToolsCall はtools/callリクエストを処理します
+// ツール実行時のエラーはJSON-RPCエラーではなく isError=true のツール結果として返す
+func (s *Server) handleToolsCall(ctx context.Context, params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+
+	for _, t := range s.tools {
+		if t.definition.Name == p.Name {
+			arguments := p.Arguments
+			if len(arguments) == 0 {
+				arguments = json.RawMessage("{}")
+			}
+			structured, err := t.handler(ctx, arguments)
+			if err != nil {
+				return errorResult(err), nil
+			}
+			return successResult(structured), nil
+		}
+	}
+
+	return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", p.Name)}
+}
+
+// errorResponse はJSON-RPCエラーレスポンスを作成します
+func errorResponse(id json.RawMessage, code int, message string) *response {
+	return &response{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &rpcError{Code: code, Message: message},
+	}
+}
@@ -0,0 +1,378 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+type mockEsaClient struct {
+	createPostFunc func(*esa.PostInput) (*esa.Post, error)
+	updatePostFunc func(int, *esa.PostInput) (*esa.Post, error)
+	getPostFunc    func(int) (*esa.Post, error)
+}
+
+func (m *mockEsaClient) ListPosts(ctx context.Context, q string, page, perPage int) (*esa.PostList, error) {
+	return nil, fmt.Errorf("ListPosts should not be called")
+}
+
+func (m *mockEsaClient) CreatePost(ctx context.Context, input *esa.PostInput) (*esa.Post, error) {
+	if m.createPostFunc != nil {
+		return m.createPostFunc(input)
+	}
+	return nil, fmt.Errorf("CreatePost should not be called")
+}
+
+func (m *mockEsaClient) UpdatePost(ctx context.Context, number int, input *esa.PostInput) (*esa.Post, error) {
+	if m.updatePostFunc != nil {
+		return m.updatePostFunc(number, input)
+	}
+	return nil, fmt.Errorf("UpdatePost should not be called")
+}
+
+func (m *mockEsaClient) GetPost(ctx context.Context, number int) (*esa.Post, error) {
+	if m.getPostFunc != nil {
+		return m.getPostFunc(number)
+	}
+	return nil, fmt.Errorf("GetPost should not be called")
+}
+
+const validCreateArgs = `{"create_new":true,"name":"Test Post","category":"LLM/Tasks/2026/01/28","body":{"background":"Test background","tasks":[{"id":"task-1","title":"Task 1: Test","status":"not_started","summary":["Test summary"],"description":"Test description"}]}}`
+
+// roundTrip はリクエストを1行ずつ送り、レスポンスをデコードして返す
+func roundTrip(t *testing.T, s *Server, requests ...string) []map[string]interface{} {
+	t.Helper()
+	in := strings.NewReader(strings.Join(requests, "\n") + "\n")
+	var out bytes.Buffer
+	if err := s.Serve(in, &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	var responses []map[string]interface{}
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var resp map[string]interface{}
+		if err := decoder.Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func toolCall(id int, name string, args string) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":%q,"arguments":%s}}`, id, name, args)
+}
+
+func TestServe_InitializeNegotiatesVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		want   string
+	}{
+		{"対応しているバージョン", `{"protocolVersion":"2024-11-05"}`, "2024-11-05"},
+		{"対応していないバージョン", `{"protocolVersion":"2099-01-01"}`, ProtocolVersion},
+		{"バージョンの指定なし", `{}`, ProtocolVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), &mockEsaClient{})
+			responses := roundTrip(t, s, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":`+tt.params+`}`)
+			result := responses[0]["result"].(map[string]interface{})
+			if result["protocolVersion"] != tt.want {
+				t.Errorf("protocolVersion = %v, want %s", result["protocolVersion"], tt.want)
+			}
+		})
+	}
+}
+
+func TestServe_InitializeAndList(t *testing.T) {
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), &mockEsaClient{})
+	responses := roundTrip(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+	)
+
+	// 通知には応答しない
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(responses))
+	}
+
+	result := responses[0]["result"].(map[string]interface{})
+	if result["protocolVersion"] != "2025-03-26" {
+		t.Errorf("protocolVersion = %v, want 2025-03-26", result["protocolVersion"])
+	}
+
+	tools := responses[1]["result"].(map[string]interface{})["tools"].([]interface{})
+	var names []string
+	for _, tl := range tools {
+		m := tl.(map[string]interface{})
+		names = append(names, m["name"].(string))
+		if m["inputSchema"] == nil {
+			t.Errorf("tool %v has no inputSchema", m["name"])
+		}
+	}
+	if got := strings.Join(names, ","); got != "validate,preview,diff,post,fetch,patch" {
+		t.Errorf("tools = %s, want validate,preview,diff,post,fetch,patch", got)
+	}
+}
+
+func TestServeContext_Canceled(t *testing.T) {
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), &mockEsaClient{})
+	in, inWriter := io.Pipe() // 入力が届かないまま待ち続ける
+	defer inWriter.Close()
+	var out bytes.Buffer
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.ServeContext(ctx, in, &out) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeContext() error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeContext() did not return after cancel")
+	}
+}
+
+func TestServe_UnknownMethod(t *testing.T) {
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), &mockEsaClient{})
+	responses := roundTrip(t, s, `{"jsonrpc":"2.0","id":1,"method":"resources/list"}`)
+
+	rpcErr, ok := responses[0]["error"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected error response, got %v", responses[0])
+	}
+	if rpcErr["code"].(float64) != codeMethodNotFound {
+		t.Errorf("code = %v, want %d", rpcErr["code"], codeMethodNotFound)
+	}
+}
+
+func TestServe_ParseError(t *testing.T) {
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), &mockEsaClient{})
+	responses := roundTrip(t, s, `{not json`)
+
+	rpcErr, ok := responses[0]["error"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected error response, got %v", responses[0])
+	}
+	if rpcErr["code"].(float64) != codeParseError {
+		t.Errorf("code = %v, want %d", rpcErr["code"], codeParseError)
+	}
+}
+
+func TestToolsCall_Validate(t *testing.T) {
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), &mockEsaClient{})
+	responses := roundTrip(t, s, toolCall(1, "validate", validCreateArgs))
+
+	result := responses[0]["result"].(map[string]interface{})
+	if result["isError"] != false {
+		t.Fatalf("expected success, got %v", result)
+	}
+	structured := result["structuredContent"].(map[string]interface{})
+	if structured["valid"] != true {
+		t.Errorf("valid = %v, want true", structured["valid"])
+	}
+}
+
+func TestToolsCall_ValidationErrorIsStructured(t *testing.T) {
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), &mockEsaClient{})
+	args := strings.Replace(validCreateArgs, "Task 1: Test", "Task 2: Test", 1)
+	responses := roundTrip(t, s, toolCall(1, "validate", args))
+
+	result := responses[0]["result"].(map[string]interface{})
+	if result["isError"] != true {
+		t.Fatalf("expected isError, got %v", result)
+	}
+	detail := result["structuredContent"].(map[string]interface{})["error"].(map[string]interface{})
+	if detail["code"] != "task_number_not_sequential" {
+		t.Errorf("code = %v, want task_number_not_sequential", detail["code"])
+	}
+	if detail["field"] != "task.title" {
+		t.Errorf("field = %v, want task.title", detail["field"])
+	}
+	if detail["index"].(float64) != 0 {
+		t.Errorf("index = %v, want 0", detail["index"])
+	}
+}
+
+func TestToolsCall_UnknownFieldRejected(t *testing.T) {
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), &mockEsaClient{})
+	args := strings.Replace(validCreateArgs, `"create_new":true`, `"create_new":true,"tags":["x"]`, 1)
+	responses := roundTrip(t, s, toolCall(1, "validate", args))
+
+	result := responses[0]["result"].(map[string]interface{})
+	if result["isError"] != true {
+		t.Fatalf("expected isError for unknown field, got %v", result)
+	}
+}
+
+func TestToolsCall_PostCategoryNotAllowed(t *testing.T) {
+	client := &mockEsaClient{}
+	s := NewServer(guard.NewPolicy([]string{"Other/Category"}), client)
+	responses := roundTrip(t, s, toolCall(1, "post", validCreateArgs))
+
+	result := responses[0]["result"].(map[string]interface{})
+	if result["isError"] != true {
+		t.Fatalf("expected isError, got %v", result)
+	}
+	content := result["content"].([]interface{})[0].(map[string]interface{})
+	if !strings.Contains(content["text"].(string), "not allowed") {
+		t.Errorf("expected 'not allowed' message, got %v", content["text"])
+	}
+}
+
+func TestToolsCall_PostCreate(t *testing.T) {
+	var captured *esa.PostInput
+	client := &mockEsaClient{
+		createPostFunc: func(input *esa.PostInput) (*esa.Post, error) {
+			captured = input
+			return &esa.Post{Number: 42, URL: "https://example.esa.io/posts/42"}, nil
+		},
+	}
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), client)
+	responses := roundTrip(t, s, toolCall(1, "post", validCreateArgs))
+
+	result := responses[0]["result"].(map[string]interface{})
+	if result["isError"] != false {
+		t.Fatalf("expected success, got %v", result)
+	}
+	structured := result["structuredContent"].(map[string]interface{})
+	if structured["post_number"].(float64) != 42 {
+		t.Errorf("post_number = %v, want 42", structured["post_number"])
+	}
+	if structured["created"] != true {
+		t.Errorf("created = %v, want true", structured["created"])
+	}
+	if captured == nil || captured.Category != "LLM/Tasks/2026/01/28" {
+		t.Errorf("CreatePost was not called with the expected category: %v", captured)
+	}
+}
+
+func TestToolsCall_UpdateCategoryChangeRejected(t *testing.T) {
+	client := &mockEsaClient{
+		getPostFunc: func(number int) (*esa.Post, error) {
+			return &esa.Post{Number: number, Category: "LLM/Tasks/2026/01/27"}, nil
+		},
+	}
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), client)
+	args := strings.Replace(validCreateArgs, `"create_new":true`, `"post_number":5`, 1)
+	responses := roundTrip(t, s, toolCall(1, "post", args))
+
+	result := responses[0]["result"].(map[string]interface{})
+	if result["isError"] != true {
+		t.Fatalf("expected isError, got %v", result)
+	}
+	detail := result["structuredContent"].(map[string]interface{})["error"].(map[string]interface{})
+	if detail["code"] != "category_change_not_allowed" {
+		t.Errorf("code = %v, want category_change_not_allowed", detail["code"])
+	}
+}
+
+func TestToolsCall_Fetch(t *testing.T) {
+	bodyMD := "<!-- esa-guard-json\n" +
+		`{"post_number":7,"name":"Test","category":"LLM/Tasks/2026/01/28","body":{"background":"test","tasks":[{"id":"task-1","title":"Task 1: Test","status":"not_started","summary":["test"],"description":"test"}]}}` +
+		"\n-->\n\n## サマリー\n"
+	client := &mockEsaClient{
+		getPostFunc: func(number int) (*esa.Post, error) {
+			return &esa.Post{Number: number, Category: "LLM/Tasks/2026/01/28", BodyMD: bodyMD}, nil
+		},
+	}
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), client)
+	responses := roundTrip(t, s, toolCall(1, "fetch", `{"post_number":7}`))
+
+	result := responses[0]["result"].(map[string]interface{})
+	if result["isError"] != false {
+		t.Fatalf("expected success, got %v", result)
+	}
+	structured := result["structuredContent"].(map[string]interface{})
+	if structured["post_number"].(float64) != 7 {
+		t.Errorf("post_number = %v, want 7", structured["post_number"])
+	}
+}
+
+func TestToolsCall_FetchReadScope(t *testing.T) {
+	bodyMD := "<!-- esa-guard-json\n" +
+		`{"post_number":7,"name":"Test","category":"Docs/Design/2026/01/28","body":{"background":"test","tasks":[{"id":"task-1","title":"Task 1: Test","status":"not_started","summary":["test"],"description":"test"}]}}` +
+		"\n-->\n\n## サマリー\n"
+	client := &mockEsaClient{
+		getPostFunc: func(number int) (*esa.Post, error) {
+			return &esa.Post{Number: number, Category: "Docs/Design/2026/01/28", BodyMD: bodyMD}, nil
+		},
+	}
+
+	// 書き込み先のカテゴリだけでは読み取れない
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), client)
+	responses := roundTrip(t, s, toolCall(1, "fetch", `{"post_number":7}`))
+	result := responses[0]["result"].(map[string]interface{})
+	if result["isError"] != true {
+		t.Fatalf("expected isError, got %v", result)
+	}
+	detail := result["structuredContent"].(map[string]interface{})["error"].(map[string]interface{})
+	if detail["code"] != "read_not_allowed" {
+		t.Errorf("code = %v, want read_not_allowed", detail["code"])
+	}
+
+	// 読み取り可能なカテゴリを追加すると取得できる
+	s.policy.AllowRead("Docs/Design")
+	responses = roundTrip(t, s, toolCall(1, "fetch", `{"post_number":7}`))
+	result = responses[0]["result"].(map[string]interface{})
+	if result["isError"] != false {
+		t.Fatalf("expected success, got %v", result)
+	}
+}
+
+func TestToolsCall_UnknownTool(t *testing.T) {
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), &mockEsaClient{})
+	responses := roundTrip(t, s, toolCall(1, "delete", `{}`))
+
+	if _, ok := responses[0]["error"]; !ok {
+		t.Fatalf("expected JSON-RPC error for unknown tool, got %v", responses[0])
+	}
+}
+
+func TestToolsCall_UnmanagedPostRejected(t *testing.T) {
+	client := &mockEsaClient{
+		getPostFunc: func(number int) (*esa.Post, error) {
+			return &esa.Post{Number: number, Category: "LLM/Tasks/2026/01/28", BodyMD: "手書きのメモ"}, nil
+		},
+	}
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), client)
+	args := strings.Replace(validCreateArgs, `"create_new":true`, `"post_number":5`, 1)
+	responses := roundTrip(t, s, toolCall(1, "post", args))
+
+	result := responses[0]["result"].(map[string]interface{})
+	if result["isError"] != true {
+		t.Fatalf("expected isError, got %v", result)
+	}
+	detail := result["structuredContent"].(map[string]interface{})["error"].(map[string]interface{})
+	if detail["code"] != "post_not_managed" {
+		t.Errorf("code = %v, want post_not_managed", detail["code"])
+	}
+}
+
+func TestToolsCall_PatchRejectsCategory(t *testing.T) {
+	s := NewServer(guard.NewPolicy([]string{"LLM/Tasks"}), &mockEsaClient{})
+	responses := roundTrip(t, s, toolCall(1, "patch", `{"post_number":7,"patch":{"category":"Other/2026/01/28"}}`))
+
+	result := responses[0]["result"].(map[string]interface{})
+	if result["isError"] != true {
+		t.Fatalf("expected isError, got %v", result)
+	}
+	detail := result["structuredContent"].(map[string]interface{})["error"].(map[string]interface{})
+	if detail["code"] != "patch_forbidden_field" {
+		t.Errorf("code = %v, want patch_forbidden_field", detail["code"])
+	}
+}
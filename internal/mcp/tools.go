@@ -0,0 +1,238 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+// toolDefinition はtools/listで返すツール定義
+type toolDefinition struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+// tool はツール定義とその実装
+// handlerはstructuredContentとして返すオブジェクトを返す。ctxはesa.io APIの呼び出しに渡す
+type tool struct {
+	definition toolDefinition
+	handler    func(ctx context.Context, arguments json.RawMessage) (interface{}, error)
+}
+
+// fetchInputSchema はfetchツールの入力スキーマ
+const fetchInputSchema = `{
+  "type": "object",
+  "properties": {
+    "post_number": {
+      "type": "integer",
+      "minimum": 1,
+      "description": "esa.io post number to fetch"
+    }
+  },
+  "required": ["post_number"],
+  "additionalProperties": false
+}`
+
+// patchInputSchema はpatchツールの入力スキーマ
+const patchInputSchema = `{
+  "type": "object",
+  "properties": {
+    "post_number": {
+      "type": "integer",
+      "minimum": 1,
+      "description": "esa.io post number to patch"
+    },
+    "patch": {
+      "type": ["array", "object"],
+      "description": "RFC 6902 JSON Patch (array) or RFC 7386 merge patch (object) applied to the embedded JSON. category and post_number cannot be changed"
+    }
+  },
+  "required": ["post_number", "patch"],
+  "additionalProperties": false
+}`
+
+// buildTools はサーバーが公開するツールを構築します
+func (s *Server) buildTools() []tool {
+	postSchema := guard.PostInputSchema()
+
+	return []tool{
+		{
+			definition: toolDefinition{
+				Name:        "validate",
+				Description: "Validate post JSON without posting. Returns {valid: true} on success.",
+				InputSchema: postSchema,
+			},
+			handler: s.handleValidate,
+		},
+		{
+			definition: toolDefinition{
+				Name:        "preview",
+				Description: "Preview the Markdown (with embedded JSON) that would be posted to esa.io.",
+				InputSchema: postSchema,
+			},
+			handler: s.handlePreview,
+		},
+		{
+			definition: toolDefinition{
+				Name:        "diff",
+				Description: "Show a unified diff between the existing esa.io post and the new content. Category restrictions apply.",
+				InputSchema: postSchema,
+			},
+			handler: s.handleDiff,
+		},
+		{
+			definition: toolDefinition{
+				Name:        "post",
+				Description: "Create (create_new: true) or update (post_number) a post on esa.io. Category restrictions apply. Pass revision_number (from fetch) to reject the update if the post changed since.",
+				InputSchema: postSchema,
+			},
+			handler: s.handlePost,
+		},
+		{
+			definition: toolDefinition{
+				Name:        "fetch",
+				Description: "Fetch the embedded JSON from an existing esa.io post created by this tool. Only posts in readable categories can be fetched. The result includes the current revision_number.",
+				InputSchema: json.RawMessage(fetchInputSchema),
+			},
+			handler: s.handleFetch,
+		},
+		{
+			definition: toolDefinition{
+				Name:        "patch",
+				Description: "Apply a JSON Patch (array) or merge patch (object) to the embedded JSON of an existing post and update it. Validation and category restrictions apply as for post; category and post_number cannot be patched.",
+				InputSchema: json.RawMessage(patchInputSchema),
+			},
+			handler: s.handlePatch,
+		},
+	}
+}
+
+func (s *Server) handleValidate(_ context.Context, arguments json.RawMessage) (interface{}, error) {
+	input, err := guard.DecodePostInput(arguments)
+	if err != nil {
+		return nil, err
+	}
+	if err := guard.ValidateInput(input); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"valid": true}, nil
+}
+
+func (s *Server) handlePreview(_ context.Context, arguments json.RawMessage) (interface{}, error) {
+	input, err := guard.DecodePostInput(arguments)
+	if err != nil {
+		return nil, err
+	}
+	markdown, err := guard.RenderPreview(input)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"markdown": markdown}, nil
+}
+
+func (s *Server) handleDiff(ctx context.Context, arguments json.RawMessage) (interface{}, error) {
+	input, err := guard.DecodePostInput(arguments)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := guard.Diff(ctx, input, s.policy, s.client)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"diff": diff}, nil
+}
+
+func (s *Server) handlePost(ctx context.Context, arguments json.RawMessage) (interface{}, error) {
+	input, err := guard.DecodePostInput(arguments)
+	if err != nil {
+		return nil, err
+	}
+	// ガード管理外の記事の引き継ぎ（-adopt）は人が差分を確認して行うものなので、MCPからは許可しない
+	result, err := guard.Post(ctx, input, s.policy, s.client, s.postOptions())
+	if err != nil {
+		return nil, err
+	}
+	output := map[string]interface{}{
+		"post_number":     result.Post.Number,
+		"url":             result.Post.URL,
+		"created":         result.Created,
+		"revision_number": result.Post.RevisionNumber,
+		"team":            input.Team,
+	}
+	if len(result.Warnings) > 0 {
+		output["warnings"] = result.Warnings
+	}
+	return output, nil
+}
+
+func (s *Server) handleFetch(ctx context.Context, arguments json.RawMessage) (interface{}, error) {
+	var p struct {
+		PostNumber int `json:"post_number"`
+	}
+	if err := json.Unmarshal(arguments, &p); err != nil {
+		return nil, guard.NewValidationError(guard.ErrCodeJSONInvalid, fmt.Sprintf("failed to parse arguments: %v", err)).Wrap(err)
+	}
+	if p.PostNumber <= 0 {
+		return nil, guard.NewValidationError(guard.ErrCodeInvalidValue, fmt.Sprintf("post number must be a positive integer (got %d)", p.PostNumber)).
+			WithField("post_number")
+	}
+
+	// 埋め込みJSONをそのままstructuredContentとして返す（postツールの入力と同じ形）
+	return guard.Fetch(ctx, p.PostNumber, s.policy, s.client)
+}
+
+func (s *Server) handlePatch(ctx context.Context, arguments json.RawMessage) (interface{}, error) {
+	var p struct {
+		PostNumber int             `json:"post_number"`
+		Patch      json.RawMessage `json:"patch"`
+	}
+	if err := json.Unmarshal(arguments, &p); err != nil {
+		return nil, guard.NewValidationError(guard.ErrCodeJSONInvalid, fmt.Sprintf("failed to parse arguments: %v", err)).Wrap(err)
+	}
+	if p.PostNumber <= 0 {
+		return nil, guard.NewValidationError(guard.ErrCodeInvalidValue, fmt.Sprintf("post number must be a positive integer (got %d)", p.PostNumber)).
+			WithField("post_number")
+	}
+
+	result, patched, err := guard.Patch(ctx, p.PostNumber, p.Patch, s.policy, s.client, s.postOptions())
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"post_number":     result.Post.Number,
+		"url":             result.Post.URL,
+		"revision_number": result.Post.RevisionNumber,
+		"json":            patched,
+	}, nil
+}
+
+// successResult は成功時のツール結果を作成します
+// テキストコンテンツにも同じJSONを入れ、structuredContent非対応のクライアントでも読めるようにする
+func successResult(structured interface{}) map[string]interface{} {
+	text, err := json.Marshal(structured)
+	if err != nil {
+		return errorResult(fmt.Errorf("failed to marshal result: %w", err))
+	}
+	return map[string]interface{}{
+		"content": []map[string]interface{}{
+			{"type": "text", "text": string(text)},
+		},
+		"structuredContent": structured,
+		"isError":           false,
+	}
+}
+
+// errorResult はエラー時のツール結果を作成します
+// エラーの種類に加え、ValidationErrorの場合はコード・フィールド・インデックスを構造化して返す
+func errorResult(err error) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]interface{}{
+			{"type": "text", "text": err.Error()},
+		},
+		"structuredContent": map[string]interface{}{"error": guard.NewErrorDetail(err)},
+		"isError":           true,
+	}
+}
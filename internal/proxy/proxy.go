@@ -0,0 +1,562 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+// ReadPolicy は記事の読み取り（GET）の扱い
+type ReadPolicy string
+
+const (
+	// ReadPolicyReadable は読み取り可能なカテゴリ（許可カテゴリと読み取り専用カテゴリ）の記事だけを返す
+	ReadPolicyReadable ReadPolicy = "readable"
+	// ReadPolicyAll は読み取りをそのまま中継する
+	ReadPolicyAll ReadPolicy = "all"
+)
+
+// maxRequestSize は受け付けるリクエストボディの最大サイズ
+// 本文（10MB上限）をエスケープ込みで受け取れるよう余裕を持たせる
+const maxRequestSize = 2 * guard.MaxInputSize
+
+// forwardedHeaders はesa.io APIのレスポンスからクライアントに返すヘッダー
+var forwardedHeaders = []string{"Content-Type", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}
+
+// Upstream はesa.io APIにリクエストを中継するクライアント（*esa.EsaClient）
+type Upstream interface {
+	Forward(ctx context.Context, method, path, rawQuery string, body []byte) (*esa.Response, error)
+}
+
+// Handler はesa.io APIのv1 posts APIを提供し、本物のトークンを付けてesa.ioに中継するhttp.Handler
+// 作成・更新・削除・カテゴリの移動にはCLIと同じカテゴリ制限を適用し、posts API以外のリクエストは拒否する
+type Handler struct {
+	team       string
+	hosts      []string
+	policy     *guard.Policy
+	readPolicy ReadPolicy
+	// repositoryTag はリポジトリにカテゴリを割り当てている場合のリポジトリのタグ（空なら割り当てなし）
+	repositoryTag string
+	upstream      Upstream
+	audit         *guard.AuditLog
+	mux           *http.ServeMux
+}
+
+// NewHandler は新しいHandlerを作成します（読み取りの既定は ReadPolicyReadable）
+// hosts は待ち受けているアドレスとして受け付けるHostヘッダー（ListenHosts）で、これと異なるリクエストは拒否する
+func NewHandler(team string, hosts []string, policy *guard.Policy, upstream Upstream) *Handler {
+	h := &Handler{
+		team:       team,
+		hosts:      hosts,
+		policy:     policy,
+		readPolicy: ReadPolicyReadable,
+		upstream:   upstream,
+		mux:        http.NewServeMux(),
+	}
+	h.mux.HandleFunc("GET /v1/teams/{team}/posts", h.handleList)
+	h.mux.HandleFunc("POST /v1/teams/{team}/posts", h.handleCreate)
+	h.mux.HandleFunc("GET /v1/teams/{team}/posts/{number}", h.handleGet)
+	h.mux.HandleFunc("PATCH /v1/teams/{team}/posts/{number}", h.handleUpdate)
+	h.mux.HandleFunc("DELETE /v1/teams/{team}/posts/{number}", h.handleDelete)
+	h.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusForbidden, "forbidden", fmt.Sprintf("%s %s is not available through the proxy (only the posts API is)", r.Method, r.URL.Path))
+	})
+	return h
+}
+
+// ListenHosts は -addr の指定どおりの表記（localhost:8787 など）と実際に待ち受けているアドレスを、
+// Hostヘッダーとして受け付けるアドレスの一覧として返します（ポート0を指定した場合は実際のポートを使う）
+func ListenHosts(addr string, listenAddr net.Addr) []string {
+	hosts := []string{listenAddr.String()}
+	host, _, err := net.SplitHostPort(addr)
+	_, port, portErr := net.SplitHostPort(listenAddr.String())
+	if err != nil || portErr != nil || host == "" {
+		return hosts
+	}
+	if literal := net.JoinHostPort(host, port); !strings.EqualFold(literal, hosts[0]) {
+		hosts = append(hosts, literal)
+	}
+	return hosts
+}
+
+// SetReadPolicy は読み取りの扱いを設定します
+func (h *Handler) SetReadPolicy(policy ReadPolicy) {
+	h.readPolicy = policy
+}
+
+// SetRepositoryTag はリポジトリにカテゴリを割り当てている場合のリポジトリのタグを設定します
+// 設定すると、作成する記事にはこのタグを付け、更新・削除はこのタグが付いた記事に限る（CLIの post と同じ）
+func (h *Handler) SetRepositoryTag(tag string) {
+	h.repositoryTag = tag
+}
+
+// SetAuditLog は作成・更新・削除を記録する監査ログを設定します
+func (h *Handler) SetAuditLog(audit *guard.AuditLog) {
+	h.audit = audit
+}
+
+// ParseReadPolicy は設定値を読み取りの扱いに変換します（空は readable）
+func ParseReadPolicy(value string) (ReadPolicy, error) {
+	switch ReadPolicy(value) {
+	case "", ReadPolicyReadable:
+		return ReadPolicyReadable, nil
+	case ReadPolicyAll:
+		return ReadPolicyAll, nil
+	default:
+		return "", fmt.Errorf("invalid read policy %q (must be %s or %s)", value, ReadPolicyReadable, ReadPolicyAll)
+	}
+}
+
+// ServeHTTP はリクエストを処理します
+// クライアントが送ったAuthorizationヘッダーは使わず、中継時にはupstreamのトークンを付ける
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.checkLocalClient(w, r) {
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// checkLocalClient はブラウザ経由のリクエストを拒否します
+// トークンを付けて中継するため、DNSリバインディング（待ち受けアドレス以外のHost）、
+// 他のサイトからのリクエスト（Originヘッダー付き）、フォーム送信（JSON以外の書き込み）は受け付けない
+func (h *Handler) checkLocalClient(w http.ResponseWriter, r *http.Request) bool {
+	if !slices.ContainsFunc(h.hosts, func(host string) bool { return strings.EqualFold(r.Host, host) }) {
+		writeError(w, http.StatusForbidden, "forbidden", fmt.Sprintf("Host %q is not the proxy address (%s)", r.Host, strings.Join(h.hosts, ", ")))
+		return false
+	}
+	if r.Header.Get("Origin") != "" {
+		writeError(w, http.StatusForbidden, "forbidden", "requests with an Origin header (from browsers) are not accepted")
+		return false
+	}
+	if r.Method == http.MethodPost || r.Method == http.MethodPatch {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Handler) postsPath() string {
+	return fmt.Sprintf("/v1/teams/%s/posts", h.team)
+}
+
+func (h *Handler) postPath(number int) string {
+	return fmt.Sprintf("/v1/teams/%s/posts/%d", h.team, number)
+}
+
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	if !h.checkTeam(w, r) {
+		return
+	}
+	resp, ok := h.forward(w, r, http.MethodGet, h.postsPath(), r.URL.RawQuery, nil)
+	if !ok {
+		return
+	}
+	if h.readPolicy == ReadPolicyAll || resp.StatusCode != http.StatusOK {
+		writeResponse(w, resp)
+		return
+	}
+
+	// 読み取れないカテゴリの記事を取り除く（ページングなど他のフィールドはそのまま）
+	var list map[string]json.RawMessage
+	if err := json.Unmarshal(resp.Body, &list); err != nil {
+		writeError(w, http.StatusBadGateway, "bad_gateway", fmt.Sprintf("invalid posts response: %v", err))
+		return
+	}
+	var posts []json.RawMessage
+	if err := json.Unmarshal(list["posts"], &posts); err != nil {
+		writeError(w, http.StatusBadGateway, "bad_gateway", fmt.Sprintf("invalid posts response: %v", err))
+		return
+	}
+	readable := make([]json.RawMessage, 0, len(posts))
+	for _, post := range posts {
+		var p struct {
+			Category string `json:"category"`
+		}
+		if err := json.Unmarshal(post, &p); err == nil && guard.ValidateReadAccess(p.Category, h.policy) == nil {
+			readable = append(readable, post)
+		}
+	}
+	list["posts"], _ = json.Marshal(readable)
+	body, err := json.Marshal(list)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	resp.Body = body
+	writeResponse(w, resp)
+}
+
+func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request) {
+	number, ok := h.checkPost(w, r)
+	if !ok {
+		return
+	}
+	resp, ok := h.forward(w, r, http.MethodGet, h.postPath(number), r.URL.RawQuery, nil)
+	if !ok {
+		return
+	}
+	if h.readPolicy == ReadPolicyAll || resp.StatusCode != http.StatusOK {
+		writeResponse(w, resp)
+		return
+	}
+
+	var p struct {
+		Category string `json:"category"`
+	}
+	if err := json.Unmarshal(resp.Body, &p); err != nil {
+		writeError(w, http.StatusBadGateway, "bad_gateway", fmt.Sprintf("invalid post response: %v", err))
+		return
+	}
+	if err := guard.ValidateReadAccess(p.Category, h.policy); err != nil {
+		writeGuardError(w, err)
+		return
+	}
+	writeResponse(w, resp)
+}
+
+func (h *Handler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if !h.checkTeam(w, r) {
+		return
+	}
+	input, err := decodePostRequest(r)
+	if err != nil {
+		writeGuardError(w, err)
+		return
+	}
+
+	// カテゴリは必須（記事名にカテゴリを含める指定は decodePostRequest で拒否している）
+	if err := h.policy.Check("category", input.category, guard.OperationCreate); err != nil {
+		writeGuardError(w, err)
+		return
+	}
+	// 作成した記事を後でこのリポジトリから更新できるよう、リポジトリのタグを付ける
+	if err := input.addTag(h.repositoryTag); err != nil {
+		writeGuardError(w, err)
+		return
+	}
+
+	resp, ok := h.forward(w, r, http.MethodPost, h.postsPath(), "", input.body)
+	if !ok {
+		h.recordWrite(guard.AuditOperationCreate, 0, input.category, input.bodyMD, nil, errors.New("request to esa.io failed"))
+		return
+	}
+	h.recordWrite(guard.AuditOperationCreate, 0, input.category, input.bodyMD, resp, nil)
+	writeResponse(w, resp)
+}
+
+func (h *Handler) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	number, ok := h.checkPost(w, r)
+	if !ok {
+		return
+	}
+	input, err := decodePostRequest(r)
+	if err != nil {
+		writeGuardError(w, err)
+		return
+	}
+	existing, ok := h.getExistingPost(w, r, number)
+	if !ok {
+		return
+	}
+
+	// カテゴリを指定しない更新は既存のカテゴリのまま。指定した場合は移動（カテゴリの変更）になるため拒否する
+	category := existing.Category
+	if input.hasCategory {
+		category = input.category
+	}
+	if err := guard.ValidateUpdateRequest(existing.Category, category, h.policy); err != nil {
+		writeGuardError(w, err)
+		return
+	}
+	if !h.checkRepositoryTag(w, existing) {
+		return
+	}
+	// タグを置き換える場合もリポジトリのタグは外させない
+	if input.hasTags {
+		if err := input.addTag(h.repositoryTag); err != nil {
+			writeGuardError(w, err)
+			return
+		}
+	}
+
+	resp, ok := h.forward(w, r, http.MethodPatch, h.postPath(number), "", input.body)
+	if !ok {
+		h.recordWrite(guard.AuditOperationUpdate, number, category, input.bodyMD, nil, errors.New("request to esa.io failed"))
+		return
+	}
+	h.recordWrite(guard.AuditOperationUpdate, number, category, input.bodyMD, resp, nil)
+	writeResponse(w, resp)
+}
+
+func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	number, ok := h.checkPost(w, r)
+	if !ok {
+		return
+	}
+	existing, ok := h.getExistingPost(w, r, number)
+	if !ok {
+		return
+	}
+	if err := h.policy.Check("existing post category", existing.Category, guard.OperationDelete); err != nil {
+		writeGuardError(w, err)
+		return
+	}
+	if !h.checkRepositoryTag(w, existing) {
+		return
+	}
+
+	resp, ok := h.forward(w, r, http.MethodDelete, h.postPath(number), "", nil)
+	if !ok {
+		h.recordWrite(guard.AuditOperationDelete, number, existing.Category, "", nil, errors.New("request to esa.io failed"))
+		return
+	}
+	h.recordWrite(guard.AuditOperationDelete, number, existing.Category, "", resp, nil)
+	writeResponse(w, resp)
+}
+
+// checkTeam はパスのチームが設定のチームと一致するかを確認します（一致しなければ404を返す）
+func (h *Handler) checkTeam(w http.ResponseWriter, r *http.Request) bool {
+	if r.PathValue("team") != h.team {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return false
+	}
+	return true
+}
+
+// checkPost はチームと記事番号を確認し、記事番号を返します
+func (h *Handler) checkPost(w http.ResponseWriter, r *http.Request) (int, bool) {
+	if !h.checkTeam(w, r) {
+		return 0, false
+	}
+	number, err := strconv.Atoi(r.PathValue("number"))
+	if err != nil || number <= 0 {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return 0, false
+	}
+	return number, true
+}
+
+// checkRepositoryTag はリポジトリにカテゴリを割り当てている場合、既存記事にそのリポジトリのタグが付いているかを確認します
+func (h *Handler) checkRepositoryTag(w http.ResponseWriter, existing *esa.Post) bool {
+	if h.repositoryTag == "" {
+		return true
+	}
+	if err := guard.ValidateRepositoryTag(existing.Tags, h.repositoryTag, existing.Number); err != nil {
+		writeGuardError(w, err)
+		return false
+	}
+	return true
+}
+
+// getExistingPost は更新・削除の対象の記事を取得します
+// 取得できない場合はesa.io APIのレスポンス（404など）をそのまま返す
+func (h *Handler) getExistingPost(w http.ResponseWriter, r *http.Request, number int) (*esa.Post, bool) {
+	resp, ok := h.forward(w, r, http.MethodGet, h.postPath(number), "", nil)
+	if !ok {
+		return nil, false
+	}
+	if resp.StatusCode != http.StatusOK {
+		writeResponse(w, resp)
+		return nil, false
+	}
+	var post esa.Post
+	if err := json.Unmarshal(resp.Body, &post); err != nil {
+		writeError(w, http.StatusBadGateway, "bad_gateway", fmt.Sprintf("invalid post response: %v", err))
+		return nil, false
+	}
+	return &post, true
+}
+
+// forward はesa.io APIにリクエストを中継します（通信に失敗した場合は502を返してfalse）
+func (h *Handler) forward(w http.ResponseWriter, r *http.Request, method, path, rawQuery string, body []byte) (*esa.Response, bool) {
+	resp, err := h.upstream.Forward(r.Context(), method, path, rawQuery, body)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "bad_gateway", fmt.Sprintf("request to esa.io failed: %v", err))
+		return nil, false
+	}
+	return resp, true
+}
+
+// recordWrite は作成・更新・削除を監査ログに記録します
+// 監査ログへの書き込みに失敗しても、esa.io上の書き込みは済んでいるため警告にとどめる
+func (h *Handler) recordWrite(operation string, number int, category, bodyMD string, resp *esa.Response, writeErr error) {
+	if h.audit == nil {
+		return
+	}
+	sum := sha256.Sum256([]byte(bodyMD))
+	record := guard.AuditRecord{
+		Operation:  operation,
+		PostNumber: number,
+		Category:   category,
+		BodySHA256: hex.EncodeToString(sum[:]),
+		Status:     "ok",
+	}
+	switch {
+	case writeErr != nil:
+		record.Status = "error"
+		record.Error = writeErr.Error()
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		record.Status = "error"
+		record.Error = fmt.Sprintf("esa.io returned status %d", resp.StatusCode)
+	case operation != guard.AuditOperationDelete:
+		var post esa.Post
+		if err := json.Unmarshal(resp.Body, &post); err == nil {
+			record.PostNumber = post.Number
+			record.Revision = post.RevisionNumber
+		}
+	}
+	if err := h.audit.Record(record); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// postRequest は作成・更新リクエストの {"post": {...}} を検証したもの
+type postRequest struct {
+	category    string
+	hasCategory bool
+	hasTags     bool
+	bodyMD      string
+	post        map[string]json.RawMessage
+	// body はesa.ioに送るボディ。解釈した内容と送る内容が食い違わないよう、解釈した結果から作り直す
+	body []byte
+}
+
+// addTag はtagをタグに加えてボディを作り直します（空のtagや既に付いているタグは何もしない）
+func (p *postRequest) addTag(tag string) error {
+	if tag == "" {
+		return nil
+	}
+	var tags []string
+	if raw, ok := p.post["tags"]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &tags); err != nil {
+			return guard.NewValidationError(guard.ErrCodeInvalidValue, "post.tags must be an array of strings").WithField("tags")
+		}
+	}
+	if slices.Contains(tags, tag) {
+		return nil
+	}
+	raw, err := json.Marshal(append(tags, tag))
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+	p.post["tags"] = raw
+	return p.marshal()
+}
+
+// marshal はesa.ioに送るボディを解釈した post から作り直します
+func (p *postRequest) marshal() error {
+	body, err := json.Marshal(map[string]interface{}{"post": p.post})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	p.body = body
+	return nil
+}
+
+// decodePostRequest は作成・更新リクエストのボディを読み込み、検証します
+// JSON以外の形式（フォーム）や post 以外のトップレベルのキーは拒否する。
+// esa.ioは記事名の "/" をカテゴリの区切りとして扱うため、記事名に "/" を含むリクエストも拒否する
+func decodePostRequest(r *http.Request) (*postRequest, error) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize+1))
+	if err != nil {
+		return nil, guard.NewValidationError(guard.ErrCodeJSONInvalid, fmt.Sprintf("failed to read request body: %v", err))
+	}
+	if len(data) > maxRequestSize {
+		return nil, guard.NewValidationError(guard.ErrCodeFileSizeExceeded, fmt.Sprintf("request body exceeds %d bytes", maxRequestSize))
+	}
+
+	var wrapper struct {
+		Post map[string]json.RawMessage `json:"post"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&wrapper); err != nil {
+		return nil, guard.NewValidationError(guard.ErrCodeJSONInvalid, fmt.Sprintf("request body must be JSON like {\"post\": {...}}: %v", err)).Wrap(err)
+	}
+	if decoder.More() {
+		return nil, guard.NewValidationError(guard.ErrCodeJSONInvalid, "request body has trailing data")
+	}
+	if wrapper.Post == nil {
+		return nil, guard.NewValidationError(guard.ErrCodeMissingRequired, "request body must have a post object").WithField("post")
+	}
+
+	input := &postRequest{post: wrapper.Post}
+	_, input.hasTags = wrapper.Post["tags"]
+	if raw, ok := wrapper.Post["category"]; ok {
+		if err := json.Unmarshal(raw, &input.category); err != nil {
+			return nil, guard.NewValidationError(guard.ErrCodeInvalidValue, "post.category must be a string").WithField("category")
+		}
+		input.hasCategory = true
+	}
+	if raw, ok := wrapper.Post["name"]; ok {
+		var name string
+		if err := json.Unmarshal(raw, &name); err != nil {
+			return nil, guard.NewValidationError(guard.ErrCodeInvalidValue, "post.name must be a string").WithField("name")
+		}
+		if strings.Contains(name, "/") {
+			return nil, guard.NewValidationError(guard.ErrCodeFieldInvalidChars, "post.name cannot contain / (set the category with post.category)").WithField("name")
+		}
+	}
+	if raw, ok := wrapper.Post["body_md"]; ok {
+		if err := json.Unmarshal(raw, &input.bodyMD); err != nil {
+			return nil, guard.NewValidationError(guard.ErrCodeInvalidValue, "post.body_md must be a string").WithField("body_md")
+		}
+	}
+
+	if err := input.marshal(); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// writeGuardError はガードの検証エラーをesa.ioと同じ形式のエラーとして返します
+// カテゴリの制限による拒否は403、それ以外の不正なリクエストは400
+func writeGuardError(w http.ResponseWriter, err error) {
+	var ve *guard.ValidationError
+	if errors.As(err, &ve) {
+		switch ve.Code() {
+		case guard.ErrCodeCategoryNotAllowed, guard.ErrCodeCategoryChangeNotAllowed, guard.ErrCodeReadNotAllowed, guard.ErrCodeRepositoryTagMissing:
+			writeError(w, http.StatusForbidden, string(ve.Code()), err.Error())
+			return
+		}
+		writeError(w, http.StatusBadRequest, string(ve.Code()), err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+}
+
+// writeResponse はesa.io APIのレスポンスを中継します
+func writeResponse(w http.ResponseWriter, resp *esa.Response) {
+	for _, key := range forwardedHeaders {
+		if value := resp.Header.Get(key); value != "" {
+			w.Header().Set(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(resp.Body)
+}
+
+// writeError はesa.io APIと同じ形式（{"error", "message"}）のエラーを返します
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "message": message})
+}
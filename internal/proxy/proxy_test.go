@@ -0,0 +1,382 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa/esatest"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+// newTestProxy はfakeのesa.ioサーバーの前にproxyを立て、proxyのURLを返す
+func newTestProxy(t *testing.T, configure func(h *Handler)) (*esatest.Server, string, *bytes.Buffer) {
+	t.Helper()
+	return newTestProxyAt(t, "127.0.0.1:0", configure)
+}
+
+// newTestProxyAt は -addr にaddrを指定した場合と同じように待ち受けるproxyを立てる
+func newTestProxyAt(t *testing.T, addr string, configure func(h *Handler)) (*esatest.Server, string, *bytes.Buffer) {
+	t.Helper()
+	fake := esatest.NewServer("test-team", "test-token")
+	upstream := httptest.NewServer(fake)
+	t.Cleanup(upstream.Close)
+
+	client := esa.NewEsaClient("test-team", "test-token")
+	if err := client.SetBaseURL(upstream.URL); err != nil {
+		t.Fatalf("SetBaseURL() error = %v", err)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &httptest.Server{Listener: listener, Config: &http.Server{}}
+	handler := NewHandler("test-team", ListenHosts(addr, listener.Addr()), guard.NewPolicy([]string{"LLM/Tasks"}), client)
+	var auditBuf bytes.Buffer
+	handler.SetAuditLog(guard.NewAuditLog(&auditBuf, "proxy", "test-token"))
+	if configure != nil {
+		configure(handler)
+	}
+	server.Config.Handler = handler
+	server.Start()
+	t.Cleanup(server.Close)
+	return fake, server.URL, &auditBuf
+}
+
+// do はproxyにリクエストを送り、ステータスとレスポンスボディを返す（Authorizationは付けない）
+func do(t *testing.T, method, url, body string) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var buf bytes.Buffer
+	buf.ReadFrom(resp.Body)
+	return resp.StatusCode, buf.Bytes()
+}
+
+func TestHandler_Writes(t *testing.T) {
+	fake, url, audit := newTestProxy(t, nil)
+	inside := fake.AddPost(esatest.Post{Name: "Plan", Category: "LLM/Tasks/2026/01/28", BodyMD: "old"})
+	outside := fake.AddPost(esatest.Post{Name: "Secret", Category: "Private/Notes", BodyMD: "secret"})
+	posts := url + "/v1/teams/test-team/posts"
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"許可カテゴリへの作成", "POST", "", `{"post": {"name": "New", "category": "LLM/Tasks/2026/01/29", "body_md": "x"}}`, http.StatusCreated},
+		{"許可カテゴリ外への作成", "POST", "", `{"post": {"name": "New", "category": "Private/Notes", "body_md": "x"}}`, http.StatusForbidden},
+		{"境界を越えるカテゴリへの作成", "POST", "", `{"post": {"name": "New", "category": "LLM/Tasks-evil", "body_md": "x"}}`, http.StatusForbidden},
+		{"カテゴリなしの作成", "POST", "", `{"post": {"name": "New", "body_md": "x"}}`, http.StatusForbidden},
+		{"記事名でカテゴリを指定する作成", "POST", "", `{"post": {"name": "Private/Notes/New", "category": "LLM/Tasks", "body_md": "x"}}`, http.StatusBadRequest},
+		{"JSONでないボディ", "POST", "", `post[name]=New&post[category]=Private`, http.StatusBadRequest},
+		{"許可カテゴリ内の更新", "PATCH", "/1", `{"post": {"body_md": "new"}}`, http.StatusOK},
+		{"カテゴリの移動", "PATCH", "/1", `{"post": {"category": "Private/Notes"}}`, http.StatusForbidden},
+		{"許可カテゴリ外の記事の更新", "PATCH", "/2", `{"post": {"body_md": "x", "category": "Private/Notes"}}`, http.StatusForbidden},
+		{"存在しない記事の更新", "PATCH", "/99", `{"post": {"body_md": "x"}}`, http.StatusNotFound},
+		{"許可カテゴリ外の記事の削除", "DELETE", "/2", ``, http.StatusForbidden},
+		{"許可カテゴリ内の記事の削除", "DELETE", "/1", ``, http.StatusNoContent},
+		{"posts API以外", "POST", "/2/comments", `{"comment": {"body_md": "x"}}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := do(t, tt.method, posts+tt.path, tt.body)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", status, tt.wantStatus, body)
+			}
+		})
+	}
+
+	if _, ok := fake.Post(inside.Number); ok {
+		t.Error("post in the allowed category should have been deleted")
+	}
+	if p, ok := fake.Post(outside.Number); !ok || p.BodyMD != "secret" || p.Category != "Private/Notes" {
+		t.Errorf("post outside the allowed categories was changed: %+v", p)
+	}
+
+	// esa.ioに送った作成・更新・削除だけが監査ログに残る（拒否したリクエストは送っていない）
+	records, _, err := guard.ReadAuditLog(audit, guard.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var operations []string
+	for _, r := range records {
+		operations = append(operations, r.Operation+":"+r.Status)
+	}
+	if strings.Join(operations, ",") != "create:ok,update:ok,delete:ok" {
+		t.Errorf("audit operations = %v", operations)
+	}
+}
+
+func TestHandler_DuplicateKeysAreNormalized(t *testing.T) {
+	fake, url, _ := newTestProxy(t, nil)
+
+	// 検証した値と異なる値がesa.ioに届かないよう、解釈した結果からボディを作り直して送る
+	status, body := do(t, "POST", url+"/v1/teams/test-team/posts",
+		`{"post": {"name": "New", "category": "Private/Notes", "category": "LLM/Tasks", "body_md": "x"}}`)
+	if status != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", status, body)
+	}
+	for _, p := range fake.Posts() {
+		if p.Category != "LLM/Tasks" {
+			t.Errorf("post created in %s", p.Category)
+		}
+	}
+}
+
+func TestHandler_RejectsBrowserRequests(t *testing.T) {
+	fake, url, audit := newTestProxy(t, nil)
+	fake.AddPost(esatest.Post{Name: "Plan", Category: "LLM/Tasks/2026/01/28", BodyMD: "old"})
+	posts := url + "/v1/teams/test-team/posts"
+	create := `{"post": {"name": "New", "category": "LLM/Tasks/2026/01/29", "body_md": "x"}}`
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		host        string // 空なら待ち受けアドレス
+		origin      string
+		contentType string
+		wantStatus  int
+	}{
+		{"待ち受けアドレス以外のHostの読み取り", "GET", "/1", ``, "attacker.example:80", "", "", http.StatusForbidden},
+		{"待ち受けアドレス以外のHostの作成", "POST", "", create, "attacker.example:80", "", "application/json", http.StatusForbidden},
+		{"ポートが異なるHost", "GET", "/1", ``, "127.0.0.1:1", "", "", http.StatusForbidden},
+		{"Origin付きの読み取り", "GET", "/1", ``, "", "https://attacker.example", "", http.StatusForbidden},
+		{"Origin付きの作成", "POST", "", create, "", "http://127.0.0.1", "application/json", http.StatusForbidden},
+		{"フォーム送信の作成", "POST", "", create, "", "", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"text/plainの更新", "PATCH", "/1", `{"post": {"body_md": "new"}}`, "", "", "text/plain", http.StatusUnsupportedMediaType},
+		{"Content-Typeなしの作成", "POST", "", create, "", "", "", http.StatusUnsupportedMediaType},
+		{"charset付きのJSONの作成", "POST", "", create, "", "", "application/json; charset=utf-8", http.StatusCreated},
+		{"Content-Typeなしの読み取り", "GET", "/1", ``, "", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, posts+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+
+	// 拒否したリクエストはesa.ioに送っていない
+	if posts := fake.Posts(); len(posts) != 2 {
+		t.Errorf("posts = %d, want 2 (only the charset JSON create)", len(posts))
+	}
+	records, _, err := guard.ReadAuditLog(audit, guard.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Errorf("audit records = %d, want 1", len(records))
+	}
+}
+
+func TestHandler_RepositoryTag(t *testing.T) {
+	fake, url, _ := newTestProxy(t, func(h *Handler) {
+		h.SetRepositoryTag("repo-a")
+	})
+	tagged := fake.AddPost(esatest.Post{Name: "Plan", Category: "LLM/Tasks/2026/01/28", BodyMD: "old", Tags: []string{"repo-a"}})
+	other := fake.AddPost(esatest.Post{Name: "Other", Category: "LLM/Tasks/2026/01/28", BodyMD: "old", Tags: []string{"repo-b"}})
+	posts := url + "/v1/teams/test-team/posts"
+
+	// 作成した記事にはリポジトリのタグが付く
+	status, body := do(t, "POST", posts, `{"post": {"name": "New", "category": "LLM/Tasks/2026/01/29", "body_md": "x", "tags": ["memo"]}}`)
+	if status != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", status, body)
+	}
+	var created esa.Post
+	if err := json.Unmarshal(body, &created); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(created.Tags, []string{"memo", "repo-a"}) {
+		t.Errorf("created tags = %v, want [memo repo-a]", created.Tags)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		number     int
+		body       string
+		wantStatus int
+	}{
+		{"別のリポジトリのタグの記事の更新", "PATCH", other.Number, `{"post": {"body_md": "new"}}`, http.StatusForbidden},
+		{"別のリポジトリのタグの記事の削除", "DELETE", other.Number, ``, http.StatusForbidden},
+		{"タグ付きの記事の更新", "PATCH", tagged.Number, `{"post": {"body_md": "new", "tags": []}}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := do(t, tt.method, fmt.Sprintf("%s/%d", posts, tt.number), tt.body)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", status, tt.wantStatus, body)
+			}
+		})
+	}
+
+	// タグを置き換える更新でもリポジトリのタグは残る
+	if p, _ := fake.Post(tagged.Number); !slices.Equal(p.Tags, []string{"repo-a"}) {
+		t.Errorf("tags after update = %v, want [repo-a]", p.Tags)
+	}
+	if p, ok := fake.Post(other.Number); !ok || p.BodyMD != "old" {
+		t.Errorf("post of another repository was changed: %+v", p)
+	}
+}
+
+func TestHandler_LocalhostAddr(t *testing.T) {
+	fake, url, _ := newTestProxyAt(t, "localhost:0", nil)
+	fake.AddPost(esatest.Post{Name: "Plan", Category: "LLM/Tasks/2026/01/28", BodyMD: "old"})
+	_, port, err := net.SplitHostPort(strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// -addr の表記どおり localhost で接続するクライアントも、実際のアドレスで接続するクライアントも受け付ける
+	for _, host := range []string{"localhost:" + port, strings.TrimPrefix(url, "http://")} {
+		req, err := http.NewRequest("GET", url+"/v1/teams/test-team/posts/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Host %s: status = %d, want 200", host, resp.StatusCode)
+		}
+	}
+}
+
+func TestListenHosts(t *testing.T) {
+	listenAddr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8787}
+	tests := []struct {
+		name string
+		addr string
+		want []string
+	}{
+		{"IPアドレスで指定", "127.0.0.1:8787", []string{"127.0.0.1:8787"}},
+		{"localhostで指定", "localhost:8787", []string{"127.0.0.1:8787", "localhost:8787"}},
+		{"ポート0で指定", "localhost:0", []string{"127.0.0.1:8787", "localhost:8787"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ListenHosts(tt.addr, listenAddr); !slices.Equal(got, tt.want) {
+				t.Errorf("ListenHosts(%q) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_ReadPolicy(t *testing.T) {
+	setup := func(t *testing.T, policy ReadPolicy) string {
+		fake, url, _ := newTestProxy(t, func(h *Handler) {
+			h.policy.AllowRead("Docs")
+			h.SetReadPolicy(policy)
+		})
+		fake.AddPost(esatest.Post{Name: "Plan", Category: "LLM/Tasks", BodyMD: "x"})
+		fake.AddPost(esatest.Post{Name: "Design", Category: "Docs/Design", BodyMD: "x"})
+		fake.AddPost(esatest.Post{Name: "Secret", Category: "Private", BodyMD: "x"})
+		return url + "/v1/teams/test-team/posts"
+	}
+
+	listNames := func(t *testing.T, url string) []string {
+		status, body := do(t, "GET", url+"?sort=number&order=asc", "")
+		if status != http.StatusOK {
+			t.Fatalf("list status = %d", status)
+		}
+		var list esa.PostList
+		if err := json.Unmarshal(body, &list); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, p := range list.Posts {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	t.Run("readable", func(t *testing.T) {
+		url := setup(t, ReadPolicyReadable)
+		if names := listNames(t, url); strings.Join(names, ",") != "Plan,Design" {
+			t.Errorf("list = %v", names)
+		}
+		if status, _ := do(t, "GET", url+"/2", ""); status != http.StatusOK {
+			t.Errorf("readable post status = %d", status)
+		}
+		if status, body := do(t, "GET", url+"/3", ""); status != http.StatusForbidden || bytes.Contains(body, []byte("Secret")) {
+			t.Errorf("unreadable post status = %d, body %s", status, body)
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		url := setup(t, ReadPolicyAll)
+		if names := listNames(t, url); len(names) != 3 {
+			t.Errorf("list = %v", names)
+		}
+		if status, _ := do(t, "GET", url+"/3", ""); status != http.StatusOK {
+			t.Errorf("post status = %d", status)
+		}
+	})
+
+	t.Run("別のチーム", func(t *testing.T) {
+		_, url, _ := newTestProxy(t, nil)
+		if status, _ := do(t, "GET", url+"/v1/teams/other-team/posts", ""); status != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", status)
+		}
+	})
+}
+
+func TestParseReadPolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ReadPolicy
+		wantErr bool
+	}{
+		{"", ReadPolicyReadable, false},
+		{"readable", ReadPolicyReadable, false},
+		{"all", ReadPolicyAll, false},
+		{"none", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseReadPolicy(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseReadPolicy(%q) = %q, %v", tt.value, got, err)
+		}
+	}
+}
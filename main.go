@@ -1,36 +1,142 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa/esatest"
 	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/mcp"
 )
 
 const usage = `esa-llm-scoped-guard - Write to esa.io with category restrictions
 
 Usage:
-  esa-llm-scoped-guard <command> [options]
+  esa-llm-scoped-guard [-output text|json] [-profile name] <command> [options]
 
 Commands:
+  init      Write a starter JSON for a new post dated today (requires config)
   validate  Validate JSON file only (no config required)
   preview   Preview the generated Markdown without posting (no config required)
   diff      Show diff between existing post and new content (requires config)
-  fetch     Fetch embedded JSON from an existing post (requires config)
+  fetch     Fetch embedded JSON from an existing post in a readable category (requires config)
+  read      Print the Markdown of a post in a readable category (requires config)
+  list      List posts created by this tool in the allowed categories with task progress (requires config)
   post      Create or update a post on esa.io (requires config)
+  patch     Apply a JSON Patch (RFC 6902) or merge patch (RFC 7386) to a post's embedded JSON (requires config)
+  task      Add, update the status of, or remove a task in a JSON file or post:
+              task add|set-status|remove [-json file | -post N] ... (-post requires config)
+  reconcile Sync summary checkboxes ticked in esa back into task statuses (requires config)
+  serve-mcp Serve validate/preview/diff/post/fetch as MCP tools over stdio (requires config)
+  audit     Show the local log of writes made to esa.io by this tool (no config required)
+  fake-server Serve an in-memory fake of the esa.io API on a loopback address for offline testing
+              (no config required; point api_base_url at it)
+  daemon    Hold the token and config and serve validate/diff/post/fetch to local clients over a unix
+            socket (requires config and -allow-uid; Linux only, connections are checked by peer user ID).
+            Run the agent as another user: the daemon's own user can read its token and is not isolated
+  proxy     Serve the esa.io v1 posts API on a loopback address with the real token and category
+            restrictions on create/update/delete/move (requires config; for the esa MCP server etc.)
+  hook      Claude Code PreToolUse hook: read the hook event from stdin and allow or deny esa MCP
+            create/update/move/delete tool calls by category; writes must name the configured team
+            (teamName) (requires config; the token only for update/move/delete)
 
 Options:
   -json string
         Path to JSON file containing post data
+  -category string
+        (list) Search only under this category (must be allowed). (audit) Show only writes under this category. (init) Base category under allowed_categories; today's /yyyy/mm/dd is appended
+        in the configured timezone
+  -name string
+        (init only) Post name (defaults to the source post's name with -from-post)
+  -from-post int
+        (init only) Copy background, instructions and tasks from an existing post's
+        embedded JSON (statuses reset to not_started, github_urls removed)
+  -q string
+        (list only) esa.io search query. Category qualifiers (in:, on:, category:) and OR are rejected
+  -limit int
+        (list) Maximum number of posts to show (default 50). (audit) Number of most recent records to show (default 50)
+  -since string
+        (audit only) Show only writes since a duration ago (e.g. 24h) or a date (2006-01-02 or RFC 3339)
+  -status string
+        (audit only) Show only successful (ok) or failed (error) writes
+  -repo string
+        (audit only) Show only writes tagged with this repository name
+  -addr string
+        (fake-server) Loopback address to listen on (default 127.0.0.1:8080).
+        (proxy) Loopback address to listen on (default 127.0.0.1:8787). Requests must use it as
+        Host, carry no Origin header, and send writes as Content-Type: application/json
+  -team string
+        (fake-server only) Team name the fake server accepts (default fake-team)
+  -token string
+        (fake-server only) Access token the fake server accepts (default $ESA_ACCESS_TOKEN)
+  -socket string
+        (daemon) Unix socket to listen on (default $ESA_LLM_SCOPED_GUARD_SOCKET, else
+        $XDG_RUNTIME_DIR/esa-llm-scoped-guard/daemon.sock). (validate/diff/post/fetch) Forward the
+        request to the daemon on this socket instead of using the config and token (default
+        $ESA_LLM_SCOPED_GUARD_SOCKET); the JSON file is still read and written back locally
+  -allow-uid string
+        (daemon only) Comma-separated user IDs allowed to connect (the agent's user). Required unless
+        -allow-same-user is set; the daemon's own user ID is rejected here
+  -allow-same-user
+        (daemon only) Also accept the daemon's own user. Processes of that user can read the token,
+        so this gives no isolation; use it only to keep the token out of the agent's environment
+  -all
+        (validate only) Report every validation error instead of stopping at the first one
+  -adopt
+        (post only) Take over an existing post that was not created by this tool
+        (no embedded JSON). The post is overwritten first and the replaced body is shown
+        afterwards as a diff of what was written, not as a review step. Run diff before -adopt
+  -post int
+        (fetch/read/patch/reconcile/task/audit) Post number
+  -patch string
+        (patch only) Path to the patch file, or - for stdin. A JSON array is applied as
+        JSON Patch, an object as merge patch. Patches touching category or post_number are rejected
+  -id string
+        (task set-status/remove) Task ID. (task add) Optional ID, task-N by default
+  -title string
+        (task add) Task name without "Task N: " (titles are renumbered automatically)
+  -summary, -description, -depends-on string
+        (task add) Task fields (-summary and -depends-on can be repeated)
+  -status string
+        (task add/set-status) not_started/in_progress/in_review/completed
+  -github-url string
+        (task add/set-status) GitHub PR/Issue URL to attach to the task (repeatable for add)
+  -apply
+        (reconcile only) Update the post with the reconciled statuses after showing the diff
+  -edits string
+        (post/patch/task) What to do when the post was edited in esa since the last write:
+        abort (default) stops with the edited sections, force overwrites the edits,
+        import merges them into the JSON (statuses/checkboxes, summaries, details, background)
+  -output string
+        Output format: text (default) or json. In json mode all commands except serve-mcp
+        print a single JSON envelope to stdout ({"status": "ok"|"error", ...}), including
+        error kind (validation/config/api/not_found/unauthorized/forbidden/rate_limited/io/content/conflict/usage/canceled/timeout/internal)
+        and validation code/field/index
+  -profile string
+        Profile to use when the config defines profiles (before the command). Defaults to the
+        profile that maps the current repository in repository_categories, then default_profile
   -help
         Show help message for the command
 
+Exit Status:
+  0 on success, 1 on errors, except esa.io API errors: 3 not found (404), 4 unauthorized (401),
+  5 forbidden (403), 6 rate limited (429), 124 timed out (config timeouts), 130 interrupted (Ctrl-C/SIGTERM)
+
 JSON Schema:
   {
     "create_new": true,            // Optional: set true for new post (cannot use with post_number)
     "post_number": 123,            // Optional: existing post number for update (cannot use with create_new)
+    "revision_number": 4,          // Optional: last seen revision (set by fetch); update fails if the post changed
+    "team": "my-team",             // Optional: esa.io team of the post (set by post); posting fails for another team
     "name": "Post Title",          // Required: max 255 bytes, no /, （）, or ：
     "category": "LLM/Tasks/2026/01/18", // Required: allowed category + /yyyy/mm/dd
     "body": {                      // Required: structured format
@@ -113,41 +219,117 @@ Markdown Output Example:
 Note: Tags are automatically set to the Git repository name (no tags if not a git repository).
 
 Environment Variables:
-  ESA_ACCESS_TOKEN    esa.io API access token
+  ESA_ACCESS_TOKEN    esa.io API access token (ignored when token_file or token_command is configured)
+  ESA_LLM_SCOPED_GUARD_SOCKET
+                      Daemon socket: validate/diff/post/fetch run in client mode and need no config or token
 
 Configuration:
   ~/.config/esa-llm-scoped-guard/config.yaml
+  An optional .esa-llm-scoped-guard.yaml at the git repository root (allowed_categories only) narrows
+  allowed_categories for commands run inside that repository; each category must be within the user config.
+  Optional denied_categories (deny every operation, including reads, under a category) and
+  category_permissions (e.g. "LLM/Docs": [read, update]; operations are read, create, update, delete)
+  narrow what allowed_categories/readable_categories permit. Errors name the rule that decided.
+  Optional repository_categories (owner/repo of origin -> category) restrict writes inside a mapped
+  repository to its category and to posts tagged with the repository name; other repositories fall back to
+  default_repository_category, or cannot write when it is not set. hook and proxy use the repository of
+  their working directory; daemon and serve-mcp cannot know the caller's repository and always use
+  default_repository_category (they also ignore .esa-llm-scoped-guard.yaml).
+  Optional token_file (absolute path, same ownership/permission checks as the config file) or
+  token_command (e.g. token_command: [pass, show, esa]; run once, output never logged) supply the token
+  instead of ESA_ACCESS_TOKEN.
+  Optional proxy.read_policy sets what proxy returns for reads: readable (default; only posts in
+  allowed_categories/readable_categories) or all (pass reads through).
+  Optional api_base_url points the client at another esa.io API host (https, or http on loopback only).
+  Optional timeouts per command (e.g. timeouts: {default: 1m, post: 3m}) cancel requests and retries
+  when exceeded. Ctrl-C/SIGTERM cancel them too; the JSON file write-back after a post is never interrupted
+  Optional retry (max_attempts, default 3; base_delay, default 1s, doubled each retry; max_delay, default 30s)
+  controls retries of rate limits, 5xx and network errors. Creates retry only rate limits: after a 5xx or
+  network error the post may exist already, so the command fails instead of risking a duplicate.
+
+Audit Log:
+  $XDG_STATE_HOME/esa-llm-scoped-guard/audit.jsonl (default: ~/.local/state/esa-llm-scoped-guard/audit.jsonl)
+  Every create/update sent to esa.io is appended as one JSON line (the access token is never logged)
 
 Examples:
+  esa-llm-scoped-guard init -category LLM/Tasks -name "Weekly plan" -json ./tasks/new.json # Scaffold a new post
   esa-llm-scoped-guard validate -json ./tasks/123.json # Validate JSON
+  esa-llm-scoped-guard validate -all -json ./tasks/123.json # Report all validation errors
   esa-llm-scoped-guard preview -json ./tasks/123.json  # Preview markdown
   esa-llm-scoped-guard diff -json ./tasks/123.json     # Show diff with existing
   esa-llm-scoped-guard fetch -post 3221                # Fetch embedded JSON from post
+  esa-llm-scoped-guard read -post 3221                 # Print the post's Markdown
+  esa-llm-scoped-guard list -q "API"                   # List managed posts matching a keyword
   esa-llm-scoped-guard post -json ./tasks/123.json     # Post to esa.io
+  esa-llm-scoped-guard -output json post -json ./tasks/123.json # Post and print JSON result
+  esa-llm-scoped-guard patch -post 3221 -patch ./p.json # Patch the post's embedded JSON
+  esa-llm-scoped-guard task add -json ./tasks/123.json -title "Write docs" -summary "Update README" -description "..."
+  esa-llm-scoped-guard task set-status -post 3221 -id task-2 -status in_review -github-url https://github.com/owner/repo/pull/1
+  esa-llm-scoped-guard task remove -json ./tasks/123.json -id task-3 # Remove a task and its depends_on references
+  esa-llm-scoped-guard reconcile -post 3221 -apply     # Apply checkboxes ticked in esa
+  esa-llm-scoped-guard serve-mcp                       # Start MCP server on stdio
+  esa-llm-scoped-guard audit -post 3221 -since 24h     # Show writes to a post in the last day
+  esa-llm-scoped-guard fake-server -addr 127.0.0.1:8080 # Fake esa.io API for offline tests
+  esa-llm-scoped-guard daemon -allow-uid 1001          # Hold the token away from the agent's user (UID 1001)
+  esa-llm-scoped-guard proxy -addr 127.0.0.1:8787      # Category-enforcing esa.io API for other tools
+  esa-llm-scoped-guard hook < event.json               # Decide a PreToolUse event for an esa MCP tool
+  ESA_LLM_SCOPED_GUARD_SOCKET=$XDG_RUNTIME_DIR/esa-llm-scoped-guard/daemon.sock \
+    esa-llm-scoped-guard post -json ./tasks/123.json   # Post through the daemon
 `
 
 func main() {
-	if len(os.Args) < 2 {
+	args, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
 		fmt.Fprint(os.Stderr, usage)
 		os.Exit(1)
 	}
 
-	switch os.Args[1] {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	switch args[0] {
 	case "post":
-		runPost(os.Args[2:])
+		runPost(args[1:])
+	case "init":
+		runInit(args[1:])
 	case "validate":
-		runValidate(os.Args[2:])
+		runValidate(args[1:])
 	case "preview":
-		runPreview(os.Args[2:])
+		runPreview(args[1:])
 	case "diff":
-		runDiff(os.Args[2:])
+		runDiff(args[1:])
 	case "fetch":
-		runFetch(os.Args[2:])
+		runFetch(args[1:])
+	case "patch":
+		runPatch(args[1:])
+	case "task":
+		runTask(args[1:])
+	case "read":
+		runRead(args[1:])
+	case "list":
+		runList(args[1:])
+	case "reconcile":
+		runReconcile(args[1:])
+	case "serve-mcp":
+		runServeMCP(args[1:])
+	case "audit":
+		runAudit(args[1:])
+	case "fake-server":
+		runFakeServer(args[1:])
+	case "daemon":
+		runDaemon(args[1:])
+	case "proxy":
+		runProxy(args[1:])
+	case "hook":
+		runHook(args[1:])
 	case "-help", "--help", "help":
 		fmt.Fprint(os.Stderr, usage)
 		os.Exit(0)
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown subcommand: %s\n\n", os.Args[1])
+		fmt.Fprintf(os.Stderr, "Unknown subcommand: %s\n\n", args[0])
 		fmt.Fprint(os.Stderr, usage)
 		os.Exit(1)
 	}
@@ -158,7 +340,15 @@ func runPost(args []string) {
 	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
 	var jsonPath string
 	var showHelp bool
+	var output string
+	var socketPath string
+	var opts guard.PostOptions
+	var editMode string
 	fs.StringVar(&jsonPath, "json", "", "Path to JSON file containing post data")
+	fs.BoolVar(&opts.Adopt, "adopt", false, "Allow overwriting an existing post that was not created by this tool")
+	fs.StringVar(&editMode, "edits", string(guard.EditModeAbort), "How to handle edits made in esa since the last write (abort, force, or import)")
+	fs.StringVar(&socketPath, "socket", "", "Forward the request to the daemon listening on this unix socket")
+	fs.StringVar(&output, "output", defaultOutputFormat, "Output format (text or json)")
 	fs.BoolVar(&showHelp, "help", false, "Show help message")
 	fs.Parse(args)
 
@@ -167,14 +357,116 @@ func runPost(args []string) {
 		os.Exit(0)
 	}
 
+	rep := newReporter("post", output)
 	if jsonPath == "" {
-		fmt.Fprint(os.Stderr, usage)
-		os.Exit(1)
+		rep.failUsage("-json is required")
+	}
+	mode, err := guard.ParseEditMode(editMode)
+	if err != nil {
+		rep.fail(guard.WithKind(guard.ErrorKindUsage, err))
 	}
+	opts.EditMode = mode
 
-	if err := execPost(jsonPath); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	if socket := daemonSocketFor(socketPath); socket != "" {
+		// ガード管理外の記事の引き継ぎや人の編集の上書き・取り込みは人が手元で行うもので、daemonには依頼できない
+		if opts.Adopt || opts.EditMode != guard.EditModeAbort {
+			rep.fail(guard.WithKind(guard.ErrorKindUsage, fmt.Errorf("-adopt and -edits cannot be used with the daemon")))
+		}
+		postViaDaemon(rep, socket, jsonPath)
+		return
+	}
+
+	config, accessToken, err := loadConfigAndToken()
+	if err != nil {
+		rep.fail(err)
+	}
+	opts.Audit, err = openAuditLog("post", accessToken)
+	if err != nil {
+		rep.fail(err)
+	}
+	opts.RepositoryTag = config.RepositoryTag()
+	opts.Team = config.Esa.TeamName
+
+	ctx, cancel := commandContext("post", config)
+	defer cancel()
+	client, err := newEsaClient(config, accessToken)
+	if err != nil {
+		rep.fail(err)
+	}
+
+	if rep.isJSON() {
+		rep.emit(guard.PostFile(ctx, jsonPath, config.Policy(), client, opts))
+		return
+	}
+
+	if err := guard.ExecutePost(ctx, jsonPath, config.Policy(), client, opts); err != nil {
+		rep.fail(err)
+	}
+}
+
+func runInit(args []string) {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var jsonPath string
+	var opts guard.ScaffoldOptions
+	var showHelp bool
+	var output string
+	fs.StringVar(&jsonPath, "json", "", "Path to the JSON file to create")
+	fs.StringVar(&opts.BaseCategory, "category", "", "Base category (the date suffix is appended)")
+	fs.StringVar(&opts.Name, "name", "", "Post name")
+	fs.IntVar(&opts.FromPost, "from-post", 0, "Post number to copy the structure from")
+	fs.StringVar(&output, "output", defaultOutputFormat, "Output format (text or json)")
+	fs.BoolVar(&showHelp, "help", false, "Show help message")
+	fs.Parse(args)
+
+	if showHelp {
+		fs.Usage()
+		os.Exit(0)
+	}
+
+	rep := newReporter("init", output)
+	if jsonPath == "" {
+		rep.failUsage("-json is required")
+	}
+	if opts.BaseCategory == "" {
+		rep.failUsage("-category is required")
+	}
+	if opts.Name == "" && opts.FromPost <= 0 {
+		rep.failUsage("-name is required")
+	}
+
+	// 元記事を取得する場合のみトークンが必要
+	var config *Config
+	var accessToken string
+	var err error
+	if opts.FromPost > 0 {
+		config, accessToken, err = loadConfigAndToken()
+	} else {
+		config, err = loadUserConfig()
+	}
+	if err != nil {
+		rep.fail(err)
+	}
+	opts.Now = time.Now()
+	opts.Location = config.Location()
+
+	ctx, cancel := commandContext("init", config)
+	defer cancel()
+	var client esa.EsaClientInterface
+	if opts.FromPost > 0 {
+		client, err = newEsaClient(config, accessToken)
+		if err != nil {
+			rep.fail(err)
+		}
+	}
+
+	if rep.isJSON() {
+		rep.emit(guard.InitFile(ctx, opts, jsonPath, config.Policy(), client))
+		return
+	}
+
+	if err := guard.ExecuteInit(ctx, opts, jsonPath, config.Policy(), client); err != nil {
+		rep.fail(err)
 	}
 }
 
@@ -183,7 +475,13 @@ func runValidate(args []string) {
 	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
 	var jsonPath string
 	var showHelp bool
+	var all bool
+	var output string
+	var socketPath string
 	fs.StringVar(&jsonPath, "json", "", "Path to JSON file containing post data")
+	fs.BoolVar(&all, "all", false, "Report every validation error instead of stopping at the first one")
+	fs.StringVar(&socketPath, "socket", "", "Forward the request to the daemon listening on this unix socket")
+	fs.StringVar(&output, "output", defaultOutputFormat, "Output format (text or json)")
 	fs.BoolVar(&showHelp, "help", false, "Show help message")
 	fs.Parse(args)
 
@@ -192,23 +490,71 @@ func runValidate(args []string) {
 		os.Exit(0)
 	}
 
+	rep := newReporter("validate", output)
 	if jsonPath == "" {
-		fmt.Fprint(os.Stderr, usage)
-		os.Exit(1)
+		rep.failUsage("-json is required")
+	}
+
+	if socket := daemonSocketFor(socketPath); socket != "" {
+		if all {
+			rep.fail(guard.WithKind(guard.ErrorKindUsage, fmt.Errorf("-all cannot be used with the daemon")))
+		}
+		validateViaDaemon(rep, socket, jsonPath)
+		return
+	}
+
+	if all {
+		errs, err := guard.ExecuteValidateAll(jsonPath)
+		if err != nil {
+			rep.fail(err)
+		}
+		if len(errs) > 0 {
+			if rep.isJSON() {
+				writeJSON(guard.NewValidationErrorsResult("validate", errs))
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "Error: found %d validation error(s)\n", len(errs))
+			for _, ve := range errs {
+				fmt.Fprintf(os.Stderr, "  %s\n", formatValidationError(ve))
+			}
+			os.Exit(1)
+		}
+		if rep.isJSON() {
+			writeJSON(&guard.Result{Status: "ok", Command: "validate"})
+		}
+		return
+	}
+
+	if rep.isJSON() {
+		rep.emit(guard.ValidateFile(jsonPath))
+		return
 	}
 
 	if err := guard.ExecuteValidate(jsonPath); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		rep.fail(err)
 	}
 }
 
+// formatValidationError はバリデーションエラーを "[code] field[index]: message" 形式に整形します
+func formatValidationError(ve *guard.ValidationError) string {
+	location := ve.Field()
+	if ve.Index() >= 0 {
+		location = fmt.Sprintf("%s[%d]", location, ve.Index())
+	}
+	if location == "" {
+		return fmt.Sprintf("[%s] %s", ve.Code(), ve.Message())
+	}
+	return fmt.Sprintf("[%s] %s: %s", ve.Code(), location, ve.Message())
+}
+
 func runPreview(args []string) {
 	fs := flag.NewFlagSet("preview", flag.ExitOnError)
 	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
 	var jsonPath string
 	var showHelp bool
+	var output string
 	fs.StringVar(&jsonPath, "json", "", "Path to JSON file containing post data")
+	fs.StringVar(&output, "output", defaultOutputFormat, "Output format (text or json)")
 	fs.BoolVar(&showHelp, "help", false, "Show help message")
 	fs.Parse(args)
 
@@ -217,14 +563,18 @@ func runPreview(args []string) {
 		os.Exit(0)
 	}
 
+	rep := newReporter("preview", output)
 	if jsonPath == "" {
-		fmt.Fprint(os.Stderr, usage)
-		os.Exit(1)
+		rep.failUsage("-json is required")
+	}
+
+	if rep.isJSON() {
+		rep.emit(guard.PreviewFile(jsonPath))
+		return
 	}
 
 	if err := guard.ExecutePreview(jsonPath); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		rep.fail(err)
 	}
 }
 
@@ -233,7 +583,11 @@ func runDiff(args []string) {
 	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
 	var jsonPath string
 	var showHelp bool
+	var output string
+	var socketPath string
 	fs.StringVar(&jsonPath, "json", "", "Path to JSON file containing post data")
+	fs.StringVar(&socketPath, "socket", "", "Forward the request to the daemon listening on this unix socket")
+	fs.StringVar(&output, "output", defaultOutputFormat, "Output format (text or json)")
 	fs.BoolVar(&showHelp, "help", false, "Show help message")
 	fs.Parse(args)
 
@@ -242,32 +596,35 @@ func runDiff(args []string) {
 		os.Exit(0)
 	}
 
+	rep := newReporter("diff", output)
 	if jsonPath == "" {
-		fmt.Fprint(os.Stderr, usage)
-		os.Exit(1)
+		rep.failUsage("-json is required")
 	}
 
-	homeDir, err := os.UserHomeDir()
+	if socket := daemonSocketFor(socketPath); socket != "" {
+		diffViaDaemon(rep, socket, jsonPath)
+		return
+	}
+
+	config, accessToken, err := loadConfigAndToken()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to get home directory: %v\n", err)
-		os.Exit(1)
+		rep.fail(err)
 	}
-	configPath := filepath.Join(homeDir, ".config", "esa-llm-scoped-guard", "config.yaml")
-	config, err := LoadAndValidateConfig(configPath)
+
+	ctx, cancel := commandContext("diff", config)
+	defer cancel()
+	client, err := newEsaClient(config, accessToken)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
-		os.Exit(1)
+		rep.fail(err)
 	}
 
-	accessToken := os.Getenv("ESA_ACCESS_TOKEN")
-	if accessToken == "" {
-		fmt.Fprintf(os.Stderr, "Error: ESA_ACCESS_TOKEN environment variable is not set\n")
-		os.Exit(1)
+	if rep.isJSON() {
+		rep.emit(guard.DiffFile(ctx, jsonPath, config.Policy(), client))
+		return
 	}
 
-	if err := guard.ExecuteDiff(jsonPath, config.Esa.TeamName, config.AllowedCategories, accessToken); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	if err := guard.ExecuteDiff(ctx, jsonPath, config.Policy(), client); err != nil {
+		rep.fail(err)
 	}
 }
 
@@ -276,7 +633,59 @@ func runFetch(args []string) {
 	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
 	var postNumber int
 	var showHelp bool
+	var output string
+	var socketPath string
 	fs.IntVar(&postNumber, "post", 0, "Post number to fetch")
+	fs.StringVar(&socketPath, "socket", "", "Forward the request to the daemon listening on this unix socket")
+	fs.StringVar(&output, "output", defaultOutputFormat, "Output format (text or json)")
+	fs.BoolVar(&showHelp, "help", false, "Show help message")
+	fs.Parse(args)
+
+	if showHelp {
+		fs.Usage()
+		os.Exit(0)
+	}
+
+	rep := newReporter("fetch", output)
+	if postNumber <= 0 {
+		rep.fail(guard.WithKind(guard.ErrorKindUsage, fmt.Errorf("post number must be a positive integer (got %d)", postNumber)))
+	}
+
+	if socket := daemonSocketFor(socketPath); socket != "" {
+		fetchViaDaemon(rep, socket, postNumber)
+		return
+	}
+
+	config, accessToken, err := loadConfigAndToken()
+	if err != nil {
+		rep.fail(err)
+	}
+
+	ctx, cancel := commandContext("fetch", config)
+	defer cancel()
+	client, err := newEsaClient(config, accessToken)
+	if err != nil {
+		rep.fail(err)
+	}
+
+	if rep.isJSON() {
+		rep.emit(guard.FetchPost(ctx, postNumber, config.Policy(), client))
+		return
+	}
+
+	if err := guard.ExecuteFetch(ctx, postNumber, config.Policy(), client); err != nil {
+		rep.fail(err)
+	}
+}
+
+func runRead(args []string) {
+	fs := flag.NewFlagSet("read", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var postNumber int
+	var showHelp bool
+	var output string
+	fs.IntVar(&postNumber, "post", 0, "Post number to read")
+	fs.StringVar(&output, "output", defaultOutputFormat, "Output format (text or json)")
 	fs.BoolVar(&showHelp, "help", false, "Show help message")
 	fs.Parse(args)
 
@@ -285,52 +694,559 @@ func runFetch(args []string) {
 		os.Exit(0)
 	}
 
+	rep := newReporter("read", output)
 	if postNumber <= 0 {
-		fmt.Fprintf(os.Stderr, "Error: post number must be a positive integer (got %d)\n", postNumber)
+		rep.fail(guard.WithKind(guard.ErrorKindUsage, fmt.Errorf("post number must be a positive integer (got %d)", postNumber)))
+	}
+
+	config, accessToken, err := loadConfigAndToken()
+	if err != nil {
+		rep.fail(err)
+	}
+
+	ctx, cancel := commandContext("read", config)
+	defer cancel()
+	client, err := newEsaClient(config, accessToken)
+	if err != nil {
+		rep.fail(err)
+	}
+
+	if rep.isJSON() {
+		rep.emit(guard.ReadPost(ctx, postNumber, config.Policy(), client))
+		return
+	}
+
+	if err := guard.ExecuteRead(ctx, postNumber, config.Policy(), client); err != nil {
+		rep.fail(err)
+	}
+}
+
+func runPatch(args []string) {
+	fs := flag.NewFlagSet("patch", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var postNumber int
+	var patchPath string
+	var editMode string
+	var showHelp bool
+	var output string
+	fs.IntVar(&postNumber, "post", 0, "Post number to patch")
+	fs.StringVar(&patchPath, "patch", "", "Path to the patch file (- for stdin)")
+	fs.StringVar(&editMode, "edits", string(guard.EditModeAbort), "How to handle edits made in esa since the last write (abort, force, or import)")
+	fs.StringVar(&output, "output", defaultOutputFormat, "Output format (text or json)")
+	fs.BoolVar(&showHelp, "help", false, "Show help message")
+	fs.Parse(args)
+
+	if showHelp {
+		fs.Usage()
+		os.Exit(0)
+	}
+
+	rep := newReporter("patch", output)
+	if postNumber <= 0 {
+		rep.fail(guard.WithKind(guard.ErrorKindUsage, fmt.Errorf("post number must be a positive integer (got %d)", postNumber)))
+	}
+	if patchPath == "" {
+		rep.failUsage("-patch is required")
+	}
+	mode, err := guard.ParseEditMode(editMode)
+	if err != nil {
+		rep.fail(guard.WithKind(guard.ErrorKindUsage, err))
+	}
+	opts := guard.PostOptions{EditMode: mode}
+
+	config, accessToken, err := loadConfigAndToken()
+	if err != nil {
+		rep.fail(err)
+	}
+	opts.Audit, err = openAuditLog("patch", accessToken)
+	if err != nil {
+		rep.fail(err)
+	}
+	opts.RepositoryTag = config.RepositoryTag()
+	opts.Team = config.Esa.TeamName
+
+	ctx, cancel := commandContext("patch", config)
+	defer cancel()
+	client, err := newEsaClient(config, accessToken)
+	if err != nil {
+		rep.fail(err)
+	}
+
+	if rep.isJSON() {
+		rep.emit(guard.PatchFile(ctx, postNumber, patchPath, config.Policy(), client, opts))
+		return
+	}
+
+	if err := guard.ExecutePatch(ctx, postNumber, patchPath, config.Policy(), client, opts); err != nil {
+		rep.fail(err)
+	}
+}
+
+// stringListFlag は複数回指定できる文字列フラグ
+type stringListFlag []string
+
+func (f *stringListFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func runTask(args []string) {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usage)
 		os.Exit(1)
 	}
+	subcommand := args[0]
 
-	homeDir, err := os.UserHomeDir()
+	fs := flag.NewFlagSet("task "+subcommand, flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var target guard.TaskTarget
+	var editMode string
+	var showHelp bool
+	var output string
+	fs.StringVar(&target.JSONPath, "json", "", "Path to JSON file to edit")
+	fs.IntVar(&target.PostNumber, "post", 0, "Post number to edit")
+	fs.StringVar(&editMode, "edits", string(guard.EditModeAbort), "How to handle edits made in esa since the last write (abort, force, or import)")
+	fs.StringVar(&output, "output", defaultOutputFormat, "Output format (text or json)")
+	fs.BoolVar(&showHelp, "help", false, "Show help message")
+
+	var id, title, description, status, githubURL string
+	var summary, dependsOn, githubURLs stringListFlag
+	switch subcommand {
+	case "add":
+		fs.StringVar(&id, "id", "", "Task ID (default: task-N)")
+		fs.StringVar(&title, "title", "", "Task name without the \"Task N: \" prefix")
+		fs.StringVar(&description, "description", "", "Task description")
+		fs.StringVar(&status, "status", string(guard.TaskStatusNotStarted), "Task status")
+		fs.Var(&summary, "summary", "Task summary line (repeatable)")
+		fs.Var(&dependsOn, "depends-on", "ID of a task this task depends on (repeatable)")
+		fs.Var(&githubURLs, "github-url", "GitHub PR/Issue URL (repeatable)")
+	case "set-status":
+		fs.StringVar(&id, "id", "", "Task ID")
+		fs.StringVar(&status, "status", "", "New task status")
+		fs.StringVar(&githubURL, "github-url", "", "GitHub PR/Issue URL to attach")
+	case "remove":
+		fs.StringVar(&id, "id", "", "Task ID")
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown task subcommand: %s\n\n", subcommand)
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	fs.Parse(args[1:])
+
+	if showHelp {
+		fs.Usage()
+		os.Exit(0)
+	}
+
+	rep := newReporter("task", output)
+	if (target.JSONPath == "") == (target.PostNumber <= 0) {
+		rep.failUsage("exactly one of -json or -post is required")
+	}
+	if subcommand != "add" && id == "" {
+		rep.failUsage("-id is required")
+	}
+	mode, err := guard.ParseEditMode(editMode)
+	if err != nil {
+		rep.fail(guard.WithKind(guard.ErrorKindUsage, err))
+	}
+	opts := guard.PostOptions{EditMode: mode}
+
+	var edit guard.TaskEdit
+	switch subcommand {
+	case "add":
+		taskStatus, err := guard.ParseTaskStatus(status)
+		if err != nil {
+			rep.fail(guard.WithKind(guard.ErrorKindUsage, err))
+		}
+		edit = guard.AddTask(guard.Task{
+			ID:          id,
+			Title:       title,
+			Status:      taskStatus,
+			Summary:     summary,
+			Description: description,
+			GitHubURLs:  githubURLs,
+			DependsOn:   dependsOn,
+		})
+	case "set-status":
+		taskStatus, err := guard.ParseTaskStatus(status)
+		if err != nil {
+			rep.fail(guard.WithKind(guard.ErrorKindUsage, err))
+		}
+		edit = guard.SetTaskStatus(id, taskStatus, githubURL)
+	case "remove":
+		edit = guard.RemoveTask(id)
+	}
+
+	// ローカルのJSONファイルを編集する場合は設定不要
+	var config *Config
+	var policy *guard.Policy
+	var accessToken string
+	if target.PostNumber > 0 {
+		config, accessToken, err = loadConfigAndToken()
+		if err != nil {
+			rep.fail(err)
+		}
+		policy = config.Policy()
+		opts.Audit, err = openAuditLog("task", accessToken)
+		if err != nil {
+			rep.fail(err)
+		}
+		opts.RepositoryTag = config.RepositoryTag()
+		opts.Team = config.Esa.TeamName
+	}
+
+	ctx, cancel := commandContext("task", config)
+	defer cancel()
+	var client esa.EsaClientInterface
+	if target.PostNumber > 0 {
+		client, err = newEsaClient(config, accessToken)
+		if err != nil {
+			rep.fail(err)
+		}
+	}
+
+	if rep.isJSON() {
+		rep.emit(guard.EditTasks(ctx, target, edit, policy, client, opts))
+		return
+	}
+
+	if err := guard.ExecuteTask(ctx, target, edit, policy, client, opts); err != nil {
+		rep.fail(err)
+	}
+}
+
+func runList(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var opts guard.ListOptions
+	var showHelp bool
+	var output string
+	fs.StringVar(&opts.Query, "q", "", "esa.io search query")
+	fs.StringVar(&opts.Category, "category", "", "Search only under this allowed category")
+	fs.IntVar(&opts.Limit, "limit", guard.DefaultListLimit, "Maximum number of posts to show")
+	fs.StringVar(&output, "output", defaultOutputFormat, "Output format (text or json)")
+	fs.BoolVar(&showHelp, "help", false, "Show help message")
+	fs.Parse(args)
+
+	if showHelp {
+		fs.Usage()
+		os.Exit(0)
+	}
+
+	rep := newReporter("list", output)
+	if opts.Limit <= 0 {
+		rep.fail(guard.WithKind(guard.ErrorKindUsage, fmt.Errorf("limit must be a positive integer (got %d)", opts.Limit)))
+	}
+
+	config, accessToken, err := loadConfigAndToken()
+	if err != nil {
+		rep.fail(err)
+	}
+
+	ctx, cancel := commandContext("list", config)
+	defer cancel()
+	client, err := newEsaClient(config, accessToken)
+	if err != nil {
+		rep.fail(err)
+	}
+
+	if rep.isJSON() {
+		rep.emit(guard.ListPosts(ctx, opts, config.Policy(), client))
+		return
+	}
+
+	if err := guard.ExecuteList(ctx, opts, config.Policy(), client); err != nil {
+		rep.fail(err)
+	}
+}
+
+func runReconcile(args []string) {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var postNumber int
+	var apply bool
+	var showHelp bool
+	var output string
+	fs.IntVar(&postNumber, "post", 0, "Post number to reconcile")
+	fs.BoolVar(&apply, "apply", false, "Update the post with the reconciled statuses")
+	fs.StringVar(&output, "output", defaultOutputFormat, "Output format (text or json)")
+	fs.BoolVar(&showHelp, "help", false, "Show help message")
+	fs.Parse(args)
+
+	if showHelp {
+		fs.Usage()
+		os.Exit(0)
+	}
+
+	rep := newReporter("reconcile", output)
+	if postNumber <= 0 {
+		rep.fail(guard.WithKind(guard.ErrorKindUsage, fmt.Errorf("post number must be a positive integer (got %d)", postNumber)))
+	}
+
+	config, accessToken, err := loadConfigAndToken()
+	if err != nil {
+		rep.fail(err)
+	}
+	opts := guard.PostOptions{RepositoryTag: config.RepositoryTag(), Team: config.Esa.TeamName}
+	if apply {
+		opts.Audit, err = openAuditLog("reconcile", accessToken)
+		if err != nil {
+			rep.fail(err)
+		}
+	}
+
+	ctx, cancel := commandContext("reconcile", config)
+	defer cancel()
+	client, err := newEsaClient(config, accessToken)
+	if err != nil {
+		rep.fail(err)
+	}
+
+	if rep.isJSON() {
+		rep.emit(guard.ReconcilePost(ctx, postNumber, config.Policy(), client, apply, opts))
+		return
+	}
+
+	if err := guard.ExecuteReconcile(ctx, postNumber, config.Policy(), client, apply, opts); err != nil {
+		rep.fail(err)
+	}
+}
+
+func runServeMCP(args []string) {
+	fs := flag.NewFlagSet("serve-mcp", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var showHelp bool
+	fs.BoolVar(&showHelp, "help", false, "Show help message")
+	fs.Parse(args)
+
+	if showHelp {
+		fs.Usage()
+		os.Exit(0)
+	}
+
+	config, accessToken, err := loadServerConfigAndToken()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to get home directory: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	configPath := filepath.Join(homeDir, ".config", "esa-llm-scoped-guard", "config.yaml")
-	config, err := LoadAndValidateConfig(configPath)
+
+	audit, err := openAuditLog("serve-mcp", accessToken)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	accessToken := os.Getenv("ESA_ACCESS_TOKEN")
-	if accessToken == "" {
-		fmt.Fprintf(os.Stderr, "Error: ESA_ACCESS_TOKEN environment variable is not set\n")
+	// 常駐するためタイムアウトは設定せず、Ctrl-C・SIGTERMで処理中の呼び出しを中断して終了する
+	ctx, cancel := commandContext("serve-mcp", nil)
+	defer cancel()
+
+	// stdoutはMCPプロトコル専用のため、エラーはstderrに出力する
+	client, err := newEsaClient(config, accessToken)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	server := mcp.NewServer(config.Policy(), client)
+	server.SetAuditLog(audit)
+	server.SetRepositoryTag(config.RepositoryTag())
+	server.SetTeam(config.Esa.TeamName)
+	if err := server.ServeContext(ctx, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runAudit(args []string) {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var filter guard.AuditFilter
+	var since string
+	var showHelp bool
+	var output string
+	fs.IntVar(&filter.PostNumber, "post", 0, "Show only writes to this post")
+	fs.StringVar(&filter.Category, "category", "", "Show only writes under this category")
+	fs.StringVar(&filter.Repo, "repo", "", "Show only writes tagged with this repository name")
+	fs.StringVar(&filter.Status, "status", "", "Show only ok or error records")
+	fs.StringVar(&since, "since", "", "Show only writes since a duration ago (e.g. 24h) or a date")
+	fs.IntVar(&filter.Limit, "limit", guard.DefaultAuditLimit, "Number of most recent records to show")
+	fs.StringVar(&output, "output", defaultOutputFormat, "Output format (text or json)")
+	fs.BoolVar(&showHelp, "help", false, "Show help message")
+	fs.Parse(args)
+
+	if showHelp {
+		fs.Usage()
+		os.Exit(0)
+	}
 
-	if err := guard.ExecuteFetch(postNumber, config.Esa.TeamName, accessToken); err != nil {
+	rep := newReporter("audit", output)
+	if filter.Status != "" && filter.Status != "ok" && filter.Status != "error" {
+		rep.fail(guard.WithKind(guard.ErrorKindUsage, fmt.Errorf("status must be ok or error (got %q)", filter.Status)))
+	}
+	if filter.Limit <= 0 {
+		rep.fail(guard.WithKind(guard.ErrorKindUsage, fmt.Errorf("limit must be a positive integer (got %d)", filter.Limit)))
+	}
+	if since != "" {
+		t, err := parseSince(since, time.Now())
+		if err != nil {
+			rep.fail(guard.WithKind(guard.ErrorKindUsage, err))
+		}
+		filter.Since = t
+	}
+
+	path, err := auditLogPath()
+	if err != nil {
+		rep.fail(guard.WithKind(guard.ErrorKindIO, err))
+	}
+	path, err = checkAuditLogFile(path)
+	if err != nil {
+		rep.fail(guard.WithKind(guard.ErrorKindIO, err))
+	}
+
+	if rep.isJSON() {
+		rep.emit(guard.AuditEntries(path, filter))
+		return
+	}
+
+	if err := guard.ExecuteAudit(path, filter); err != nil {
+		rep.fail(err)
+	}
+}
+
+func runFakeServer(args []string) {
+	fs := flag.NewFlagSet("fake-server", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var addr, team, token string
+	var showHelp bool
+	fs.StringVar(&addr, "addr", "127.0.0.1:8080", "Loopback address to listen on")
+	fs.StringVar(&team, "team", "fake-team", "Team name the fake server accepts")
+	fs.StringVar(&token, "token", os.Getenv(accessTokenEnv), "Access token the fake server accepts")
+	fs.BoolVar(&showHelp, "help", false, "Show help message")
+	fs.Parse(args)
+
+	if showHelp {
+		fs.Usage()
+		os.Exit(0)
+	}
+
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "Error: -token or ESA_ACCESS_TOKEN is required")
+		os.Exit(1)
+	}
+	// テスト用のサーバーのため、ループバック以外では待ち受けない
+	if err := esa.ValidateBaseURL("http://" + addr); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -addr must be a loopback address: %v\n", err)
+		os.Exit(1)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to listen: %v\n", err)
+		os.Exit(1)
+	}
+	server := &http.Server{Handler: esatest.NewServer(team, token), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, cancel := commandContext("fake-server", nil)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Fprintf(os.Stderr, "Fake esa.io API for team %s listening on http://%s (set api_base_url to this URL)\n", team, listener.Addr())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func execPost(jsonPath string) error {
+// parseSince は -since の値（24h のような期間、または日付・日時）を時刻に変換します
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("since must not be a negative duration: %s", value)
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q (use a duration like 24h, 2006-01-02, or RFC 3339)", value)
+}
+
+// loadConfigAndToken は設定ファイルを読み込み、アクセストークンを取得します
+// トークンの取得元（token_command / token_file / ESA_ACCESS_TOKEN）はresolveAccessTokenで決める
+// 返すエラーには種類 config が付与される
+func loadConfigAndToken() (*Config, string, error) {
 	// 1. 設定ファイルの読み込み
-	homeDir, err := os.UserHomeDir()
+	config, err := loadUserConfig()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return nil, "", err
 	}
-	configPath := filepath.Join(homeDir, ".config", "esa-llm-scoped-guard", "config.yaml")
-	config, err := LoadAndValidateConfig(configPath)
+
+	// 2. アクセストークンの取得
+	accessToken, err := resolveAccessToken(config)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return nil, "", err
 	}
 
-	// 2. 環境変数からESA_ACCESS_TOKENを取得
-	accessToken := os.Getenv("ESA_ACCESS_TOKEN")
-	if accessToken == "" {
-		return fmt.Errorf("ESA_ACCESS_TOKEN environment variable is not set")
+	return config, accessToken, nil
+}
+
+// newEsaClient は設定のチーム・APIのベースURLでesa.io APIクライアントを作成します
+func newEsaClient(config *Config, accessToken string) (*esa.EsaClient, error) {
+	client := esa.NewEsaClient(config.Esa.TeamName, accessToken)
+	client.SetRetryPolicy(config.RetryPolicy())
+	if config.APIBaseURL != "" {
+		if err := client.SetBaseURL(config.APIBaseURL); err != nil {
+			return nil, guard.WithKind(guard.ErrorKindConfig, err)
+		}
+	}
+	return client, nil
+}
+
+// selectedProfile はグローバルフラグ -profile で指定されたプロファイル名（空ならリポジトリの割り当てか default_profile）
+var selectedProfile string
+
+// loadServerConfigAndToken は daemon・serve-mcp 用に設定ファイルを読み込み、アクセストークンを取得します
+// 呼び出し元のリポジトリは分からないため、起動したディレクトリのリポジトリの設定・割り当ては使わない
+// （repository_categories がある場合は割り当てのないリポジトリとして default_repository_category に狭める）
+func loadServerConfigAndToken() (*Config, string, error) {
+	config, err := loadUserConfigAt("")
+	if err != nil {
+		return nil, "", err
+	}
+	accessToken, err := resolveAccessToken(config)
+	if err != nil {
+		return nil, "", err
+	}
+	return config, accessToken, nil
+}
+
+// loadUserConfig はホームディレクトリの設定ファイルを読み込み、検証します
+func loadUserConfig() (*Config, error) {
+	// カレントディレクトリのgitリポジトリに .esa-llm-scoped-guard.yaml があれば書き込めるカテゴリを狭める
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, guard.WithKind(guard.ErrorKindConfig, fmt.Errorf("failed to get working directory: %w", err))
 	}
+	return loadUserConfigAt(workDir)
+}
 
-	return guard.ExecutePost(jsonPath, config.Esa.TeamName, config.AllowedCategories, accessToken)
+// loadUserConfigAt はworkDirのリポジトリの設定・割り当てを適用してホームディレクトリの設定ファイルを読み込みます（空なら適用しない）
+func loadUserConfigAt(workDir string) (*Config, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, guard.WithKind(guard.ErrorKindConfig, fmt.Errorf("failed to get home directory: %w", err))
+	}
+	configPath := filepath.Join(homeDir, ".config", "esa-llm-scoped-guard", "config.yaml")
+	config, err := LoadAndValidateConfig(configPath, LoadOptions{WorkDir: workDir, Profile: selectedProfile})
+	if err != nil {
+		return nil, guard.WithKind(guard.ErrorKindConfig, fmt.Errorf("failed to load config: %w", err))
+	}
+	return config, nil
 }
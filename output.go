@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// defaultOutputFormat はサブコマンドより前に指定されたグローバルな -output の値
+var defaultOutputFormat = outputText
+
+// reporter はサブコマンドの結果を出力形式に応じて出力します
+type reporter struct {
+	command string
+	format  string
+}
+
+// newReporter は新しいreporterを作成します
+// 不正な出力形式の場合はテキストでエラーを出力して終了します
+func newReporter(command, format string) *reporter {
+	if format != outputText && format != outputJSON {
+		fmt.Fprintf(os.Stderr, "Error: invalid output format %q (must be text or json)\n", format)
+		os.Exit(1)
+	}
+	return &reporter{command: command, format: format}
+}
+
+// isJSON はJSON出力モードかを返します
+func (r *reporter) isJSON() bool {
+	return r.format == outputJSON
+}
+
+// fail はエラーを出力し、エラーの種類に応じた終了コード（通常は1）で終了します
+// JSON出力モードではエンベロープを標準出力に、テキストモードでは "Error: ..." を標準エラー出力に書き込む
+func (r *reporter) fail(err error) {
+	if r.isJSON() {
+		writeJSON(guard.NewErrorResult(r.command, err))
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(guard.ErrorKindOf(err).ExitCode())
+}
+
+// failUsage は引数エラーを出力し、終了コード1で終了します
+// テキストモードではヘルプを表示する
+func (r *reporter) failUsage(message string) {
+	if r.isJSON() {
+		r.fail(guard.WithKind(guard.ErrorKindUsage, fmt.Errorf("%s", message)))
+	}
+	fmt.Fprint(os.Stderr, usage)
+	os.Exit(1)
+}
+
+// emit はJSON出力モードで実行結果を出力します（エラーの場合は fail と同じ）
+func (r *reporter) emit(result *guard.Result, err error) {
+	if err != nil {
+		r.fail(err)
+	}
+	writeJSON(result)
+}
+
+// writeJSON は結果を1つのJSONとして標準出力に書き込みます
+func writeJSON(result *guard.Result) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write JSON output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// globalFlags はサブコマンドより前に指定できるグローバルフラグと、その値の設定先
+var globalFlags = map[string]*string{
+	"output":  &defaultOutputFormat,
+	"profile": &selectedProfile,
+}
+
+// parseGlobalFlags はサブコマンドより前のグローバルフラグ（-output, -profile）を解釈し、残りの引数を返します
+func parseGlobalFlags(args []string) ([]string, error) {
+	for len(args) > 0 {
+		arg := args[0]
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+		target, ok := globalFlags[name]
+		if !strings.HasPrefix(arg, "-") || !ok {
+			return args, nil
+		}
+		if !hasValue {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("flag needs an argument: -%s", name)
+			}
+			value = args[1]
+			args = args[1:]
+		}
+		*target = value
+		args = args[1:]
+	}
+	return args, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGlobalFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantArgs   []string
+		wantFormat string
+		wantErr    bool
+	}{
+		{"指定なし", []string{"validate", "-json", "a.json"}, []string{"validate", "-json", "a.json"}, outputText, false},
+		{"スペース区切り", []string{"-output", "json", "post"}, []string{"post"}, outputJSON, false},
+		{"イコール区切り", []string{"--output=json", "fetch"}, []string{"fetch"}, outputJSON, false},
+		{"プロファイルと併用", []string{"-profile", "work", "-output=json", "post"}, []string{"post"}, outputJSON, false},
+		{"値なし", []string{"-output"}, nil, outputText, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaultOutputFormat = outputText
+			selectedProfile = ""
+			t.Cleanup(func() { defaultOutputFormat, selectedProfile = outputText, "" })
+
+			got, err := parseGlobalFlags(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseGlobalFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", got, tt.wantArgs)
+			}
+			if defaultOutputFormat != tt.wantFormat {
+				t.Errorf("format = %v, want %v", defaultOutputFormat, tt.wantFormat)
+			}
+		})
+	}
+}
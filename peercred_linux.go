@@ -0,0 +1,32 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// peerCredSupported は接続元の資格情報（SO_PEERCRED）を取得できるか
+const peerCredSupported = true
+
+// peerCredentials はunixソケットの接続元プロセスのUIDとPIDを返します
+// 接続時点でカーネルが記録した値のため、接続元が後から偽ることはできない
+func peerCredentials(conn *net.UnixConn) (uint32, int32, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get raw connection: %w", err)
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return 0, 0, fmt.Errorf("failed to get peer credentials: %w", err)
+	}
+	if credErr != nil {
+		return 0, 0, fmt.Errorf("failed to get peer credentials: %w", credErr)
+	}
+	return cred.Uid, cred.Pid, nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"net"
+)
+
+// peerCredSupported は接続元の資格情報を取得できるか（Linux以外は未対応）
+const peerCredSupported = false
+
+// peerCredentials はLinux以外では常にエラーを返します（接続元を確認できない接続は受け付けない）
+func peerCredentials(conn *net.UnixConn) (uint32, int32, error) {
+	return 0, 0, errors.New("peer credential check is not supported on this platform")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/esa"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+	"github.com/syou6162/esa-llm-scoped-guard/internal/proxy"
+)
+
+func runProxy(args []string) {
+	fs := flag.NewFlagSet("proxy", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var addr string
+	var showHelp bool
+	fs.StringVar(&addr, "addr", "127.0.0.1:8787", "Loopback address to listen on")
+	fs.BoolVar(&showHelp, "help", false, "Show help message")
+	fs.Parse(args)
+
+	if showHelp {
+		fs.Usage()
+		os.Exit(0)
+	}
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(guard.ErrorKindOf(err).ExitCode())
+	}
+
+	// トークンを付けて中継するため、ループバック以外では待ち受けない
+	if err := esa.ValidateBaseURL("http://" + addr); err != nil {
+		fail(guard.WithKind(guard.ErrorKindUsage, fmt.Errorf("-addr must be a loopback address: %w", err)))
+	}
+
+	config, accessToken, err := loadConfigAndToken()
+	if err != nil {
+		fail(err)
+	}
+	readPolicy, err := proxy.ParseReadPolicy(config.Proxy.ReadPolicy)
+	if err != nil {
+		fail(guard.WithKind(guard.ErrorKindConfig, err))
+	}
+	audit, err := openAuditLog("proxy", accessToken)
+	if err != nil {
+		fail(err)
+	}
+
+	ctx, cancel := commandContext("proxy", nil)
+	defer cancel()
+	client, err := newEsaClient(config, accessToken)
+	if err != nil {
+		fail(err)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fail(guard.WithKind(guard.ErrorKindIO, fmt.Errorf("failed to listen: %w", err)))
+	}
+
+	// Hostヘッダーは -addr の表記と実際に待ち受けているアドレスのどちらかと照合する
+	handler := proxy.NewHandler(config.Esa.TeamName, proxy.ListenHosts(addr, listener.Addr()), config.Policy(), client)
+	handler.SetReadPolicy(readPolicy)
+	handler.SetRepositoryTag(config.RepositoryTag())
+	handler.SetAuditLog(audit)
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Fprintf(os.Stderr, "Proxying the esa.io posts API for team %s on http://%s (reads: %s)\n", config.Esa.TeamName, listener.Addr(), readPolicy)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fail(guard.WithKind(guard.ErrorKindIO, err))
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+// accessTokenEnv はアクセストークンを渡す環境変数
+const accessTokenEnv = "ESA_ACCESS_TOKEN"
+
+// maxTokenSize はトークンファイル・token_commandの出力として読み込む最大サイズ
+const maxTokenSize = 64 * 1024
+
+// tokenCommandTimeout はtoken_commandの実行を待つ最大時間
+const tokenCommandTimeout = 30 * time.Second
+
+// resolveAccessToken はesa.ioのアクセストークンを取得します
+// 優先順位は token_command、token_file、環境変数 ESA_ACCESS_TOKEN の順で、設定ファイルで指定した取得元があれば環境変数は使わない
+// トークンの値はエラーメッセージにも含めない。返すエラーには種類 config が付与される
+func resolveAccessToken(config *Config) (string, error) {
+	var token string
+	var err error
+	switch {
+	case len(config.TokenCommand) > 0:
+		token, err = runTokenCommand(config.TokenCommand)
+	case config.TokenFile != "":
+		token, err = readTokenFile(config.TokenFile)
+	default:
+		token = os.Getenv(accessTokenEnv)
+		if token == "" {
+			err = fmt.Errorf("%s environment variable is not set (or set token_file / token_command in the config file)", accessTokenEnv)
+		}
+	}
+	if err != nil {
+		return "", guard.WithKind(guard.ErrorKindConfig, err)
+	}
+	return token, nil
+}
+
+// readTokenFile はトークンファイルを設定ファイルと同じ所有者・権限の検証をしてから読み込みます
+func readTokenFile(path string) (string, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(homeDir, rest)
+	}
+
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve token file: %w", err)
+	}
+	if err := validatePrivateFile(realPath, "token"); err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(realPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open token file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxTokenSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	return parseToken(data, "token file")
+}
+
+// runTokenCommand はtoken_commandを一度だけ実行し、標準出力をトークンとして返します
+// 標準入力は渡さず（serve-mcpではプロトコルに使うため）、標準エラーはそのまま表示する
+func runTokenCommand(args []string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("failed to run token_command: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to run token_command: %w", err)
+	}
+
+	data, readErr := io.ReadAll(io.LimitReader(stdout, maxTokenSize+1))
+	if len(data) > maxTokenSize {
+		// 出力し続けるコマンドを待ち続けないよう止める
+		cmd.Process.Kill()
+	}
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("token_command did not finish within %s", tokenCommandTimeout)
+		}
+		if len(data) > maxTokenSize {
+			return "", fmt.Errorf("token_command output exceeds %d bytes", maxTokenSize)
+		}
+		return "", fmt.Errorf("token_command failed: %w", err)
+	}
+	if readErr != nil {
+		return "", fmt.Errorf("failed to read token_command output: %w", readErr)
+	}
+	return parseToken(data, "token_command output")
+}
+
+// parseToken は前後の空白・改行を取り除いたトークンを返します
+// 空のものや途中に空白・制御文字を含むもの（複数行の出力など）は拒否する
+func parseToken(data []byte, source string) (string, error) {
+	if len(data) > maxTokenSize {
+		return "", fmt.Errorf("%s exceeds %d bytes", source, maxTokenSize)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%s is empty", source)
+	}
+	if strings.IndexFunc(token, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return "", fmt.Errorf("%s must be a single token without whitespace", source)
+	}
+	return token, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/syou6162/esa-llm-scoped-guard/internal/guard"
+)
+
+func TestResolveAccessToken(t *testing.T) {
+	// writeTokenFile はトークンファイルを作成し、そのパスを返す
+	writeTokenFile := func(t *testing.T, content string, perm os.FileMode) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "token")
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write token file: %v", err)
+		}
+		if err := os.Chmod(path, perm); err != nil {
+			t.Fatalf("Failed to chmod token file: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name      string
+		env       string
+		setConfig func(t *testing.T, config *Config)
+		want      string
+		wantErr   string
+	}{
+		{
+			name: "環境変数のトークン",
+			env:  "env-token",
+			want: "env-token",
+		},
+		{
+			name:    "環境変数も設定もない",
+			wantErr: "ESA_ACCESS_TOKEN environment variable is not set",
+		},
+		{
+			name: "token_fileは環境変数より優先",
+			env:  "env-token",
+			setConfig: func(t *testing.T, config *Config) {
+				config.TokenFile = writeTokenFile(t, "file-token\n", 0600)
+			},
+			want: "file-token",
+		},
+		{
+			name: "group-writableなtoken_file",
+			env:  "env-token",
+			setConfig: func(t *testing.T, config *Config) {
+				config.TokenFile = writeTokenFile(t, "file-token", 0620)
+			},
+			wantErr: "token file is group or world writable",
+		},
+		{
+			name: "空のtoken_file",
+			setConfig: func(t *testing.T, config *Config) {
+				config.TokenFile = writeTokenFile(t, "\n", 0600)
+			},
+			wantErr: "token file is empty",
+		},
+		{
+			name: "存在しないtoken_file",
+			setConfig: func(t *testing.T, config *Config) {
+				config.TokenFile = filepath.Join(t.TempDir(), "missing")
+			},
+			wantErr: "failed to resolve token file",
+		},
+		{
+			name: "token_commandは環境変数より優先",
+			env:  "env-token",
+			setConfig: func(t *testing.T, config *Config) {
+				config.TokenCommand = []string{"sh", "-c", "printf 'command-token\\n'"}
+			},
+			want: "command-token",
+		},
+		{
+			name: "失敗したtoken_commandの出力はエラーに含めない",
+			setConfig: func(t *testing.T, config *Config) {
+				config.TokenCommand = []string{"sh", "-c", "echo secret-output; exit 3"}
+			},
+			wantErr: "token_command failed: exit status 3",
+		},
+		{
+			name: "複数行を出力するtoken_command",
+			setConfig: func(t *testing.T, config *Config) {
+				config.TokenCommand = []string{"sh", "-c", "printf 'secret-output\\nsecond-line\\n'"}
+			},
+			wantErr: "token_command output must be a single token",
+		},
+		{
+			name: "存在しないtoken_command",
+			setConfig: func(t *testing.T, config *Config) {
+				config.TokenCommand = []string{filepath.Join(t.TempDir(), "missing")}
+			},
+			wantErr: "failed to run token_command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(accessTokenEnv, tt.env)
+			config := &Config{}
+			if tt.setConfig != nil {
+				tt.setConfig(t, config)
+			}
+
+			got, err := resolveAccessToken(config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveAccessToken() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				if strings.Contains(err.Error(), "secret-output") {
+					t.Errorf("error message leaks the command output: %v", err)
+				}
+				if guard.ErrorKindOf(err) != guard.ErrorKindConfig {
+					t.Errorf("ErrorKindOf() = %v, want config", guard.ErrorKindOf(err))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveAccessToken() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveAccessToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}